@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/cgimenes/gomenes-boy/gameboy"
+	"github.com/cgimenes/gomenes-boy/hardware/model"
+)
+
+// bootFlags are the flags choosing the model and how it boots, shared by the
+// commands that power a Game Boy on
+type bootFlags struct {
+	model    *string
+	bootROM  *string
+	skipBoot *bool
+}
+
+func addBootFlags(flags *flag.FlagSet) *bootFlags {
+	return &bootFlags{
+		model:    flags.String("model", "auto", "hardware to emulate: auto, dmg0, dmg, mgb, sgb or cgb"),
+		bootROM:  flags.String("boot-rom", "", "boot ROM image for the model, the DMG one is built in"),
+		skipBoot: flags.Bool("skip-boot", false, "start at the cartridge entry point as if the boot ROM had run"),
+	}
+}
+
+// apply fills the boot options in, reading the boot ROM file
+func (b *bootFlags) apply(opts *gameboy.Options) error {
+	m, err := model.Parse(*b.model)
+	if err != nil {
+		return err
+	}
+	opts.Model = m
+	opts.SkipBoot = *b.skipBoot
+	if *b.bootROM != "" {
+		if opts.BootROM, err = os.ReadFile(*b.bootROM); err != nil {
+			return err
+		}
+	}
+	return nil
+}
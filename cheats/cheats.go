@@ -0,0 +1,134 @@
+// Package cheats implements Game Genie ROM patches and GameShark RAM writes.
+// Neither touches the ROM image: patches are applied as the MMU reads the
+// cartridge and RAM writes once per frame.
+package cheats
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+type Kind int
+
+const (
+	// GameGenie codes replace a ROM byte, optionally only when it holds an
+	// expected value
+	GameGenie Kind = iota
+	// GameShark codes write a RAM byte every frame
+	GameShark
+)
+
+type Cheat struct {
+	Code        string
+	Description string
+	Enabled     bool
+
+	Kind    Kind
+	Address types.Word
+	Value   types.Byte
+	// Compare is the ROM value a Game Genie code expects, when HasCompare
+	HasCompare bool
+	Compare    types.Byte
+	// Bank is the WRAM bank a GameShark code targets, -1 for whichever is
+	// mapped
+	Bank int
+}
+
+// Parse decodes a Game Genie code (ABC-DEF or ABC-DEF-GHI) or a GameShark
+// code (TTVVAAAA). The cheat starts enabled.
+func Parse(code string) (Cheat, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if strings.Contains(code, "-") {
+		return parseGameGenie(code)
+	}
+	return parseGameShark(code)
+}
+
+func parseGameGenie(code string) (Cheat, error) {
+	digits := strings.ReplaceAll(code, "-", "")
+	if (len(digits) != 6 && len(digits) != 9) || !validGroups(code) {
+		return Cheat{}, fmt.Errorf("cheats: bad Game Genie code %q", code)
+	}
+	n := make([]types.Word, len(digits))
+	for i, d := range digits {
+		v, err := strconv.ParseUint(string(d), 16, 8)
+		if err != nil {
+			return Cheat{}, fmt.Errorf("cheats: bad Game Genie code %q", code)
+		}
+		n[i] = types.Word(v)
+	}
+
+	c := Cheat{Code: code, Enabled: true, Kind: GameGenie, Bank: -1}
+	c.Value = types.Byte(n[0]<<4 | n[1])
+	c.Address = (n[5]^0xF)<<12 | n[2]<<8 | n[3]<<4 | n[4]
+	if c.Address >= 0x8000 {
+		return Cheat{}, fmt.Errorf("cheats: Game Genie code %q patches 0x%04X, outside ROM", code, c.Address)
+	}
+	if len(n) == 9 {
+		// the compare value is scrambled over G and I, H is not used
+		compare := types.Byte(n[6]<<4 | n[8])
+		compare = compare>>2 | compare<<6
+		c.HasCompare = true
+		c.Compare = compare ^ 0xBA
+	}
+	return c, nil
+}
+
+// validGroups checks the dashes split the code in groups of 3
+func validGroups(code string) bool {
+	for _, group := range strings.Split(code, "-") {
+		if len(group) != 3 {
+			return false
+		}
+	}
+	return true
+}
+
+func parseGameShark(code string) (Cheat, error) {
+	if len(code) != 8 {
+		return Cheat{}, fmt.Errorf("cheats: bad GameShark code %q", code)
+	}
+	v, err := strconv.ParseUint(code, 16, 32)
+	if err != nil {
+		return Cheat{}, fmt.Errorf("cheats: bad GameShark code %q", code)
+	}
+
+	kind := types.Byte(v >> 24)
+	c := Cheat{Code: code, Enabled: true, Kind: GameShark, Bank: -1}
+	c.Value = types.Byte(v >> 16)
+	// the address is stored low byte first
+	c.Address = types.Word(v&0xFF)<<8 | types.Word(v>>8&0xFF)
+
+	switch {
+	case kind == 0x01:
+	case kind >= 0x90 && kind <= 0x97:
+		// 9X selects WRAM bank X for D000-DFFF. Like SVBK, bank 0 selects
+		// bank 1.
+		c.Bank = int(kind & 0x07)
+		if c.Bank == 0 {
+			c.Bank = 1
+		}
+	default:
+		return Cheat{}, fmt.Errorf("cheats: unsupported GameShark code type 0x%02X in %q", kind, code)
+	}
+	if c.Address < 0x8000 {
+		return Cheat{}, fmt.Errorf("cheats: GameShark code %q writes 0x%04X, inside ROM", code, c.Address)
+	}
+	return c, nil
+}
+
+func (c Cheat) String() string {
+	switch {
+	case c.Kind == GameShark && c.Bank >= 0:
+		return fmt.Sprintf("%s: write $%02X to %X:%04X", c.Code, c.Value, c.Bank, c.Address)
+	case c.Kind == GameShark:
+		return fmt.Sprintf("%s: write $%02X to %04X", c.Code, c.Value, c.Address)
+	case c.HasCompare:
+		return fmt.Sprintf("%s: patch %04X from $%02X to $%02X", c.Code, c.Address, c.Compare, c.Value)
+	default:
+		return fmt.Sprintf("%s: patch %04X to $%02X", c.Code, c.Address, c.Value)
+	}
+}
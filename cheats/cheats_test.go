@@ -0,0 +1,141 @@
+package cheats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		code string
+		want Cheat
+	}{
+		{"3E9-A1E-4C1", Cheat{Kind: GameGenie, Address: 0x19A1, Value: 0x3E, HasCompare: true, Compare: 0xEA, Bank: -1}},
+		{"00A-17B", Cheat{Kind: GameGenie, Address: 0x4A17, Value: 0x00, Bank: -1}},
+		{"010238CD", Cheat{Kind: GameShark, Address: 0xCD38, Value: 0x02, Bank: -1}},
+		{"9263F5D2", Cheat{Kind: GameShark, Address: 0xD2F5, Value: 0x63, Bank: 2}},
+		{"9063F5D2", Cheat{Kind: GameShark, Address: 0xD2F5, Value: 0x63, Bank: 1}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.code)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.code, err)
+			continue
+		}
+		tt.want.Code = tt.code
+		tt.want.Enabled = true
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.code, got, tt.want)
+		}
+	}
+
+	for _, code := range []string{"", "3E9A1E4C1", "3E-9A1E-4C1", "ZZZ-A1E-4C1", "0102", "02023412", "01020040"} {
+		if _, err := Parse(code); err == nil {
+			t.Errorf("Parse(%q) did not fail", code)
+		}
+	}
+}
+
+func TestPatch(t *testing.T) {
+	l := &List{}
+	changes := 0
+	l.OnChange = func() { changes++ }
+	if _, err := l.Add("3E9-A1E-4C1", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := l.Patch(0x19A1, 0xEA); got != 0x3E {
+		t.Errorf("matching compare: got 0x%02X, want 0x3E", got)
+	}
+	if got := l.Patch(0x19A1, 0x12); got != 0x12 {
+		t.Errorf("other bank: got 0x%02X, want 0x12", got)
+	}
+	if got := l.Patch(0x19A2, 0xEA); got != 0xEA {
+		t.Errorf("other address: got 0x%02X, want 0xEA", got)
+	}
+
+	l.Toggle(0)
+	if got := l.Patch(0x19A1, 0xEA); got != 0xEA {
+		t.Errorf("disabled: got 0x%02X, want 0xEA", got)
+	}
+	if changes != 2 {
+		t.Errorf("OnChange called %d times, want 2", changes)
+	}
+}
+
+type fakeMemory struct {
+	bank   int
+	writes map[types.Word]types.Byte
+}
+
+func (m *fakeMemory) Set(address types.Word, value types.Byte) {
+	m.writes[address] = value
+}
+
+func (m *fakeMemory) WRAMBank() int {
+	return m.bank
+}
+
+func TestApply(t *testing.T) {
+	l := &List{}
+	for _, code := range []string{"010238CD", "9263F5D2", "9164F5D2", "01FF00C0"} {
+		if _, err := l.Add(code, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Enable(3, false)
+
+	m := &fakeMemory{bank: 1, writes: map[types.Word]types.Byte{}}
+	l.Apply(m)
+	want := map[types.Word]types.Byte{0xCD38: 0x02, 0xD2F5: 0x64}
+	if len(m.writes) != len(want) {
+		t.Fatalf("wrote %v, want %v", m.writes, want)
+	}
+	for address, value := range want {
+		if m.writes[address] != value {
+			t.Errorf("0x%04X = 0x%02X, want 0x%02X", address, m.writes[address], value)
+		}
+	}
+}
+
+func TestFileRoundTrip(t *testing.T) {
+	const file = `# comment
+
+3E9-A1E-4C1 Infinite time
+off 010238CD
+`
+	l, err := Read(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Cheats) != 2 || !l.Cheats[0].Enabled || l.Cheats[1].Enabled {
+		t.Fatalf("got %+v", l.Cheats)
+	}
+	if l.Cheats[0].Description != "Infinite time" {
+		t.Errorf("description = %q", l.Cheats[0].Description)
+	}
+	if l.Patch(0x19A1, 0xEA) != 0x3E {
+		t.Error("enabled cheat from the file does not patch")
+	}
+
+	var buf bytes.Buffer
+	if err := l.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "3E9-A1E-4C1 Infinite time\noff 010238CD\n"; buf.String() != want {
+		t.Errorf("Write = %q, want %q", buf.String(), want)
+	}
+
+	if _, err := Read(strings.NewReader("off\n")); err == nil {
+		t.Error("a line without a code did not fail")
+	}
+}
+
+func TestPathFor(t *testing.T) {
+	if got := PathFor("roms/tetris.gb"); got != "roms/tetris.cht" {
+		t.Errorf("PathFor = %q", got)
+	}
+}
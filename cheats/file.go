@@ -0,0 +1,84 @@
+package cheats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PathFor returns the cheat file kept next to a ROM: game.gb uses game.cht
+func PathFor(romPath string) string {
+	return strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".cht"
+}
+
+// Read parses a cheat file. Each line holds a code and an optional
+// description, lines starting with "off" are loaded disabled and lines
+// starting with # are comments:
+//
+//	# Tetris
+//	00A-17B-C49 Start on level 9
+//	off 010138C0 Always clear a line
+func Read(r io.Reader) (*List, error) {
+	l := &List{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		enabled := true
+		fields := strings.Fields(line)
+		if strings.EqualFold(fields[0], "off") {
+			enabled = false
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("cheats: line %d: missing code", n)
+		}
+
+		c, err := l.Add(fields[0], strings.Join(fields[1:], " "))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		c.Enabled = enabled
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	l.update()
+	return l, nil
+}
+
+// Write saves the list in the format Read expects
+func (l *List) Write(w io.Writer) error {
+	for _, c := range l.Cheats {
+		line := c.Code
+		if !c.Enabled {
+			line = "off " + line
+		}
+		if c.Description != "" {
+			line += " " + c.Description
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load reads the cheat file at path. A missing file is an empty list.
+func Load(path string) (*List, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &List{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
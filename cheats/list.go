@@ -0,0 +1,94 @@
+package cheats
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// Memory is where GameShark codes write
+type Memory interface {
+	Set(address types.Word, value types.Byte)
+	// WRAMBank is the bank mapped at D000-DFFF
+	WRAMBank() int
+}
+
+// List holds the cheats of a game. It patches ROM reads as a memory.Patcher
+// and applies RAM writes through Apply.
+type List struct {
+	Cheats []*Cheat
+	// OnChange is called when the patched ROM bytes change, so caches of
+	// decoded code can be dropped
+	OnChange func()
+
+	// enabled Game Genie cheats by address
+	patches map[types.Word][]*Cheat
+	patched [0x8000]bool
+}
+
+// Add parses code and appends it, enabled
+func (l *List) Add(code, description string) (*Cheat, error) {
+	c, err := Parse(code)
+	if err != nil {
+		return nil, err
+	}
+	c.Description = description
+	l.Cheats = append(l.Cheats, &c)
+	l.update()
+	return &c, nil
+}
+
+// Enable turns the cheat at index i on or off
+func (l *List) Enable(i int, enabled bool) {
+	l.Cheats[i].Enabled = enabled
+	l.update()
+}
+
+// Toggle flips the cheat at index i and returns its new state
+func (l *List) Toggle(i int) bool {
+	l.Enable(i, !l.Cheats[i].Enabled)
+	return l.Cheats[i].Enabled
+}
+
+// update rebuilds the patch index after a change
+func (l *List) update() {
+	for address := range l.patches {
+		l.patched[address] = false
+	}
+	l.patches = map[types.Word][]*Cheat{}
+	for _, c := range l.Cheats {
+		if c.Enabled && c.Kind == GameGenie {
+			l.patches[c.Address] = append(l.patches[c.Address], c)
+			l.patched[c.Address] = true
+		}
+	}
+	if l.OnChange != nil {
+		l.OnChange()
+	}
+}
+
+// Patch returns what a ROM read at address yields with the cheats applied.
+// A code with a compare value only applies while the ROM holds that value,
+// which keeps it from firing in other banks.
+func (l *List) Patch(address types.Word, value types.Byte) types.Byte {
+	if address >= 0x8000 || !l.patched[address] {
+		return value
+	}
+	for _, c := range l.patches[address] {
+		if !c.HasCompare || c.Compare == value {
+			return c.Value
+		}
+	}
+	return value
+}
+
+// Apply performs the enabled GameShark writes, once per frame
+func (l *List) Apply(m Memory) {
+	for _, c := range l.Cheats {
+		if !c.Enabled || c.Kind != GameShark {
+			continue
+		}
+		if c.Bank >= 0 && c.Address >= 0xD000 && c.Address < 0xE000 && c.Bank != m.WRAMBank() {
+			continue
+		}
+		m.Set(c.Address, c.Value)
+	}
+}
@@ -0,0 +1,207 @@
+package cheats
+
+import (
+	"fmt"
+
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// Reader is what a Search looks at, memory.MMU satisfies it
+type Reader interface {
+	// Banks lists the banks that can be mapped at address
+	Banks(address types.Word) []int
+	// PeekBank reads address with the given bank mapped
+	PeekBank(bank int, address types.Word) types.Byte
+}
+
+// Width is the size of the values searched for, in bytes
+type Width int
+
+const (
+	Width8  Width = 1
+	Width16 Width = 2
+)
+
+type Filter int
+
+const (
+	// EqualTo keeps values equal to the one given
+	EqualTo Filter = iota
+	// NotEqualTo keeps values different from the one given
+	NotEqualTo
+	Changed
+	Unchanged
+	Increased
+	Decreased
+)
+
+var filterNames = map[string]Filter{
+	"eq": EqualTo, "ne": NotEqualTo,
+	"changed": Changed, "unchanged": Unchanged,
+	"inc": Increased, "dec": Decreased,
+}
+
+// ParseFilter accepts eq, ne, changed, unchanged, inc and dec
+func ParseFilter(name string) (Filter, error) {
+	f, ok := filterNames[name]
+	if !ok {
+		return 0, fmt.Errorf("cheats: unknown filter %q", name)
+	}
+	return f, nil
+}
+
+// regions searched: cartridge RAM, WRAM and HRAM. Every bank of a region is
+// searched, whichever is mapped.
+var searchRegions = [][2]types.Word{
+	{0xA000, 0xBFFF},
+	{0xC000, 0xCFFF},
+	{0xD000, 0xDFFF},
+	{0xFF80, 0xFFFE},
+}
+
+// Candidate is an address still matching every filter so far. Bank is -1
+// where the region has a single bank.
+type Candidate struct {
+	Bank    int
+	Address types.Word
+	Value   int
+}
+
+// area is one bank of a searched region
+type area struct {
+	bank     int
+	banked   bool
+	from, to types.Word
+}
+
+type location struct {
+	area    int
+	address types.Word
+}
+
+// Search narrows down the RAM addresses holding a value, as the classic
+// cheat finders do: take a snapshot, play a bit, keep the addresses whose
+// value changed the expected way, repeat.
+type Search struct {
+	Width     Width
+	BigEndian bool
+
+	memory Reader
+	areas  []area
+	// snapshot holds the bytes of every area, at the same index
+	snapshot   [][]types.Byte
+	candidates []location
+}
+
+// NewSearch snapshots the RAM and starts with every address of every bank
+// as a candidate
+func NewSearch(m Reader, width Width, bigEndian bool) *Search {
+	s := &Search{Width: width, BigEndian: bigEndian, memory: m}
+	for _, region := range searchRegions {
+		banks := m.Banks(region[0])
+		for _, bank := range banks {
+			a := area{bank: bank, banked: len(banks) > 1, from: region[0], to: region[1]}
+			s.areas = append(s.areas, a)
+			s.snapshot = append(s.snapshot, make([]types.Byte, int(a.to-a.from)+1))
+			for address := int(a.from); address+int(width)-1 <= int(a.to); address++ {
+				s.candidates = append(s.candidates, location{len(s.areas) - 1, types.Word(address)})
+			}
+		}
+	}
+	s.take()
+	return s
+}
+
+// take snapshots the RAM to compare the next filter against
+func (s *Search) take() {
+	for i, a := range s.areas {
+		for address := int(a.from); address <= int(a.to); address++ {
+			s.snapshot[i][address-int(a.from)] = s.memory.PeekBank(a.bank, types.Word(address))
+		}
+	}
+}
+
+func (s *Search) value(read func(area int, address types.Word) types.Byte, l location) int {
+	if s.Width == Width8 {
+		return int(read(l.area, l.address))
+	}
+	lo, hi := read(l.area, l.address), read(l.area, l.address+1)
+	if s.BigEndian {
+		lo, hi = hi, lo
+	}
+	return int(hi)<<8 | int(lo)
+}
+
+func (s *Search) previous(area int, address types.Word) types.Byte {
+	return s.snapshot[area][address-s.areas[area].from]
+}
+
+func (s *Search) current(area int, address types.Word) types.Byte {
+	return s.memory.PeekBank(s.areas[area].bank, address)
+}
+
+// Filter drops the candidates that do not match and snapshots the RAM again.
+// value is only used by EqualTo and NotEqualTo. It returns how many
+// candidates are left.
+func (s *Search) Filter(f Filter, value int) int {
+	kept := s.candidates[:0]
+	for _, l := range s.candidates {
+		old := s.value(s.previous, l)
+		current := s.value(s.current, l)
+
+		var match bool
+		switch f {
+		case EqualTo:
+			match = current == value
+		case NotEqualTo:
+			match = current != value
+		case Changed:
+			match = current != old
+		case Unchanged:
+			match = current == old
+		case Increased:
+			match = current > old
+		case Decreased:
+			match = current < old
+		}
+		if match {
+			kept = append(kept, l)
+		}
+	}
+	s.candidates = kept
+	s.take()
+	return len(s.candidates)
+}
+
+// Len is the number of candidates left
+func (s *Search) Len() int {
+	return len(s.candidates)
+}
+
+// Candidates returns up to n candidates with their current values, all of
+// them when n is negative
+func (s *Search) Candidates(n int) []Candidate {
+	if n < 0 || n > len(s.candidates) {
+		n = len(s.candidates)
+	}
+	result := make([]Candidate, n)
+	for i, l := range s.candidates[:n] {
+		result[i] = Candidate{Bank: -1, Address: l.address, Value: s.value(s.current, l)}
+		if a := s.areas[l.area]; a.banked {
+			result[i].Bank = a.bank
+		}
+	}
+	return result
+}
+
+// GameSharkCode returns the code that keeps value at address, for freezing
+// what a search found. A WRAM bank from 1 to 7 at D000-DFFF gets a 9X code
+// that only writes while it is mapped; any other bank is ignored, as the
+// GameShark cannot select one.
+func GameSharkCode(bank int, address types.Word, value types.Byte) string {
+	kind := 0x01
+	if bank >= 1 && bank <= 7 && address >= 0xD000 && address < 0xE000 {
+		kind = 0x90 | bank
+	}
+	return fmt.Sprintf("%02X%02X%02X%02X", kind, value, address&0xFF, address>>8)
+}
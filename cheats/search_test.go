@@ -0,0 +1,141 @@
+package cheats
+
+import (
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// ram has two banks of cartridge RAM and, like the CGB, seven banks of WRAM
+// at D000-DFFF
+type ram struct {
+	flat [0x10000]types.Byte
+	sram [2][0x2000]types.Byte
+	wram [8][0x1000]types.Byte
+}
+
+func (r *ram) Banks(address types.Word) []int {
+	switch {
+	case address >= 0xA000 && address < 0xC000:
+		return []int{0, 1}
+	case address >= 0xD000 && address < 0xE000:
+		return []int{1, 2, 3, 4, 5, 6, 7}
+	}
+	return []int{0}
+}
+
+func (r *ram) PeekBank(bank int, address types.Word) types.Byte {
+	switch {
+	case address >= 0xA000 && address < 0xC000:
+		return r.sram[bank][address-0xA000]
+	case address >= 0xD000 && address < 0xE000:
+		return r.wram[bank][address-0xD000]
+	}
+	return r.flat[address]
+}
+
+func TestSearch8(t *testing.T) {
+	r := &ram{}
+	r.flat[0xC123], r.wram[1][0], r.flat[0xFF90] = 3, 3, 3
+	s := NewSearch(r, Width8, false)
+
+	if n := s.Filter(EqualTo, 3); n != 3 {
+		t.Fatalf("eq 3 left %d candidates, want 3", n)
+	}
+	r.flat[0xC123], r.wram[1][0] = 2, 4
+	if n := s.Filter(Decreased, 0); n != 1 {
+		t.Fatalf("dec left %d candidates, want 1", n)
+	}
+	got := s.Candidates(-1)
+	if got[0] != (Candidate{Bank: -1, Address: 0xC123, Value: 2}) {
+		t.Errorf("candidate = %+v", got[0])
+	}
+	// the filter compares against the previous search, not the first one
+	if n := s.Filter(Unchanged, 0); n != 1 {
+		t.Errorf("unchanged left %d candidates, want 1", n)
+	}
+	r.flat[0xC123] = 2
+	if n := s.Filter(Changed, 0); n != 0 {
+		t.Errorf("changed left %d candidates, want 0", n)
+	}
+}
+
+func TestSearch16(t *testing.T) {
+	for _, bigEndian := range []bool{false, true} {
+		r := &ram{}
+		hi, lo := types.Byte(0x12), types.Byte(0x34)
+		if bigEndian {
+			r.flat[0xC010], r.flat[0xC011] = hi, lo
+		} else {
+			r.flat[0xC010], r.flat[0xC011] = lo, hi
+		}
+		s := NewSearch(r, Width16, bigEndian)
+		if n := s.Filter(EqualTo, 0x1234); n != 1 {
+			t.Fatalf("big endian %t: eq left %d candidates, want 1", bigEndian, n)
+		}
+		r.flat[0xC010]++
+		if n := s.Filter(Increased, 0); n != 1 {
+			t.Errorf("big endian %t: inc left %d candidates, want 1", bigEndian, n)
+		}
+	}
+
+	// a 16-bit value does not straddle two regions
+	for _, c := range NewSearch(&ram{}, Width16, false).candidates {
+		if a := c.address; a == 0xBFFF || a == 0xCFFF || a == 0xDFFF || a == 0xFFFE {
+			t.Errorf("candidate 0x%04X runs past its region", a)
+		}
+	}
+}
+
+func TestSearchBanks(t *testing.T) {
+	r := &ram{}
+	r.sram[1][0x0123] = 0x5A
+	r.wram[5][0x0456] = 0x5A
+	s := NewSearch(r, Width8, false)
+
+	if n := s.Filter(EqualTo, 0x5A); n != 2 {
+		t.Fatalf("eq left %d candidates, want 2", n)
+	}
+	want := []Candidate{
+		{Bank: 1, Address: 0xA123, Value: 0x5A},
+		{Bank: 5, Address: 0xD456, Value: 0x5A},
+	}
+	for i, c := range s.Candidates(-1) {
+		if c != want[i] {
+			t.Errorf("candidate %d = %+v, want %+v", i, c, want[i])
+		}
+	}
+
+	r.wram[5][0x0456]++
+	if n := s.Filter(Increased, 0); n != 1 {
+		t.Errorf("inc left %d candidates, want 1", n)
+	}
+}
+
+func TestGameSharkCode(t *testing.T) {
+	tests := []struct {
+		bank    int
+		address types.Word
+		code    string
+		parsed  int
+	}{
+		{-1, 0xCD38, "010238CD", -1},
+		{3, 0xD2F5, "9302F5D2", 3},
+		// the GameShark cannot pick a cartridge RAM bank
+		{1, 0xA010, "010210A0", -1},
+	}
+	for _, tt := range tests {
+		code := GameSharkCode(tt.bank, tt.address, 0x02)
+		if code != tt.code {
+			t.Errorf("GameSharkCode(%d, %04X) = %q, want %q", tt.bank, tt.address, code, tt.code)
+			continue
+		}
+		c, err := Parse(code)
+		if err != nil || c.Address != tt.address || c.Value != 0x02 {
+			t.Errorf("Parse(%q) = %+v, %v", code, c, err)
+		}
+		if c.Bank != tt.parsed {
+			t.Errorf("Parse(%q) bank %d, want %d", code, c.Bank, tt.parsed)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/cgimenes/gomenes-boy/gameboy"
+)
+
+// debugCommand loads a ROM and hands it to the debugger REPL
+func debugCommand(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ContinueOnError)
+	romPath := flags.String("rom", "", "cartridge ROM to debug")
+	breakList := flags.String("break", "", "comma separated PCs to stop at, in hex")
+	boot := addBootFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if *romPath == "" {
+		fmt.Fprintln(os.Stderr, "debug: --rom is required")
+		return exitError
+	}
+
+	breakpoints, err := parseBreakpoints(*breakList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "debug: %v\n", err)
+		return exitError
+	}
+	rom, err := os.ReadFile(*romPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "debug: %v\n", err)
+		return exitError
+	}
+	var opts gameboy.Options
+	if err := boot.apply(&opts); err != nil {
+		fmt.Fprintf(os.Stderr, "debug: %v\n", err)
+		return exitError
+	}
+	gb, err := gameboy.New(rom, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "debug: %v\n", err)
+		return exitError
+	}
+	defer gb.Close()
+
+	d := gb.Debugger()
+	for pc := range breakpoints {
+		d.AddBreakpoint(pc)
+	}
+
+	// ctrl-c stops the CPU instead of the process
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	interrupt := make(chan struct{}, 1)
+	go func() {
+		for range signals {
+			select {
+			case interrupt <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	d.Interrupt = interrupt
+
+	if err := d.REPL(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "debug: %v\n", err)
+		return exitError
+	}
+	return exitCompleted
+}
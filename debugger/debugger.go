@@ -0,0 +1,165 @@
+package debugger
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/cgimenes/gomenes-boy/cheats"
+	"github.com/cgimenes/gomenes-boy/disasm"
+	"github.com/cgimenes/gomenes-boy/hardware/cpu"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+var (
+	ErrBreakpoint  = errors.New("breakpoint")
+	ErrInterrupted = errors.New("interrupted")
+)
+
+// Debugger drives a CPU one instruction at a time and stops it on breakpoints
+type Debugger struct {
+	CPU *cpu.CPU
+	// Interrupt stops a running CPU when something is sent on it
+	Interrupt <-chan struct{}
+
+	breakpoints map[types.Word]bool
+	watchpoints []Watchpoint
+	nextWatchID int
+	hit         *Hit
+	// PC of the instruction being executed, for watchpoint hits
+	instructionPC types.Word
+	// RAM search started from the REPL
+	search *cheats.Search
+}
+
+func New(c *cpu.CPU) *Debugger {
+	return &Debugger{CPU: c, breakpoints: map[types.Word]bool{}}
+}
+
+func (d *Debugger) AddBreakpoint(pc types.Word) {
+	d.breakpoints[pc] = true
+}
+
+func (d *Debugger) RemoveBreakpoint(pc types.Word) bool {
+	if !d.breakpoints[pc] {
+		return false
+	}
+	delete(d.breakpoints, pc)
+	return true
+}
+
+func (d *Debugger) Breakpoints() []types.Word {
+	var pcs []types.Word
+	for pc := range d.breakpoints {
+		pcs = append(pcs, pc)
+	}
+	sort.Slice(pcs, func(i, j int) bool { return pcs[i] < pcs[j] })
+	return pcs
+}
+
+// Step executes n instructions, stopping early on a breakpoint or watchpoint
+func (d *Debugger) Step(n int) error {
+	d.hit = nil
+	for i := 0; i < n; i++ {
+		if err := d.step(); err != nil {
+			return err
+		}
+		if i < n-1 && d.breakpoints[d.CPU.PC()] {
+			return ErrBreakpoint
+		}
+	}
+	return nil
+}
+
+// Continue runs until a breakpoint, an error or an interrupt
+func (d *Debugger) Continue() error {
+	return d.runUntil(func() bool { return false })
+}
+
+// Next steps over calls, running the called routine to completion
+func (d *Debugger) Next() error {
+	inst := disasm.At(d.CPU.MMU().Peek, d.CPU.PC())
+	if !strings.HasPrefix(inst.Text, "CALL") && !strings.HasPrefix(inst.Text, "RST") {
+		return d.Step(1)
+	}
+
+	ret := inst.Address + types.Word(inst.Length())
+	sp := d.CPU.Registers().SP.Get()
+	returned := func() bool {
+		return d.CPU.PC() == ret && d.CPU.Registers().SP.Get() >= sp
+	}
+	if err := d.Step(1); err != nil {
+		return err
+	}
+	// a conditional call that is not taken is already past
+	if returned() {
+		return nil
+	}
+	if d.breakpoints[d.CPU.PC()] {
+		return ErrBreakpoint
+	}
+	return d.runUntil(returned)
+}
+
+// Finish runs until the current routine returns to its caller
+func (d *Debugger) Finish() error {
+	sp := d.CPU.Registers().SP.Get()
+	returned := false
+	return d.runUntilAfter(func(opcode types.Byte) {
+		returned = isReturn(opcode) && d.CPU.Registers().SP.Get() > sp
+	}, func() bool { return returned })
+}
+
+func isReturn(opcode types.Byte) bool {
+	switch opcode {
+	case 0xC9, 0xD9, 0xC0, 0xC8, 0xD0, 0xD8:
+		return true
+	}
+	return false
+}
+
+func (d *Debugger) runUntil(done func() bool) error {
+	return d.runUntilAfter(nil, done)
+}
+
+// runUntilAfter steps until done reports true, calling after with the opcode
+// of every executed instruction. The first instruction always runs so that
+// continuing from a breakpoint moves on.
+func (d *Debugger) runUntilAfter(after func(opcode types.Byte), done func() bool) error {
+	d.hit = nil
+	for first := true; ; first = false {
+		if !first {
+			if done() {
+				return nil
+			}
+			if d.breakpoints[d.CPU.PC()] {
+				return ErrBreakpoint
+			}
+			select {
+			case <-d.Interrupt:
+				return ErrInterrupted
+			default:
+			}
+		}
+
+		opcode := d.CPU.MMU().Peek(d.CPU.PC())
+		if err := d.step(); err != nil {
+			return err
+		}
+		if after != nil {
+			after(opcode)
+		}
+	}
+}
+
+// step executes one instruction and reports a watchpoint it triggered
+func (d *Debugger) step() error {
+	d.instructionPC = d.CPU.PC()
+	if _, err := d.CPU.Step(); err != nil {
+		return err
+	}
+	if d.hit != nil {
+		return ErrWatchpoint
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package debugger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cgimenes/gomenes-boy/hardware/cartridge"
+	"github.com/cgimenes/gomenes-boy/hardware/cpu"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// newDebugger starts program at 0x100 with the boot ROM skipped. A running
+// CPU is interrupted after a second so a broken test cannot hang.
+func newDebugger(t *testing.T, program []types.Byte) *Debugger {
+	t.Helper()
+
+	rom := make([]types.Byte, 0x8000)
+	copy(rom[0x100:], program)
+	cart, err := cartridge.New(rom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &cpu.CPU{}
+	c.Init()
+	c.LoadCartridge(cart)
+	c.SkipBootROM()
+
+	interrupt := make(chan struct{})
+	timer := time.AfterFunc(time.Second, func() { close(interrupt) })
+	t.Cleanup(func() { timer.Stop() })
+
+	d := New(c)
+	d.Interrupt = interrupt
+	return d
+}
+
+func TestNext(t *testing.T) {
+	program := make([]types.Byte, 0x120)
+	copy(program, []types.Byte{
+		0xAF,             // 0100: XOR A
+		0xC4, 0x10, 0x01, // 0101: CALL NZ,$0110
+		0xCC, 0x10, 0x01, // 0104: CALL Z,$0110
+		0xC7,             // 0107: RST $00
+		0xCD, 0x10, 0x01, // 0108: CALL $0110
+		0x00, // 010B: NOP
+	})
+	copy(program[0x10:], []types.Byte{
+		0x04, // 0110: INC B
+		0xC9, // 0111: RET
+	})
+	d := newDebugger(t, program)
+	// RST $00 lands in a RET
+	d.CPU.MMU().Cartridge.ROM[0x0000] = 0xC9
+	b := d.CPU.Registers().B.Get()
+
+	if err := d.Step(1); err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		name string
+		pc   types.Word
+		b    types.Byte
+	}{
+		{"CALL NZ not taken", 0x0104, b},
+		{"CALL Z taken", 0x0107, b + 1},
+		{"RST", 0x0108, b + 1},
+	}
+	for _, s := range steps {
+		if err := d.Next(); err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if pc := d.CPU.PC(); pc != s.pc {
+			t.Fatalf("%s: stopped at %04X, want %04X", s.name, pc, s.pc)
+		}
+		if got := d.CPU.Registers().B.Get(); got != s.b {
+			t.Errorf("%s: B = %02X, want %02X", s.name, got, s.b)
+		}
+	}
+
+	// a breakpoint in the called routine still stops it
+	d.AddBreakpoint(0x0110)
+	if err := d.Next(); err != ErrBreakpoint {
+		t.Fatalf("CALL into a breakpoint: got %v, want %v", err, ErrBreakpoint)
+	}
+	if pc := d.CPU.PC(); pc != 0x0110 {
+		t.Fatalf("stopped at %04X, want the breakpoint at 0110", pc)
+	}
+}
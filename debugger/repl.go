@@ -0,0 +1,434 @@
+package debugger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cgimenes/gomenes-boy/cheats"
+	"github.com/cgimenes/gomenes-boy/disasm"
+	"github.com/cgimenes/gomenes-boy/hardware/cpu"
+	"github.com/cgimenes/gomenes-boy/hardware/cpu/registers"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+const help = `commands:
+  s, step [n]          execute n instructions (default 1)
+  n, next              step over calls
+  c, continue          run until a breakpoint or ctrl-c
+  finish               run until the current routine returns
+  b, break <addr>      set a breakpoint
+  d, delete <addr>     remove a breakpoint
+  breaks               list breakpoints
+  watch <addr>[-<end>] [read|write|access] [value]
+                       stop when memory is accessed, writes are watched by
+                       default and a value only matches writes of it
+  unwatch <id>         remove a watchpoint
+  watches              list watchpoints
+  r, regs              show registers and flags
+  x <addr> [len]       hex dump memory
+  set <reg> <value>    change a register (a f b c d e h l af bc de hl sp pc)
+  flag <z|n|h|c> <0|1> change a flag
+  w, write <addr> <byte>...
+                       write bytes to memory
+  search start [8|16] [le|be]
+                       snapshot RAM and consider every address
+  search <eq|ne> <value>
+  search <changed|unchanged|inc|dec>
+                       keep the addresses matching, against the last search
+  search list [n]      show up to n candidates (default 20)
+  q, quit              leave the debugger
+an empty line repeats the last command`
+
+var errQuit = errors.New("quit")
+
+// REPL reads commands from in until quit or end of input. An empty line
+// repeats the previous command.
+func (d *Debugger) REPL(in io.Reader, out io.Writer) error {
+	d.printRegisters(out)
+
+	scanner := bufio.NewScanner(in)
+	last := ""
+	for {
+		fmt.Fprint(out, "(gb) ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			line = last
+		}
+		last = line
+		if line == "" {
+			continue
+		}
+
+		err := d.exec(strings.Fields(line), out)
+		if err == errQuit {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(out, "%v\n", err)
+		}
+	}
+}
+
+func (d *Debugger) exec(args []string, out io.Writer) error {
+	switch args[0] {
+	case "s", "step":
+		n := 1
+		if len(args) > 1 {
+			v, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("bad count %q", args[1])
+			}
+			n = v
+		}
+		return d.stopped(d.Step(n), out)
+	case "n", "next":
+		return d.stopped(d.Next(), out)
+	case "c", "continue":
+		return d.stopped(d.Continue(), out)
+	case "finish":
+		return d.stopped(d.Finish(), out)
+	case "b", "break":
+		address, err := argAddress(args, 1)
+		if err != nil {
+			return err
+		}
+		d.AddBreakpoint(address)
+		fmt.Fprintf(out, "breakpoint at 0x%04X\n", address)
+	case "d", "delete":
+		address, err := argAddress(args, 1)
+		if err != nil {
+			return err
+		}
+		if !d.RemoveBreakpoint(address) {
+			return fmt.Errorf("no breakpoint at 0x%04X", address)
+		}
+	case "breaks":
+		for _, pc := range d.Breakpoints() {
+			fmt.Fprintf(out, "0x%04X\n", pc)
+		}
+	case "watch":
+		w, err := parseWatchpoint(args[1:])
+		if err != nil {
+			return err
+		}
+		w.ID = d.AddWatchpoint(w)
+		fmt.Fprintf(out, "watchpoint %v\n", w)
+	case "unwatch":
+		if len(args) != 2 {
+			return errors.New("usage: unwatch <id>")
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil || !d.RemoveWatchpoint(id) {
+			return fmt.Errorf("no watchpoint %q", args[1])
+		}
+	case "watches":
+		for _, w := range d.Watchpoints() {
+			fmt.Fprintln(out, w)
+		}
+	case "r", "regs":
+		d.printRegisters(out)
+	case "x":
+		address, err := argAddress(args, 1)
+		if err != nil {
+			return err
+		}
+		length := 64
+		if len(args) > 2 {
+			v, err := parseNumber(args[2], 0x10000)
+			if err != nil {
+				return err
+			}
+			length = v
+		}
+		d.dump(out, address, length)
+	case "set":
+		if len(args) != 3 {
+			return errors.New("usage: set <reg> <value>")
+		}
+		value, err := parseNumber(args[2], 0x10000)
+		if err != nil {
+			return err
+		}
+		return d.setRegister(args[1], value)
+	case "flag":
+		if len(args) != 3 {
+			return errors.New("usage: flag <z|n|h|c> <0|1>")
+		}
+		return d.setFlag(args[1], args[2])
+	case "w", "write":
+		address, err := argAddress(args, 1)
+		if err != nil {
+			return err
+		}
+		if len(args) < 3 {
+			return errors.New("usage: write <addr> <byte>...")
+		}
+		for i, arg := range args[2:] {
+			value, err := parseNumber(arg, 0x100)
+			if err != nil {
+				return err
+			}
+			d.CPU.MMU().Set(address+types.Word(i), types.Byte(value))
+		}
+		d.CPU.FlushBlocks()
+	case "search":
+		return d.searchCommand(args[1:], out)
+	case "h", "help":
+		fmt.Fprintln(out, help)
+	case "q", "quit":
+		return errQuit
+	default:
+		return fmt.Errorf("unknown command %q, try help", args[0])
+	}
+	return nil
+}
+
+func (d *Debugger) searchCommand(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: search start|list|<filter> [value]")
+	}
+	switch args[0] {
+	case "start":
+		width, bigEndian := cheats.Width8, false
+		for _, arg := range args[1:] {
+			switch arg {
+			case "8":
+				width = cheats.Width8
+			case "16":
+				width = cheats.Width16
+			case "le":
+				bigEndian = false
+			case "be":
+				bigEndian = true
+			default:
+				return fmt.Errorf("bad search option %q", arg)
+			}
+		}
+		d.search = cheats.NewSearch(d.CPU.MMU(), width, bigEndian)
+		fmt.Fprintf(out, "%d candidates\n", d.search.Len())
+		return nil
+	}
+
+	if d.search == nil {
+		return errors.New("no search, use search start")
+	}
+	if args[0] == "list" {
+		n := 20
+		if len(args) > 1 {
+			v, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("bad count %q", args[1])
+			}
+			n = v
+		}
+		for _, c := range d.search.Candidates(n) {
+			location := fmt.Sprintf("0x%04X", c.Address)
+			if c.Bank >= 0 {
+				location = fmt.Sprintf("%X:%04X", c.Bank, c.Address)
+			}
+			if d.search.Width == cheats.Width8 {
+				fmt.Fprintf(out, "%s = 0x%02X (%d)  %s\n", location, c.Value, c.Value, cheats.GameSharkCode(c.Bank, c.Address, types.Byte(c.Value)))
+			} else {
+				fmt.Fprintf(out, "%s = 0x%04X (%d)\n", location, c.Value, c.Value)
+			}
+		}
+		if n < d.search.Len() {
+			fmt.Fprintf(out, "%d more\n", d.search.Len()-n)
+		}
+		return nil
+	}
+
+	f, err := cheats.ParseFilter(args[0])
+	if err != nil {
+		return fmt.Errorf("unknown search command %q", args[0])
+	}
+	value := 0
+	if f == cheats.EqualTo || f == cheats.NotEqualTo {
+		if len(args) != 2 {
+			return fmt.Errorf("usage: search %s <value>", args[0])
+		}
+		if value, err = parseNumber(args[1], 1<<(8*d.search.Width)); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "%d candidates\n", d.search.Filter(f, value))
+	return nil
+}
+
+// stopped reports why execution stopped and where
+func (d *Debugger) stopped(err error, out io.Writer) error {
+	var unimplemented cpu.ErrUnimplementedOpcode
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrBreakpoint):
+		fmt.Fprintf(out, "breakpoint at 0x%04X\n", d.CPU.PC())
+	case errors.Is(err, ErrWatchpoint):
+		hit, _ := d.LastHit()
+		fmt.Fprintln(out, hit)
+	case errors.Is(err, ErrInterrupted):
+		fmt.Fprintln(out, "interrupted")
+	case errors.As(err, &unimplemented):
+		fmt.Fprintf(out, "%v\n", err)
+	default:
+		return err
+	}
+	d.printRegisters(out)
+	return nil
+}
+
+func (d *Debugger) printRegisters(out io.Writer) {
+	r := d.CPU.Registers()
+	flag := func(f registers.Flag, name string) string {
+		if r.Flags.Get(f) == 1 {
+			return name
+		}
+		return "-"
+	}
+	fmt.Fprintf(out, "AF=%04X BC=%04X DE=%04X HL=%04X SP=%04X PC=%04X flags=%s%s%s%s IME=%t halted=%t\n",
+		r.AF.Get(), r.BC.Get(), r.DE.Get(), r.HL.Get(), r.SP.Get(), r.PC.Get(),
+		flag(registers.Z, "Z"), flag(registers.N, "N"), flag(registers.H, "H"), flag(registers.C, "C"),
+		d.CPU.IME(), d.CPU.Halted())
+
+	fmt.Fprintln(out, disasm.At(d.CPU.MMU().Peek, r.PC.Get()))
+}
+
+func (d *Debugger) dump(out io.Writer, address types.Word, length int) {
+	for row := 0; row < length; row += 16 {
+		start := address + types.Word(row)
+		fmt.Fprintf(out, "%04X:", start)
+		var ascii strings.Builder
+		for i := 0; i < 16 && row+i < length; i++ {
+			b := d.CPU.MMU().Peek(start + types.Word(i))
+			fmt.Fprintf(out, " %02X", b)
+			if b >= 0x20 && b < 0x7F {
+				ascii.WriteByte(b)
+			} else {
+				ascii.WriteByte('.')
+			}
+		}
+		fmt.Fprintf(out, "  %s\n", ascii.String())
+	}
+}
+
+func (d *Debugger) setRegister(name string, value int) error {
+	r := d.CPU.Registers()
+	bytes := map[string]*types.ByteRegister{
+		"a": &r.A, "f": &r.F, "b": &r.B, "c": &r.C,
+		"d": &r.D, "e": &r.E, "h": &r.H, "l": &r.L,
+	}
+	words := map[string]registers.WordRegister{
+		"af": &r.AF, "bc": &r.BC, "de": &r.DE, "hl": &r.HL,
+		"sp": &r.SP, "pc": &r.PC,
+	}
+
+	name = strings.ToLower(name)
+	if reg, ok := bytes[name]; ok {
+		if value > 0xFF {
+			return fmt.Errorf("0x%X does not fit in %s", value, name)
+		}
+		if name == "f" {
+			// the low nibble of F is always zero
+			value &= 0xF0
+		}
+		reg.Set(types.Byte(value))
+		return nil
+	}
+	if reg, ok := words[name]; ok {
+		if name == "af" {
+			value &= 0xFFF0
+		}
+		reg.Set(types.Word(value))
+		return nil
+	}
+	return fmt.Errorf("unknown register %q", name)
+}
+
+func (d *Debugger) setFlag(name, value string) error {
+	flags := map[string]registers.Flag{"z": registers.Z, "n": registers.N, "h": registers.H, "c": registers.C}
+	f, ok := flags[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unknown flag %q", name)
+	}
+	switch value {
+	case "1":
+		d.CPU.Registers().Flags.Set(f)
+	case "0":
+		d.CPU.Registers().Flags.Reset(f)
+	default:
+		return fmt.Errorf("flag value must be 0 or 1")
+	}
+	return nil
+}
+
+func parseWatchpoint(args []string) (Watchpoint, error) {
+	if len(args) == 0 {
+		return Watchpoint{}, errors.New("usage: watch <addr>[-<end>] [read|write|access] [value]")
+	}
+
+	from, to, found := strings.Cut(args[0], "-")
+	start, err := parseNumber(from, 0x10000)
+	if err != nil {
+		return Watchpoint{}, err
+	}
+	end := start
+	if found {
+		if end, err = parseNumber(to, 0x10000); err != nil {
+			return Watchpoint{}, err
+		}
+		if end < start {
+			return Watchpoint{}, fmt.Errorf("range %s is backwards", args[0])
+		}
+	}
+	w := Watchpoint{From: types.Word(start), To: types.Word(end), Write: true}
+
+	for _, arg := range args[1:] {
+		switch arg {
+		case "read":
+			w.Read, w.Write = true, false
+		case "write":
+			w.Read, w.Write = false, true
+		case "access":
+			w.Read, w.Write = true, true
+		default:
+			value, err := parseNumber(arg, 0x100)
+			if err != nil {
+				return Watchpoint{}, err
+			}
+			w.Match, w.Value = true, types.Byte(value)
+		}
+	}
+	return w, nil
+}
+
+func argAddress(args []string, i int) (types.Word, error) {
+	if len(args) <= i {
+		return 0, errors.New("missing address")
+	}
+	v, err := parseNumber(args[i], 0x10000)
+	return types.Word(v), err
+}
+
+// parseNumber reads hex with a 0x or $ prefix, decimal otherwise
+func parseNumber(s string, limit int) (int, error) {
+	base := 10
+	digits := s
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		base, digits = 16, s[2:]
+	} else if strings.HasPrefix(s, "$") {
+		base, digits = 16, s[1:]
+	}
+	v, err := strconv.ParseUint(digits, base, 32)
+	if err != nil || int(v) >= limit {
+		return 0, fmt.Errorf("bad number %q", s)
+	}
+	return int(v), nil
+}
@@ -0,0 +1,119 @@
+package debugger
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+var ErrWatchpoint = errors.New("watchpoint")
+
+// Watchpoint stops execution when an address in [From, To] is accessed
+type Watchpoint struct {
+	ID    int
+	From  types.Word
+	To    types.Word
+	Read  bool
+	Write bool
+	// Match only triggers on writes of Value
+	Match bool
+	Value types.Byte
+}
+
+func (w Watchpoint) String() string {
+	access := "access"
+	if !w.Read {
+		access = "write"
+	} else if !w.Write {
+		access = "read"
+	}
+	s := fmt.Sprintf("%d: %s 0x%04X", w.ID, access, w.From)
+	if w.To != w.From {
+		s += fmt.Sprintf("-0x%04X", w.To)
+	}
+	if w.Match {
+		s += fmt.Sprintf(" == 0x%02X", w.Value)
+	}
+	return s
+}
+
+// Hit describes the access that triggered a watchpoint
+type Hit struct {
+	Watchpoint Watchpoint
+	Address    types.Word
+	Value      types.Byte
+	Write      bool
+	// PC of the instruction that made the access
+	PC types.Word
+}
+
+func (h Hit) String() string {
+	if h.Write {
+		return fmt.Sprintf("watchpoint %d: write 0x%02X to 0x%04X at PC=0x%04X", h.Watchpoint.ID, h.Value, h.Address, h.PC)
+	}
+	return fmt.Sprintf("watchpoint %d: read 0x%02X from 0x%04X at PC=0x%04X", h.Watchpoint.ID, h.Value, h.Address, h.PC)
+}
+
+// AddWatchpoint installs w and returns its id. The debugger only hooks the
+// MMU while at least one watchpoint exists.
+func (d *Debugger) AddWatchpoint(w Watchpoint) int {
+	d.nextWatchID++
+	w.ID = d.nextWatchID
+	d.watchpoints = append(d.watchpoints, w)
+	d.CPU.MMU().Watcher = d
+	return w.ID
+}
+
+func (d *Debugger) RemoveWatchpoint(id int) bool {
+	for i, w := range d.watchpoints {
+		if w.ID == id {
+			d.watchpoints = append(d.watchpoints[:i], d.watchpoints[i+1:]...)
+			if len(d.watchpoints) == 0 {
+				d.CPU.MMU().Watcher = nil
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Debugger) Watchpoints() []Watchpoint {
+	return d.watchpoints
+}
+
+// LastHit returns the access that stopped the last run
+func (d *Debugger) LastHit() (Hit, bool) {
+	if d.hit == nil {
+		return Hit{}, false
+	}
+	return *d.hit, true
+}
+
+func (d *Debugger) Read(address types.Word, value types.Byte) {
+	d.check(address, value, false)
+}
+
+func (d *Debugger) Write(address types.Word, value types.Byte) {
+	d.check(address, value, true)
+}
+
+func (d *Debugger) check(address types.Word, value types.Byte, write bool) {
+	if d.hit != nil {
+		// the first access of an instruction wins
+		return
+	}
+	for _, w := range d.watchpoints {
+		if address < w.From || address > w.To {
+			continue
+		}
+		if (write && !w.Write) || (!write && !w.Read) {
+			continue
+		}
+		if w.Match && (!write || value != w.Value) {
+			continue
+		}
+		d.hit = &Hit{Watchpoint: w, Address: address, Value: value, Write: write, PC: d.instructionPC}
+		return
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cgimenes/gomenes-boy/disasm"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+const romBankSize = 0x4000
+
+// disasmCommand prints the instructions in a bank of a ROM file
+func disasmCommand(args []string) int {
+	flags := flag.NewFlagSet("disasm", flag.ContinueOnError)
+	bank := flags.Int("bank", 0, "ROM bank to disassemble")
+	fromFlag := flags.String("from", "", "address to start at, in hex (default start of the bank)")
+	count := flags.Int("count", 0, "number of instructions to print (default up to the end of the bank)")
+
+	// the ROM may come before the flags
+	var romPath string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		romPath, args = args[0], args[1:]
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if romPath == "" && flags.NArg() > 0 {
+		romPath = flags.Arg(0)
+	}
+	if romPath == "" {
+		fmt.Fprintln(os.Stderr, "usage: gomenes-boy disasm rom.gb [--bank n] [--from addr] [--count n]")
+		return exitError
+	}
+
+	rom, err := os.ReadFile(romPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "disasm: %v\n", err)
+		return exitError
+	}
+	if *bank < 0 || (*bank+1)*romBankSize > len(rom) {
+		fmt.Fprintf(os.Stderr, "disasm: ROM has no bank %d\n", *bank)
+		return exitError
+	}
+
+	// bank 0 is always at 0x0000, the others are switched in at 0x4000
+	start := types.Word(0x0000)
+	if *bank > 0 {
+		start = romBankSize
+	}
+	end := int(start) + romBankSize
+
+	from := start
+	if *fromFlag != "" {
+		v, err := parseBreakpoints(*fromFlag)
+		if err != nil || len(v) != 1 {
+			fmt.Fprintf(os.Stderr, "disasm: bad address %q\n", *fromFlag)
+			return exitError
+		}
+		for address := range v {
+			from = address
+		}
+	}
+	if int(from) < int(start) || int(from) >= end {
+		fmt.Fprintf(os.Stderr, "disasm: 0x%04X is outside bank %d\n", from, *bank)
+		return exitError
+	}
+
+	read := func(address types.Word) types.Byte {
+		i := *bank*romBankSize + int(address) - int(start)
+		if int(address) < int(start) || int(address) >= end {
+			return 0xFF
+		}
+		return rom[i]
+	}
+
+	address := int(from)
+	for n := 0; address < end && (*count == 0 || n < *count); n++ {
+		inst := disasm.At(read, types.Word(address))
+		fmt.Println(inst)
+		address += inst.Length()
+	}
+	return exitCompleted
+}
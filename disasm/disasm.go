@@ -0,0 +1,86 @@
+package disasm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cgimenes/gomenes-boy/hardware/cpu"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// Reader returns the byte at an address, e.g. memory.MMU.Peek
+type Reader func(address types.Word) types.Byte
+
+type Instruction struct {
+	Address types.Word
+	Bytes   []types.Byte
+	Text    string
+}
+
+func (i Instruction) Length() int {
+	return len(i.Bytes)
+}
+
+func (i Instruction) String() string {
+	hex := make([]string, len(i.Bytes))
+	for n, b := range i.Bytes {
+		hex[n] = fmt.Sprintf("%02X", b)
+	}
+	return fmt.Sprintf("%04X: %-9s %s", i.Address, strings.Join(hex, " "), i.Text)
+}
+
+// At decodes the instruction at address using the CPU opcode tables
+func At(read Reader, address types.Word) Instruction {
+	opcode := read(address)
+	info := cpu.Opcodes[opcode]
+	if opcode == 0xCB {
+		info = cpu.CBOpcodes[read(address+1)]
+	}
+	if info.Illegal() {
+		return Instruction{Address: address, Bytes: []types.Byte{opcode}, Text: fmt.Sprintf("DB $%02X", opcode)}
+	}
+
+	bytes := make([]types.Byte, info.Length)
+	for i := range bytes {
+		bytes[i] = read(address + types.Word(i))
+	}
+	return Instruction{Address: address, Bytes: bytes, Text: operands(info.Mnemonic, address, bytes)}
+}
+
+// operands replaces the operand placeholder of a mnemonic with its value
+func operands(mnemonic string, address types.Word, bytes []types.Byte) string {
+	switch {
+	case strings.Contains(mnemonic, "n16"):
+		return strings.Replace(mnemonic, "n16", fmt.Sprintf("$%04X", word(bytes)), 1)
+	case strings.Contains(mnemonic, "a16"):
+		return strings.Replace(mnemonic, "a16", fmt.Sprintf("$%04X", word(bytes)), 1)
+	case strings.Contains(mnemonic, "n8"):
+		return strings.Replace(mnemonic, "n8", fmt.Sprintf("$%02X", bytes[1]), 1)
+	case strings.Contains(mnemonic, "a8"):
+		return strings.Replace(mnemonic, "a8", fmt.Sprintf("$FF%02X", bytes[1]), 1)
+	case strings.HasPrefix(mnemonic, "JR"):
+		// relative to the start of the instruction, like $ in assemblers
+		offset := int(int8(bytes[1])) + len(bytes)
+		return strings.Replace(mnemonic, "e8", fmt.Sprintf("$%+d", offset), 1)
+	case strings.Contains(mnemonic, "SP+e8"):
+		return strings.Replace(mnemonic, "SP+e8", fmt.Sprintf("SP%+d", int8(bytes[1])), 1)
+	case strings.Contains(mnemonic, "e8"):
+		return strings.Replace(mnemonic, "e8", fmt.Sprintf("%d", int8(bytes[1])), 1)
+	}
+	return mnemonic
+}
+
+func word(bytes []types.Byte) types.Word {
+	return types.WordFromBytes(bytes[2], bytes[1])
+}
+
+// Range decodes count instructions starting at address
+func Range(read Reader, address types.Word, count int) []Instruction {
+	var instructions []Instruction
+	for i := 0; i < count; i++ {
+		inst := At(read, address)
+		instructions = append(instructions, inst)
+		address += types.Word(inst.Length())
+	}
+	return instructions
+}
@@ -0,0 +1,95 @@
+package disasm
+
+import (
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// memory reads program from 0x0100, every other address holds 0x00
+func memory(program ...types.Byte) Reader {
+	return func(address types.Word) types.Byte {
+		if address >= 0x0100 && int(address-0x0100) < len(program) {
+			return program[address-0x0100]
+		}
+		return 0x00
+	}
+}
+
+func TestAt(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []types.Byte
+		text    string
+		length  int
+	}{
+		{"no operand", []types.Byte{0x00}, "NOP", 1},
+		{"n16", []types.Byte{0x01, 0x34, 0x12}, "LD BC,$1234", 3},
+		{"a16", []types.Byte{0xC3, 0x50, 0x01}, "JP $0150", 3},
+		{"a16 store", []types.Byte{0xEA, 0x00, 0xC0}, "LD ($C000),A", 3},
+		{"n8", []types.Byte{0x3E, 0x42}, "LD A,$42", 2},
+		{"a8", []types.Byte{0xE0, 0x40}, "LDH ($FF40),A", 2},
+		{"a8 load", []types.Byte{0xF0, 0x44}, "LDH A,($FF44)", 2},
+		{"JR forward", []types.Byte{0x18, 0x05}, "JR $+7", 2},
+		{"JR to itself", []types.Byte{0x18, 0xFE}, "JR $+0", 2},
+		{"JR backward", []types.Byte{0x20, 0xFB}, "JR NZ,$-3", 2},
+		{"SP+e8", []types.Byte{0xF8, 0xFF}, "LD HL,SP-1", 2},
+		{"SP+e8 positive", []types.Byte{0xF8, 0x08}, "LD HL,SP+8", 2},
+		{"ADD SP,e8", []types.Byte{0xE8, 0xF0}, "ADD SP,-16", 2},
+		{"CB register", []types.Byte{0xCB, 0x7C}, "BIT 7,H", 2},
+		{"CB (HL)", []types.Byte{0xCB, 0x36}, "SWAP (HL)", 2},
+		{"CB shift", []types.Byte{0xCB, 0x00}, "RLC B", 2},
+		{"illegal", []types.Byte{0xD3, 0x12}, "DB $D3", 1},
+		{"illegal FD", []types.Byte{0xFD}, "DB $FD", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := At(memory(tt.program...), 0x0100)
+			if inst.Text != tt.text {
+				t.Errorf("text %q, want %q", inst.Text, tt.text)
+			}
+			if inst.Length() != tt.length {
+				t.Errorf("length %d, want %d", inst.Length(), tt.length)
+			}
+			for i, b := range inst.Bytes {
+				if b != tt.program[i] {
+					t.Errorf("byte %d is %02X, want %02X", i, b, tt.program[i])
+				}
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	inst := At(memory(0xC3, 0x50, 0x01), 0x0100)
+	if got, want := inst.String(), "0100: C3 50 01  JP $0150"; got != want {
+		t.Errorf("%q, want %q", got, want)
+	}
+}
+
+func TestRange(t *testing.T) {
+	read := memory(
+		0x3E, 0x42, // LD A,$42
+		0xCB, 0x37, // SWAP A
+		0xDD,             // illegal
+		0xC3, 0x00, 0x01, // JP $0100
+	)
+	want := []struct {
+		address types.Word
+		text    string
+	}{
+		{0x0100, "LD A,$42"},
+		{0x0102, "SWAP A"},
+		{0x0104, "DB $DD"},
+		{0x0105, "JP $0100"},
+	}
+	got := Range(read, 0x0100, len(want))
+	if len(got) != len(want) {
+		t.Fatalf("%d instructions, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Address != w.address || got[i].Text != w.text {
+			t.Errorf("instruction %d is %04X %q, want %04X %q", i, got[i].Address, got[i].Text, w.address, w.text)
+		}
+	}
+}
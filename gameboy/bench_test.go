@@ -0,0 +1,119 @@
+package gameboy
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/memory"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// benchROM builds a 32KB cartridge that passes the boot ROM checks and
+// runs program from 0x150
+func benchROM(program []types.Byte) []types.Byte {
+	rom := make([]types.Byte, 0x8000)
+	// NOP; JP $0150
+	copy(rom[0x100:], []types.Byte{0x00, 0xC3, 0x50, 0x01})
+	// the boot ROM carries the logo it compares the header against
+	copy(rom[0x104:0x134], memory.BootROM[0xA8:0xD8])
+	copy(rom[0x134:], "BENCH")
+	setHeaderChecksum(rom)
+
+	copy(rom[0x150:], program)
+	return rom
+}
+
+// setHeaderChecksum fixes up the header checksum the boot ROM verifies
+func setHeaderChecksum(rom []types.Byte) {
+	var checksum types.Byte
+	for _, b := range rom[0x134:0x14D] {
+		checksum = checksum - b - 1
+	}
+	rom[0x14D] = checksum
+}
+
+// frameProgram turns the LCD on with the background and sprites enabled
+// and keeps the CPU busy in a loop touching WRAM and VRAM
+var frameProgram = []types.Byte{
+	0x3E, 0x93, // LD A,$93
+	0xE0, 0x40, // LDH ($FF40),A
+	0x21, 0x00, 0xC0, // LD HL,$C000
+	0x04,             // INC B
+	0x70,             // LD (HL),B
+	0x78,             // LD A,B
+	0xEA, 0x00, 0x98, // LD ($9800),A
+	0xC3, 0x57, 0x01, // JP $0157
+}
+
+// benchFrameROM is a vendored test ROM that changes PPU registers mid frame
+// on every frame
+var benchFrameROM = filepath.Join("..", "testroms", "testdata", "screenshots", "dmg-acid2.gb")
+
+// the DMG boot ROM hands over to the cartridge after about this many cycles
+// on hardware, going by the DIV value it leaves. This emulator takes about
+// 0.6% longer, see boot_div in the mooneye suite.
+const (
+	bootROMCycles    = 23_440_332
+	bootROMTolerance = bootROMCycles / 100
+)
+
+// BenchmarkBootROM runs the boot ROM until it unmaps itself with the write
+// to 0xFF50
+func BenchmarkBootROM(b *testing.B) {
+	rom := benchROM(frameProgram)
+	var cycles uint64
+	for b.Loop() {
+		g, err := New(rom, Options{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		for !g.cpu.MMU().BootROMDisabled() {
+			if _, err := g.StepInstruction(); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if c := g.cpu.Cycles(); c < bootROMCycles-bootROMTolerance || c > bootROMCycles+bootROMTolerance {
+			b.Fatalf("the boot ROM took %d cycles, want about %d", c, bootROMCycles)
+		}
+		cycles += g.cpu.Cycles()
+	}
+	b.ReportMetric(float64(cycles)/b.Elapsed().Seconds(), "cycles/s")
+}
+
+// BenchmarkFrame runs whole frames of a real ROM with the PPU drawing
+func BenchmarkFrame(b *testing.B) {
+	benchmarkFrames(b, Options{SkipBoot: true})
+}
+
+func BenchmarkFrameBlockCache(b *testing.B) {
+	benchmarkFrames(b, Options{SkipBoot: true, BlockCache: true})
+}
+
+func benchmarkFrames(b *testing.B, opts Options) {
+	rom, err := os.ReadFile(benchFrameROM)
+	if err != nil {
+		b.Fatal(err)
+	}
+	g, err := New(rom, opts)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	start := g.cpu.Cycles()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	frames := 0
+	for b.Loop() {
+		g.RunFrame()
+		if err := g.Err(); err != nil {
+			b.Fatal(err)
+		}
+		frames++
+	}
+	runtime.ReadMemStats(&after)
+
+	b.ReportMetric(float64(g.cpu.Cycles()-start)/b.Elapsed().Seconds(), "cycles/s")
+	b.ReportMetric(float64(after.Mallocs-before.Mallocs)/float64(frames), "allocs/frame")
+}
@@ -0,0 +1,116 @@
+package gameboy
+
+import (
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/model"
+	"github.com/cgimenes/gomenes-boy/hardware/timer"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+func TestSkipBoot(t *testing.T) {
+	cgbROM := benchROM(frameProgram)
+	cgbROM[0x143] = 0x80
+	setHeaderChecksum(cgbROM)
+
+	tests := []struct {
+		name string
+		rom  []types.Byte
+		opts Options
+		af   types.Word
+		bc   types.Word
+		// SC, DMA and NR52
+		io [3]types.Byte
+	}{
+		{"dmg", benchROM(frameProgram), Options{SkipBoot: true}, 0x01B0, 0x0013, [3]types.Byte{0x7E, 0xFF, 0xF1}},
+		{"dmg0", benchROM(frameProgram), Options{Model: model.DMG0}, 0x0100, 0xFF13, [3]types.Byte{0x7E, 0xFF, 0xF1}},
+		{"mgb", benchROM(frameProgram), Options{Model: model.MGB}, 0xFFB0, 0x0013, [3]types.Byte{0x7E, 0xFF, 0xF1}},
+		{"sgb", benchROM(frameProgram), Options{Model: model.SGB}, 0x0100, 0x0014, [3]types.Byte{0x7E, 0xFF, 0xF0}},
+		{"cgb", cgbROM, Options{}, 0x1180, 0x0000, [3]types.Byte{0x7F, 0x00, 0xF1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := New(tt.rom, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := g.cpu.Registers()
+			if r.AF.Get() != tt.af || r.BC.Get() != tt.bc {
+				t.Errorf("AF=%04X BC=%04X, want AF=%04X BC=%04X", r.AF.Get(), r.BC.Get(), tt.af, tt.bc)
+			}
+			if r.PC.Get() != 0x100 || r.SP.Get() != 0xFFFE {
+				t.Errorf("PC=%04X SP=%04X, want 0100 and FFFE", r.PC.Get(), r.SP.Get())
+			}
+			mmu := g.cpu.MMU()
+			if !mmu.BootROMDisabled() {
+				t.Error("boot ROM still mapped")
+			}
+			if got := mmu.Peek(0xFF40); got != 0x91 {
+				t.Errorf("LCDC = 0x%02X, want 0x91", got)
+			}
+			if got := mmu.Peek(0xFF47); got != 0xFC {
+				t.Errorf("BGP = 0x%02X, want 0xFC", got)
+			}
+			for i, address := range []types.Word{0xFF02, 0xFF46, 0xFF26} {
+				if got := mmu.Peek(address); got != tt.io[i] {
+					t.Errorf("%04X = 0x%02X, want 0x%02X", address, got, tt.io[i])
+				}
+			}
+			if tt.name == "cgb" {
+				// KEY1, VBK, HDMA5, BCPS, OCPS and SVBK
+				want := []types.Byte{0x7E, 0xFE, 0xFF, 0xC0, 0xC0, 0xF8}
+				for i, address := range []types.Word{0xFF4D, 0xFF4F, 0xFF55, 0xFF68, 0xFF6A, 0xFF70} {
+					if got := mmu.Peek(address); got != want[i] {
+						t.Errorf("%04X = 0x%02X, want 0x%02X", address, got, want[i])
+					}
+				}
+			}
+			if tt.name == "dmg" {
+				if got := mmu.Peek(timer.DIVAddress); got != 0xAB {
+					t.Errorf("DIV = 0x%02X, want 0xAB", got)
+				}
+			}
+		})
+	}
+}
+
+func TestBootROMFile(t *testing.T) {
+	// LD A,$42; LDH ($50),A unmaps itself, then the cartridge runs on
+	boot := make([]types.Byte, 0x100)
+	copy(boot, []types.Byte{0x3E, 0x42, 0xE0, 0x50})
+	g, err := New(benchROM(frameProgram), Options{BootROM: boot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mmu := g.cpu.MMU()
+	if got := mmu.Peek(0x0000); got != 0x3E {
+		t.Fatalf("boot ROM reads 0x%02X at 0000, want 0x3E", got)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := g.StepInstruction(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !mmu.BootROMDisabled() || g.cpu.Registers().A.Get() != 0x42 {
+		t.Error("the boot ROM from the file did not run")
+	}
+
+	// the CGB image leaves the cartridge header visible
+	rom := benchROM(frameProgram)
+	rom[0x143] = 0xC0
+	setHeaderChecksum(rom)
+	cgbBoot := make([]types.Byte, 0x900)
+	cgbBoot[0x200] = 0xAA
+	g, err = New(rom, Options{BootROM: cgbBoot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mmu = g.cpu.MMU()
+	if mmu.Peek(0x0200) != 0xAA || mmu.Peek(0x0104) != rom[0x104] {
+		t.Error("CGB boot ROM is not mapped around the header")
+	}
+
+	if _, err := New(rom, Options{BootROM: boot}); err == nil {
+		t.Error("a 256 byte boot ROM was accepted for the CGB")
+	}
+}
@@ -0,0 +1,183 @@
+package gameboy
+
+import (
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/cpu"
+	"github.com/cgimenes/gomenes-boy/hardware/memory"
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// cgbProgram colours the first background tile blue through an attribute in
+// VRAM bank 1 and the rest red, stores a different byte in two WRAM banks and
+// switches to double speed
+var cgbProgram = []types.Byte{
+	0xAF, 0xE0, 0x42, // XOR A; LDH (SCY),A
+	0xE0, 0x43, // LDH (SCX),A
+	0x3E, 0x80, 0xE0, 0x68, // BCPS = palette 0 colour 0, auto increment
+	0x3E, 0x1F, 0xE0, 0x69, // red
+	0xAF, 0xE0, 0x69,
+	0x3E, 0x88, 0xE0, 0x68, // BCPS = palette 1 colour 0, auto increment
+	0xAF, 0xE0, 0x69, // blue
+	0x3E, 0x7C, 0xE0, 0x69,
+	0x3E, 0x01, 0xE0, 0x4F, // VBK = 1
+	0xEA, 0x00, 0x98, // first tile uses palette 1
+	0xAF, 0xE0, 0x4F, // VBK = 0
+	0x3E, 0x03, 0xE0, 0x70, // SVBK = 3
+	0x3E, 0x42, 0xEA, 0x00, 0xD0, // LD ($D000),$42
+	0x3E, 0x01, 0xE0, 0x70, // SVBK = 1
+	0x3E, 0x24, 0xEA, 0x00, 0xD0, // LD ($D000),$24
+	0x3E, 0x01, 0xE0, 0x4D, // arm the speed switch
+	0x10, 0x00, // STOP
+	0x3E, 0x91, 0xE0, 0x40, // LCD and background on
+}
+
+func TestCGB(t *testing.T) {
+	end := types.Word(0x150 + len(cgbProgram))
+	program := append(cgbProgram, 0xC3, types.Byte(end), types.Byte(end>>8))
+	rom := benchROM(program)
+	rom[0x143] = 0x80
+	setHeaderChecksum(rom)
+
+	g, err := New(rom, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mmu := g.cpu.MMU()
+	if !mmu.CGB() {
+		t.Fatal("not in CGB mode")
+	}
+	for i := 0; i < 120 && g.cpu.PC() != end; i++ {
+		g.RunFrame()
+		if err := g.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g.cpu.PC() != end {
+		t.Fatalf("program did not finish, PC=0x%04X", g.cpu.PC())
+	}
+	frame := g.RunFrame()
+
+	blue, red := ppu.NewColor(0, 0, 31), ppu.NewColor(31, 0, 0)
+	if got := frame.Pixels[0]; got != blue {
+		t.Errorf("first tile is 0x%04X, want 0x%04X", got, blue)
+	}
+	if got := frame.Pixels[8]; got != red {
+		t.Errorf("second tile is 0x%04X, want 0x%04X", got, red)
+	}
+
+	if got := mmu.Peek(0xD000); got != 0x24 {
+		t.Errorf("WRAM bank 1 holds 0x%02X, want 0x24", got)
+	}
+	mmu.Set(memory.SVBKAddress, 3)
+	if got := mmu.Peek(0xD000); got != 0x42 {
+		t.Errorf("WRAM bank 3 holds 0x%02X, want 0x42", got)
+	}
+	if got := mmu.Peek(0xF000); got != 0x42 {
+		t.Errorf("echo of WRAM bank 3 holds 0x%02X, want 0x42", got)
+	}
+
+	if !mmu.DoubleSpeed() {
+		t.Error("STOP did not switch to double speed")
+	}
+	if got := mmu.Peek(memory.KEY1Address); got != 0xFE {
+		t.Errorf("KEY1 = 0x%02X, want 0xFE", got)
+	}
+}
+
+func newCGB(t *testing.T) *GameBoy {
+	end := types.Word(0x150)
+	rom := benchROM([]types.Byte{0xC3, types.Byte(end), types.Byte(end >> 8)})
+	rom[0x143] = 0xC0
+	setHeaderChecksum(rom)
+	g, err := New(rom, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// startHDMA copies from C000 to 8800 in VRAM bank 1
+func startHDMA(mmu *memory.MMU, hdma5 types.Byte) {
+	mmu.Set(ppu.VBKAddress, 1)
+	mmu.Set(memory.HDMA1Address, 0xC0)
+	mmu.Set(memory.HDMA2Address, 0x00)
+	mmu.Set(memory.HDMA3Address, 0x88)
+	mmu.Set(memory.HDMA4Address, 0x00)
+	mmu.Set(memory.HDMA5Address, hdma5)
+}
+
+func TestGeneralPurposeHDMA(t *testing.T) {
+	g := newCGB(t)
+	mmu := g.cpu.MMU()
+	for i := types.Word(0); i < 0x30; i++ {
+		mmu.Set(0xC000+i, types.Byte(i+1))
+	}
+
+	// two blocks
+	startHDMA(mmu, 0x01)
+	for i := types.Word(0); i < 0x30; i++ {
+		want := types.Byte(i + 1)
+		if i >= 0x20 {
+			want = 0
+		}
+		if got := mmu.PPU.VRAM1[0x800+i]; got != want {
+			t.Fatalf("VRAM1[0x%03X] = 0x%02X, want 0x%02X", 0x800+i, got, want)
+		}
+	}
+	if mmu.PPU.VRAM[0x800] != 0 {
+		t.Error("bank 0 was written")
+	}
+	if got := mmu.Peek(memory.HDMA5Address); got != 0xFF {
+		t.Errorf("HDMA5 = 0x%02X after the transfer, want 0xFF", got)
+	}
+
+	// the CPU waits 32 cycles per block on top of the instruction
+	info := cpu.Opcodes[mmu.Peek(g.cpu.PC())]
+	cycles, err := g.StepInstruction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instruction := cycles - 2*32; instruction != info.Cycles && instruction != info.CyclesTaken {
+		t.Errorf("%s took %d cycles, want %d plus 64", info.Mnemonic, cycles, info.Cycles)
+	}
+}
+
+func TestHBlankHDMA(t *testing.T) {
+	g := newCGB(t)
+	mmu := g.cpu.MMU()
+	for i := types.Word(0); i < 0x40; i++ {
+		mmu.Set(0xC000+i, 0xAA)
+	}
+	// there is no CGB boot ROM to run, wait for a line to start
+	mmu.Set(0xFF40, 0x91)
+	for mmu.PPU.Mode() != ppu.ModeOAMScan {
+		g.StepInstruction()
+	}
+
+	// four blocks, none copied before the HBlank
+	startHDMA(mmu, 0x83)
+	if got := mmu.Peek(memory.HDMA5Address); got != 0x03 {
+		t.Fatalf("HDMA5 = 0x%02X, want 0x03", got)
+	}
+	for mmu.PPU.Mode() != ppu.ModeHBlank {
+		g.StepInstruction()
+	}
+	if got := mmu.Peek(memory.HDMA5Address); got != 0x02 {
+		t.Fatalf("HDMA5 = 0x%02X after an HBlank, want 0x02", got)
+	}
+	if mmu.PPU.VRAM1[0x80F] != 0xAA || mmu.PPU.VRAM1[0x810] != 0 {
+		t.Fatal("the first HBlank did not copy exactly one block")
+	}
+
+	// cancel, the length left reads back with bit 7 set
+	mmu.Set(memory.HDMA5Address, 0x00)
+	if got := mmu.Peek(memory.HDMA5Address); got != 0x82 {
+		t.Fatalf("HDMA5 = 0x%02X after cancelling, want 0x82", got)
+	}
+	g.RunFrame()
+	if mmu.PPU.VRAM1[0x810] != 0 {
+		t.Error("a cancelled transfer kept copying")
+	}
+}
@@ -0,0 +1,116 @@
+package gameboy
+
+import (
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/cheats"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+func TestCheats(t *testing.T) {
+	list := &cheats.List{}
+	// LD A,$93 at 0x0150 becomes NOP, only when the ROM holds $3E there
+	if _, err := list.Add("001-50F-1A2", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := list.Add("01AAF0C0", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	rom := benchROM(frameProgram)
+	g, err := New(rom, Options{Cheats: list})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mmu := g.cpu.MMU()
+
+	if got := mmu.Peek(0x0150); got != 0x00 {
+		t.Errorf("patched ROM read 0x%02X, want 0x00", got)
+	}
+	if rom[0x150] != 0x3E {
+		t.Error("the ROM image was changed")
+	}
+
+	g.RunFrame()
+	if err := g.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got := mmu.Peek(0xC0F0); got != 0xAA {
+		t.Errorf("RAM cheat not applied: 0xC0F0 = 0x%02X", got)
+	}
+
+	list.Enable(0, false)
+	if got := mmu.Peek(0x0150); got != 0x3E {
+		t.Errorf("disabled patch still reads 0x%02X", got)
+	}
+	mmu.Set(0xC0F0, 0x11)
+	list.Enable(1, false)
+	g.RunFrame()
+	if got := mmu.Peek(0xC0F0); got != 0x11 {
+		t.Errorf("disabled RAM cheat wrote 0x%02X", got)
+	}
+}
+
+func TestCheatSeenByCachedCode(t *testing.T) {
+	list := &cheats.List{}
+	// turns the NOP at 0xC000 into INC C
+	if _, err := list.Add("010C00C0", ""); err != nil {
+		t.Fatal(err)
+	}
+	list.Enable(0, false)
+
+	// JP $C000
+	rom := benchROM([]types.Byte{0xC3, 0x00, 0xC0})
+	g, err := New(rom, Options{Cheats: list, BlockCache: true, SkipBoot: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range []types.Byte{
+		0x00,             // C000: NOP
+		0xC3, 0x00, 0xC0, // C001: JP $C000
+	} {
+		g.cpu.MMU().Set(0xC000+types.Word(i), b)
+	}
+
+	// the loop is cached by the time the cheat first writes
+	list.Enable(0, true)
+	g.RunFrame()
+	c := g.cpu.Registers().C.Get()
+	g.RunFrame()
+	if got := g.cpu.Registers().C.Get(); got == c {
+		t.Errorf("C = %02X after a frame, the cached loop did not see the cheat write", got)
+	}
+}
+
+func TestSearchUnmappedBanks(t *testing.T) {
+	rom := benchROM([]types.Byte{0xC3, 0x50, 0x01})
+	rom[0x143] = 0xC0 // CGB
+	rom[0x147] = 0x03 // MBC1+RAM+BATTERY
+	rom[0x149] = 0x03 // 32KB of RAM
+	setHeaderChecksum(rom)
+	g, err := New(rom, Options{SkipBoot: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mmu := g.cpu.MMU()
+
+	// RAM bank 2 while the RAM is disabled, WRAM bank 3 while 1 is mapped
+	mmu.Cartridge.RAM[2*0x2000+0x0123] = 0x5A
+	mmu.Set(0xFF70, 3)
+	mmu.Set(0xD456, 0x5A)
+	mmu.Set(0xFF70, 1)
+
+	s := cheats.NewSearch(mmu, cheats.Width8, false)
+	if n := s.Filter(cheats.EqualTo, 0x5A); n != 2 {
+		t.Fatalf("eq left %d candidates, want 2", n)
+	}
+	want := []cheats.Candidate{
+		{Bank: 2, Address: 0xA123, Value: 0x5A},
+		{Bank: 3, Address: 0xD456, Value: 0x5A},
+	}
+	for i, c := range s.Candidates(-1) {
+		if c != want[i] {
+			t.Errorf("candidate %d = %+v, want %+v", i, c, want[i])
+		}
+	}
+}
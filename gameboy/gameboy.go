@@ -0,0 +1,320 @@
+// Package gameboy ties the CPU, the cartridge and the devices on the bus into
+// a single machine. Frontends, tests and embedders should start here.
+package gameboy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"sync"
+
+	"github.com/cgimenes/gomenes-boy/cheats"
+	"github.com/cgimenes/gomenes-boy/debugger"
+	"github.com/cgimenes/gomenes-boy/hardware/cartridge"
+	"github.com/cgimenes/gomenes-boy/hardware/cpu"
+	"github.com/cgimenes/gomenes-boy/hardware/joypad"
+	"github.com/cgimenes/gomenes-boy/hardware/model"
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+	"github.com/cgimenes/gomenes-boy/hardware/serial"
+	"github.com/cgimenes/gomenes-boy/hardware/sgb"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// ErrClosed is returned by every method once Close has been called
+var ErrClosed = errors.New("gameboy: closed")
+
+// instructions run between two looks at the cancellation signals
+const pollInterval = 1024
+
+type Options struct {
+	// Serial is plugged into the link port
+	Serial serial.Device
+	// Tracer is called before every instruction
+	Tracer cpu.Tracer
+	// BlockCache runs decoded blocks instead of interpreting every opcode
+	BlockCache bool
+	// Cheats patches ROM reads and writes RAM every frame
+	Cheats *cheats.List
+	// Model is the hardware to emulate, by default picked from the header
+	Model model.Model
+	// BootROM is an image for the model, 2304 bytes for the CGB and 256 for
+	// the others. Only the DMG one is embedded: other models without an
+	// image start as if their boot ROM had run.
+	BootROM []types.Byte
+	// SkipBoot starts at the cartridge entry point with the registers as the
+	// boot ROM leaves them
+	SkipBoot bool
+}
+
+// Frame is a completed screen
+type Frame struct {
+	// Number counts the frames since power on, starting at 1
+	Number uint64
+	// Pixels holds the RGB555 colour of every pixel, coloured by the Super
+	// Game Boy when the game runs on one
+	Pixels [ppu.Width * ppu.Height]ppu.Color
+	// Bordered is the whole Super Game Boy picture, the screen inside its
+	// border, and nil for other games. It is reused by the next frame.
+	Bordered *[sgb.Width * sgb.Height]ppu.Color
+}
+
+// Image converts the frame, bordered if there is a border
+func (f Frame) Image() *image.RGBA {
+	if f.Bordered != nil {
+		return ppu.ToImage(f.Bordered[:], sgb.Width)
+	}
+	return ppu.ToImage(f.Pixels[:], ppu.Width)
+}
+
+type GameBoy struct {
+	cpu  *cpu.CPU
+	rom  []types.Byte
+	opts Options
+	err  error
+
+	// the frame cheats were last applied on
+	lastFrame uint64
+	// the Super Game Boy picture handed out by every frame
+	bordered *[sgb.Width * sgb.Height]ppu.Color
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New powers on a Game Boy with rom inserted
+func New(rom []types.Byte, opts Options) (*GameBoy, error) {
+	cart, err := cartridge.New(rom)
+	if err != nil {
+		return nil, err
+	}
+	m := opts.Model.Resolve(cart)
+	if opts.BootROM != nil && len(opts.BootROM) != m.BootROMSize() {
+		return nil, fmt.Errorf("gameboy: the %s boot ROM is %d bytes, not %d", m, m.BootROMSize(), len(opts.BootROM))
+	}
+	g := &GameBoy{rom: rom, opts: opts, done: make(chan struct{})}
+	g.powerOn(cart)
+	return g, nil
+}
+
+func (g *GameBoy) powerOn(cart *cartridge.Cartridge) {
+	g.cpu = &cpu.CPU{}
+	g.cpu.Init()
+	g.cpu.LoadCartridge(cart)
+	g.cpu.SetModel(g.opts.Model)
+	g.cpu.Tracer = g.opts.Tracer
+	g.cpu.EnableBlockCache(g.opts.BlockCache)
+	if g.opts.Serial != nil {
+		g.cpu.ConnectSerial(g.opts.Serial)
+	}
+	if g.opts.Cheats != nil {
+		g.cpu.MMU().Patcher = g.opts.Cheats
+		// blocks decoded with the old patches are stale
+		g.opts.Cheats.OnChange = g.cpu.FlushBlocks
+	}
+	if g.opts.BootROM != nil {
+		// the size was checked by New
+		g.cpu.MMU().LoadBootROM(g.opts.BootROM)
+	}
+	if g.opts.SkipBoot || (g.opts.BootROM == nil && g.cpu.Model() != model.DMG) {
+		g.cpu.SkipBootROM()
+	}
+	g.lastFrame = 0
+	g.err = nil
+}
+
+// Reset power cycles the machine. The cartridge RAM survives, as it would
+// on battery backed cartridges.
+func (g *GameBoy) Reset() error {
+	if g.closed() {
+		return ErrClosed
+	}
+	old := g.cpu.MMU().Cartridge
+	cart, err := cartridge.New(g.rom)
+	if err != nil {
+		return err
+	}
+	copy(cart.RAM, old.RAM)
+	g.powerOn(cart)
+	return nil
+}
+
+// Close stops any RunFrame in progress and releases the machine
+func (g *GameBoy) Close() error {
+	g.closeOnce.Do(func() {
+		close(g.done)
+	})
+	return nil
+}
+
+func (g *GameBoy) closed() bool {
+	select {
+	case <-g.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Err returns the error that stopped the last RunFrame, if any
+func (g *GameBoy) Err() error {
+	return g.err
+}
+
+// StepInstruction executes a single instruction, or dispatches an
+// interrupt, and returns the cycles it took
+func (g *GameBoy) StepInstruction() (int, error) {
+	if g.closed() {
+		return 0, ErrClosed
+	}
+	return g.step()
+}
+
+// step runs an instruction and applies the RAM cheats once a frame is done
+func (g *GameBoy) step() (int, error) {
+	cycles, err := g.cpu.Step()
+	if g.opts.Cheats != nil && g.cpu.PPU().Frame != g.lastFrame {
+		g.lastFrame = g.cpu.PPU().Frame
+		g.opts.Cheats.Apply(cheatMemory{g.cpu})
+	}
+	return cycles, err
+}
+
+// cheatMemory sends the cheat writes through the CPU, so code it has already
+// decoded sees them
+type cheatMemory struct {
+	cpu *cpu.CPU
+}
+
+func (m cheatMemory) Set(address types.Word, value types.Byte) {
+	m.cpu.Poke(address, value)
+}
+
+func (m cheatMemory) WRAMBank() int {
+	return m.cpu.MMU().WRAMBank()
+}
+
+// RunFrame runs until the next frame is complete. When the CPU stops early
+// the partly drawn frame is returned and Err tells why.
+func (g *GameBoy) RunFrame() Frame {
+	frame, err := g.RunFrameContext(context.Background())
+	g.err = err
+	return frame
+}
+
+// RunFrameContext is RunFrame that also gives up when ctx is done
+func (g *GameBoy) RunFrameContext(ctx context.Context) (Frame, error) {
+	target := g.cpu.PPU().Frame + 1
+	var err error
+	for n := 0; g.cpu.PPU().Frame < target; n++ {
+		if n%pollInterval == 0 {
+			if err = g.cancelled(ctx); err != nil {
+				break
+			}
+		}
+		if _, err = g.step(); err != nil {
+			break
+		}
+	}
+	return g.frame(), err
+}
+
+func (g *GameBoy) cancelled(ctx context.Context) error {
+	select {
+	case <-g.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return nil
+	}
+}
+
+func (g *GameBoy) frame() Frame {
+	p := g.cpu.PPU()
+	f := Frame{Number: p.Frame, Pixels: p.Framebuffer}
+	if s := g.cpu.MMU().SGB; s != nil {
+		s.Colorize(f.Pixels[:], f.Pixels[:])
+		if g.bordered == nil {
+			g.bordered = new([sgb.Width * sgb.Height]ppu.Color)
+		}
+		f.Bordered = g.bordered
+		s.Compose(f.Pixels[:], f.Bordered[:])
+	}
+	return f
+}
+
+// Frame returns the screen as it is right now
+func (g *GameBoy) Frame() Frame {
+	return g.frame()
+}
+
+func (g *GameBoy) Press(b joypad.Button) {
+	g.cpu.Press(b)
+}
+
+func (g *GameBoy) Release(b joypad.Button) {
+	g.cpu.Release(b)
+}
+
+// Buttons returns the buttons held down, a bit per joypad.Button
+func (g *GameBoy) Buttons() types.Byte {
+	return g.cpu.MMU().Joypad.Buttons()
+}
+
+// ReplayFrame holds down exactly buttons, as returned by Buttons, and runs a
+// frame. It lets recorded input be played back.
+func (g *GameBoy) ReplayFrame(buttons types.Byte) error {
+	g.cpu.SetButtons(buttons)
+	g.RunFrame()
+	return g.err
+}
+
+// SaveState writes a snapshot of the machine to w
+func (g *GameBoy) SaveState(w io.Writer) error {
+	return g.cpu.SaveState(w)
+}
+
+// LoadState restores a snapshot written by SaveState
+func (g *GameBoy) LoadState(r io.Reader) error {
+	return g.cpu.LoadState(r)
+}
+
+// Cheats returns the cheats given in the options, nil when there are none
+func (g *GameBoy) Cheats() *cheats.List {
+	return g.opts.Cheats
+}
+
+// Run runs frames until ctx is done, the machine is closed or the CPU stops
+// early, and returns why it stopped
+func (g *GameBoy) Run(ctx context.Context) error {
+	for {
+		if _, err := g.RunFrameContext(ctx); err != nil {
+			g.err = err
+			return err
+		}
+	}
+}
+
+// PC returns the address of the next instruction
+func (g *GameBoy) PC() types.Word {
+	return g.cpu.PC()
+}
+
+// Locked reports whether the CPU hung on an illegal opcode
+func (g *GameBoy) Locked() bool {
+	return g.cpu.Locked()
+}
+
+// FrameNumber counts the frames completed since power on, as Frame.Number
+// does, without copying the screen
+func (g *GameBoy) FrameNumber() uint64 {
+	return g.cpu.PPU().Frame
+}
+
+// Debugger attaches a debugger to the processor. Reset powers on a new
+// processor, so a debugger only lasts until the next Reset.
+func (g *GameBoy) Debugger() *debugger.Debugger {
+	return debugger.New(g.cpu)
+}
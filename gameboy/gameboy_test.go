@@ -0,0 +1,115 @@
+package gameboy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+func newFacade(t *testing.T, rom []types.Byte) *GameBoy {
+	t.Helper()
+	g, err := New(rom, Options{SkipBoot: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestReset(t *testing.T) {
+	rom := benchROM(frameProgram)
+	// MBC1 with 8KB of battery backed RAM
+	rom[0x147] = 0x03
+	rom[0x149] = 0x02
+	setHeaderChecksum(rom)
+	g := newFacade(t, rom)
+
+	for i := 0; i < 3; i++ {
+		g.RunFrame()
+	}
+	g.cpu.MMU().Cartridge.RAM[0x10] = 0x42
+	if err := g.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := g.FrameNumber(); n != 0 {
+		t.Errorf("%d frames after a reset, want 0", n)
+	}
+	if pc := g.PC(); pc != 0x100 {
+		t.Errorf("PC = %04X after a reset, want 0100", pc)
+	}
+	if got := g.cpu.MMU().Cartridge.RAM[0x10]; got != 0x42 {
+		t.Errorf("cartridge RAM = %02X after a reset, want 42", got)
+	}
+	if f := g.RunFrame(); f.Number != 1 || g.Err() != nil {
+		t.Errorf("first frame after a reset is %d with %v, want 1", f.Number, g.Err())
+	}
+}
+
+func TestClose(t *testing.T) {
+	g := newFacade(t, benchROM(frameProgram))
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+
+	if _, err := g.StepInstruction(); !errors.Is(err, ErrClosed) {
+		t.Errorf("StepInstruction: got %v, want %v", err, ErrClosed)
+	}
+	g.RunFrame()
+	if err := g.Err(); !errors.Is(err, ErrClosed) {
+		t.Errorf("RunFrame: got %v, want %v", err, ErrClosed)
+	}
+	if err := g.Reset(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Reset: got %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestCloseStopsRun(t *testing.T) {
+	g := newFacade(t, benchROM(frameProgram))
+	stopped := make(chan error)
+	go func() { stopped <- g.Run(context.Background()) }()
+
+	time.Sleep(10 * time.Millisecond)
+	g.Close()
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("got %v, want %v", err, ErrClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run kept going after Close")
+	}
+}
+
+func TestRunFrameContextCancelled(t *testing.T) {
+	g := newFacade(t, benchROM(frameProgram))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	f, err := g.RunFrameContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if f.Number != 0 || g.PC() != 0x100 {
+		t.Errorf("frame %d and PC %04X, want the machine untouched", f.Number, g.PC())
+	}
+
+	// the machine carries on with a live context
+	if f, err := g.RunFrameContext(context.Background()); err != nil || f.Number != 1 {
+		t.Errorf("next frame is %d with %v, want 1", f.Number, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := g.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run: got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if !errors.Is(g.Err(), context.DeadlineExceeded) {
+		t.Errorf("Err after Run: got %v, want %v", g.Err(), context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,44 @@
+package gameboy
+
+import (
+	"image"
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+)
+
+func TestSGBFrame(t *testing.T) {
+	rom := benchROM(frameProgram)
+	rom[0x146] = 0x03
+	setHeaderChecksum(rom)
+	g, err := New(rom, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame := g.RunFrame()
+	if frame.Bordered == nil {
+		t.Fatal("no border around an SGB game")
+	}
+	if got, want := frame.Image().Bounds(), image.Rect(0, 0, 256, 224); got != want {
+		t.Errorf("image is %v, want %v", got, want)
+	}
+	for _, c := range frame.Pixels {
+		for _, shade := range ppu.Shades {
+			if c == shade {
+				t.Fatalf("pixel left in DMG shade 0x%04X", c)
+			}
+		}
+	}
+	if g.RunFrame().Bordered != frame.Bordered {
+		t.Error("the border picture was allocated again")
+	}
+
+	plain, err := New(benchROM(frameProgram), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.RunFrame().Bordered != nil {
+		t.Error("border around a DMG game")
+	}
+}
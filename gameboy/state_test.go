@@ -0,0 +1,96 @@
+package gameboy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/memory"
+	"github.com/cgimenes/gomenes-boy/hardware/state"
+)
+
+func newStateGameBoy(t *testing.T) *GameBoy {
+	t.Helper()
+	g, err := New(benchROM(frameProgram), Options{SkipBoot: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	g := newStateGameBoy(t)
+	for i := 0; i < 3; i++ {
+		g.RunFrame()
+	}
+	var saved bytes.Buffer
+	if err := g.SaveState(&saved); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newStateGameBoy(t)
+	if err := restored.LoadState(bytes.NewReader(saved.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	var resaved bytes.Buffer
+	if err := restored.SaveState(&resaved); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(saved.Bytes(), resaved.Bytes()) {
+		t.Fatal("state saved after loading differs from the one loaded")
+	}
+
+	want := g.RunFrame().Image()
+	got := restored.RunFrame().Image()
+	if !bytes.Equal(got.Pix, want.Pix) {
+		t.Error("restored machine draws a different next frame")
+	}
+}
+
+func TestLoadStateKeepsBootROM(t *testing.T) {
+	g := newStateGameBoy(t)
+	var saved bytes.Buffer
+	if err := g.SaveState(&saved); err != nil {
+		t.Fatal(err)
+	}
+
+	// the MMU chunk stores the address space, then the boot ROM
+	b := saved.Bytes()
+	mmu := bytes.Index(b, []byte("MMU "))
+	if mmu < 0 {
+		t.Fatal("no MMU chunk")
+	}
+	bootROM := b[mmu+8+0x10000 : mmu+8+0x10000+len(memory.BootROM)]
+	for i := range bootROM {
+		bootROM[i] = ^bootROM[i]
+	}
+
+	want := memory.BootROM
+	if err := g.LoadState(bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	if memory.BootROM != want {
+		t.Error("loading a state overwrote the embedded boot ROM")
+	}
+}
+
+func TestLoadStateRejectsBadHeader(t *testing.T) {
+	g := newStateGameBoy(t)
+	var saved bytes.Buffer
+	if err := g.SaveState(&saved); err != nil {
+		t.Fatal(err)
+	}
+
+	badMagic := bytes.Clone(saved.Bytes())
+	copy(badMagic, "NOPE")
+	if err := g.LoadState(bytes.NewReader(badMagic)); !errors.Is(err, state.ErrBadMagic) {
+		t.Errorf("bad magic: got %v, want %v", err, state.ErrBadMagic)
+	}
+
+	newer := bytes.Clone(saved.Bytes())
+	binary.LittleEndian.PutUint16(newer[len(state.Magic):], state.Version+1)
+	if err := g.LoadState(bytes.NewReader(newer)); err == nil {
+		t.Error("a state from a newer version was accepted")
+	}
+}
@@ -0,0 +1,322 @@
+package cartridge
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+const (
+	romBankSize = 0x4000
+	ramBankSize = 0x2000
+	// MBC2 has 512 half bytes of RAM built in
+	mbc2RAMSize = 0x200
+)
+
+// header offsets
+const (
+	titleAddress     = 0x134
+	cgbFlagAddress   = 0x143
+	sgbFlagAddress   = 0x146
+	typeAddress      = 0x147
+	romSizeAddress   = 0x148
+	ramSizeAddress   = 0x149
+	headerEndAddress = 0x150
+)
+
+type Kind int
+
+const (
+	ROMOnly Kind = iota
+	MBC1
+	MBC2
+	MBC3
+	MBC5
+)
+
+type Header struct {
+	Title   string
+	Type    types.Byte
+	CGBFlag types.Byte
+	SGBFlag types.Byte
+	ROMSize int
+	RAMSize int
+}
+
+// CGB reports whether the game uses the Game Boy Color features, either
+// only running on it (0xC0) or also on DMG (0x80)
+func (h Header) CGB() bool {
+	return h.CGBFlag&0x80 != 0
+}
+
+// SGB reports whether the game uses the Super Game Boy features
+func (h Header) SGB() bool {
+	return h.SGBFlag == 0x03
+}
+
+type Cartridge struct {
+	Header Header
+	Kind   Kind
+	ROM    []types.Byte
+	RAM    []types.Byte
+
+	// mapper registers
+	ramEnabled bool
+	romBank    int
+	ramBank    int
+	// MBC1 banking mode
+	mode types.Byte
+	// MBC1M, the MBC1 of multi-game carts, wires the upper bank bits one
+	// bit lower
+	multicart bool
+}
+
+func New(rom []types.Byte) (*Cartridge, error) {
+	if len(rom) < headerEndAddress {
+		return nil, fmt.Errorf("cartridge: ROM is only %d bytes long", len(rom))
+	}
+
+	header := Header{
+		Title:   strings.TrimRight(string(rom[titleAddress:cgbFlagAddress]), "\x00"),
+		Type:    rom[typeAddress],
+		CGBFlag: rom[cgbFlagAddress],
+		SGBFlag: rom[sgbFlagAddress],
+		ROMSize: romBankSize * 2 << rom[romSizeAddress],
+		RAMSize: ramSize(rom[ramSizeAddress]),
+	}
+
+	var kind Kind
+	switch header.Type {
+	case 0x00, 0x08, 0x09:
+		kind = ROMOnly
+	case 0x01, 0x02, 0x03:
+		kind = MBC1
+	case 0x05, 0x06:
+		kind = MBC2
+		// the header declares no RAM, it is inside the MBC
+		header.RAMSize = mbc2RAMSize
+	case 0x0F, 0x10, 0x11, 0x12, 0x13:
+		kind = MBC3
+	case 0x19, 0x1A, 0x1B, 0x1C, 0x1D, 0x1E:
+		kind = MBC5
+	default:
+		return nil, fmt.Errorf("cartridge: unsupported cartridge type 0x%02X", header.Type)
+	}
+
+	// pad the image so every bank the header declares can be read
+	if len(rom) < header.ROMSize {
+		padded := make([]types.Byte, header.ROMSize)
+		copy(padded, rom)
+		rom = padded
+	}
+
+	return &Cartridge{
+		Header:    header,
+		Kind:      kind,
+		ROM:       rom,
+		RAM:       make([]types.Byte, header.RAMSize),
+		romBank:   1,
+		multicart: kind == MBC1 && isMulticart(rom),
+	}, nil
+}
+
+// isMulticart tells an MBC1M cart by the Nintendo logo of a second game in
+// bank 0x10, the first bank its upper bits reach
+func isMulticart(rom []types.Byte) bool {
+	const logo, logoLength = 0x104, 48
+	second := 0x10*romBankSize + logo
+	if len(rom) != 64*romBankSize {
+		return false
+	}
+	for i := 0; i < logoLength; i++ {
+		if rom[second+i] != rom[logo+i] {
+			return false
+		}
+	}
+	return true
+}
+
+func ramSize(code types.Byte) int {
+	switch code {
+	case 0x02:
+		return 8 * 1024
+	case 0x03:
+		return 32 * 1024
+	case 0x04:
+		return 128 * 1024
+	case 0x05:
+		return 64 * 1024
+	default:
+		return 0
+	}
+}
+
+func (c *Cartridge) romBanks() int {
+	return len(c.ROM) / romBankSize
+}
+
+// ROMBank returns the bank currently mapped at address
+func (c *Cartridge) ROMBank(address types.Word) int {
+	shift := 5
+	if c.multicart {
+		shift = 4
+	}
+	if address < romBankSize {
+		if c.Kind == MBC1 && c.mode == 1 {
+			return (c.ramBank << shift) % c.romBanks()
+		}
+		return 0
+	}
+
+	bank := c.romBank
+	if c.Kind == MBC1 {
+		if c.multicart {
+			bank &= 0x0F
+		}
+		bank |= c.ramBank << shift
+	}
+	return bank % c.romBanks()
+}
+
+func (c *Cartridge) Read(address types.Word) types.Byte {
+	if address < 0x8000 {
+		offset := int(address) % romBankSize
+		return c.ROM[c.ROMBank(address)*romBankSize+offset]
+	}
+
+	i, ok := c.ramIndex(address)
+	if !ok {
+		return 0xFF
+	}
+	if c.Kind == MBC2 {
+		// only the lower half of each byte is stored
+		return c.RAM[i] | 0xF0
+	}
+	return c.RAM[i]
+}
+
+// RAMBanks is the number of 8KB banks of cartridge RAM, one for the MBC2
+// and RAM smaller than a bank
+func (c *Cartridge) RAMBanks() int {
+	if len(c.RAM) == 0 {
+		return 0
+	}
+	return (len(c.RAM) + ramBankSize - 1) / ramBankSize
+}
+
+// PeekRAM reads address, from A000 to BFFF, in the given RAM bank whatever
+// bank is selected and even while the RAM is disabled
+func (c *Cartridge) PeekRAM(bank int, address types.Word) types.Byte {
+	if len(c.RAM) == 0 {
+		return 0xFF
+	}
+	if c.Kind == MBC2 {
+		return c.RAM[int(address-0xA000)%mbc2RAMSize] | 0xF0
+	}
+	return c.RAM[(bank*ramBankSize+int(address-0xA000))%len(c.RAM)]
+}
+
+func (c *Cartridge) Write(address types.Word, value types.Byte) {
+	if address >= 0xA000 {
+		if i, ok := c.ramIndex(address); ok {
+			c.RAM[i] = value
+		}
+		return
+	}
+
+	switch c.Kind {
+	case MBC1:
+		c.writeMBC1(address, value)
+	case MBC2:
+		c.writeMBC2(address, value)
+	case MBC3:
+		c.writeMBC3(address, value)
+	case MBC5:
+		c.writeMBC5(address, value)
+	}
+}
+
+func (c *Cartridge) writeMBC1(address types.Word, value types.Byte) {
+	switch {
+	case address < 0x2000:
+		c.ramEnabled = value&0x0F == 0x0A
+	case address < 0x4000:
+		c.romBank = int(value & 0x1F)
+		if c.romBank == 0 {
+			c.romBank = 1
+		}
+	case address < 0x6000:
+		c.ramBank = int(value & 0x03)
+	default:
+		c.mode = value & 0x01
+	}
+}
+
+func (c *Cartridge) writeMBC2(address types.Word, value types.Byte) {
+	if address >= 0x4000 {
+		return
+	}
+	// bit 8 of the address tells the two registers apart
+	if address&0x0100 == 0 {
+		c.ramEnabled = value&0x0F == 0x0A
+		return
+	}
+	c.romBank = int(value & 0x0F)
+	if c.romBank == 0 {
+		c.romBank = 1
+	}
+}
+
+func (c *Cartridge) writeMBC3(address types.Word, value types.Byte) {
+	switch {
+	case address < 0x2000:
+		c.ramEnabled = value&0x0F == 0x0A
+	case address < 0x4000:
+		c.romBank = int(value & 0x7F)
+		if c.romBank == 0 {
+			c.romBank = 1
+		}
+	case address < 0x6000:
+		// @todo banks 0x08-0x0C select the real time clock
+		c.ramBank = int(value & 0x0F)
+	}
+}
+
+func (c *Cartridge) writeMBC5(address types.Word, value types.Byte) {
+	switch {
+	case address < 0x2000:
+		c.ramEnabled = value&0x0F == 0x0A
+	case address < 0x3000:
+		c.romBank = c.romBank&0x100 | int(value)
+	case address < 0x4000:
+		c.romBank = c.romBank&0xFF | int(value&0x01)<<8
+	case address < 0x6000:
+		c.ramBank = int(value & 0x0F)
+	}
+}
+
+func (c *Cartridge) ramIndex(address types.Word) (int, bool) {
+	if len(c.RAM) == 0 || (c.Kind != ROMOnly && !c.ramEnabled) {
+		return 0, false
+	}
+
+	bank := 0
+	switch c.Kind {
+	case MBC1:
+		if c.mode == 1 {
+			bank = c.ramBank
+		}
+	case MBC2:
+		// the 512 bytes repeat across the whole area
+		return int(address-0xA000) % mbc2RAMSize, true
+	case MBC3:
+		if c.ramBank > 0x03 {
+			return 0, false
+		}
+		bank = c.ramBank
+	case MBC5:
+		bank = c.ramBank
+	}
+	return (bank*ramBankSize + int(address-0xA000)) % len(c.RAM), true
+}
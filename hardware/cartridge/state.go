@@ -0,0 +1,21 @@
+package cartridge
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/state"
+)
+
+func (c *Cartridge) SaveState(e *state.Encoder) {
+	e.Bool(c.ramEnabled)
+	e.Uint32(uint32(c.romBank))
+	e.Uint32(uint32(c.ramBank))
+	e.Byte(c.mode)
+	e.Bytes(c.RAM)
+}
+
+func (c *Cartridge) LoadState(d *state.Decoder) {
+	c.ramEnabled = d.Bool()
+	c.romBank = int(d.Uint32())
+	c.ramBank = int(d.Uint32())
+	c.mode = d.Byte()
+	d.Bytes(c.RAM)
+}
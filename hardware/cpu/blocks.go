@@ -0,0 +1,237 @@
+package cpu
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// longest run of instructions decoded into one block
+const maxBlockLength = 64
+
+// BankedBus is implemented by buses that can map different memory at the
+// same address. Without it every address is assumed to hold a single bank.
+type BankedBus interface {
+	Bus
+	// Bank identifies what is mapped at address, or returns false where
+	// code should not be cached
+	Bank(address types.Word) (int, bool)
+}
+
+type blockKey struct {
+	bank    int
+	address types.Word
+}
+
+// blockEntry is an instruction decoded once, ready to run
+type blockEntry struct {
+	address  types.Word
+	opcode   types.Byte
+	prefixed bool
+	handler  func(c *CPU)
+	info     *Opcode
+}
+
+// block is a straight run of instructions ending with a jump, a call, a
+// return or anything else that leaves the run
+type block struct {
+	key     blockKey
+	entries []blockEntry
+	// bytes covered, for invalidation
+	from, to types.Word
+}
+
+// blockCache sits between the CPU and its bus. It keeps the decoded blocks
+// and drops those a write lands in.
+type blockCache struct {
+	Bus
+
+	blocks map[blockKey]*block
+	// blocks holding code in each 256 byte page of RAM
+	pages [0x100][]*block
+
+	current *block
+	index   int
+}
+
+func newBlockCache(bus Bus) *blockCache {
+	return &blockCache{Bus: bus, blocks: map[blockKey]*block{}}
+}
+
+// EnableBlockCache switches between the interpreter, which fetches and
+// looks up every opcode, and an engine that decodes straight-line blocks
+// once. Both execute one instruction per Step, so interrupts, cycles and
+// tracers behave the same. Opcode fetches from cached blocks are not seen by
+// the MMU watcher.
+func (c *CPU) EnableBlockCache(enabled bool) {
+	switch {
+	case enabled && c.blocks == nil:
+		c.blocks = newBlockCache(c.bus)
+		c.bus = c.blocks
+	case !enabled && c.blocks != nil:
+		c.bus = c.blocks.Bus
+		c.blocks = nil
+	}
+}
+
+// FlushBlocks forgets every decoded block. Whoever changes memory behind the
+// CPU's back, without going through its bus, must call it.
+func (c *CPU) FlushBlocks() {
+	if c.blocks != nil {
+		c.blocks = newBlockCache(c.blocks.Bus)
+		c.bus = c.blocks
+	}
+}
+
+// Poke writes a byte through the CPU's bus without taking any cycles, so the
+// decoded blocks holding the address are dropped. Tools patching memory
+// while the CPU runs, such as cheats, should use it instead of the MMU.
+func (c *CPU) Poke(address types.Word, value types.Byte) {
+	c.bus.Set(address, value)
+}
+
+// cachedInstruction returns the decoded instruction at pc, with PC moved
+// past its opcode as a fetch would, or nil when the interpreter has to fetch
+// it. The caller ticks the cycles of the skipped fetch.
+func (c *CPU) cachedInstruction(pc types.Word) *blockEntry {
+	// after the HALT bug the opcode is not where the block says it is
+	if c.blocks == nil || c.haltBug {
+		return nil
+	}
+	e := c.blocks.next(pc)
+	if e == nil {
+		return nil
+	}
+	if e.prefixed {
+		c.registers.PC.Set(pc + 2)
+	} else {
+		c.registers.PC.Set(pc + 1)
+	}
+	return e
+}
+
+func (b *blockCache) Set(address types.Word, value types.Byte) {
+	switch {
+	case address < 0x8000 || address == 0xFF50 || address == 0xFF4F || address == 0xFF70:
+		// may switch banks or unmap the boot ROM, look the block up again
+		b.current = nil
+	case address >= 0xFF00 && address < 0xFF80:
+		// no code is cached in the I/O registers
+	case address >= 0xE000 && address < 0xFE00:
+		// echo RAM writes the WRAM below it
+		b.invalidate((address - 0x2000) >> 8)
+	default:
+		b.invalidate(address >> 8)
+	}
+	b.Bus.Set(address, value)
+}
+
+// invalidate drops the blocks holding code in page, along with their entry
+// in the other page when they span two
+func (b *blockCache) invalidate(page types.Word) {
+	for _, blk := range b.pages[page] {
+		delete(b.blocks, blk.key)
+		if blk == b.current {
+			b.current = nil
+		}
+		if first := blk.from >> 8; first != page {
+			b.pages[first] = removeBlock(b.pages[first], blk)
+		} else if last := blk.to >> 8; last != page {
+			b.pages[last] = removeBlock(b.pages[last], blk)
+		}
+	}
+	b.pages[page] = nil
+}
+
+func removeBlock(blocks []*block, blk *block) []*block {
+	for i, other := range blocks {
+		if other == blk {
+			return append(blocks[:i], blocks[i+1:]...)
+		}
+	}
+	return blocks
+}
+
+// next returns the decoded instruction at pc, or nil when pc is not
+// cacheable
+func (b *blockCache) next(pc types.Word) *blockEntry {
+	if blk := b.current; blk != nil {
+		if b.index < len(blk.entries) && blk.entries[b.index].address == pc {
+			e := &blk.entries[b.index]
+			b.index++
+			return e
+		}
+	}
+
+	key := blockKey{address: pc}
+	if banked, ok := b.Bus.(BankedBus); ok {
+		bank, cacheable := banked.Bank(pc)
+		if !cacheable {
+			b.current = nil
+			return nil
+		}
+		key.bank = bank
+	}
+
+	blk, ok := b.blocks[key]
+	if !ok {
+		blk = b.decode(key)
+		b.blocks[key] = blk
+		if blk.from >= 0x8000 {
+			b.pages[blk.from>>8] = append(b.pages[blk.from>>8], blk)
+			if last := blk.to >> 8; last != blk.from>>8 {
+				b.pages[last] = append(b.pages[last], blk)
+			}
+		}
+	}
+	b.current = blk
+	b.index = 1
+	return &blk.entries[0]
+}
+
+// decode reads instructions from key.address until one leaves the straight
+// line, a region boundary is reached or the block is long enough
+func (b *blockCache) decode(key blockKey) *block {
+	blk := &block{key: key, from: key.address}
+	pc := key.address
+	for len(blk.entries) < maxBlockLength {
+		e := blockEntry{address: pc, opcode: b.Peek(pc)}
+		e.handler, e.info = handlers[e.opcode], &Opcodes[e.opcode]
+		if e.opcode == 0xCB {
+			e.prefixed = true
+			e.opcode = b.Peek(pc + 1)
+			e.handler, e.info = cbHandlers[e.opcode], &CBOpcodes[e.opcode]
+		}
+		blk.entries = append(blk.entries, e)
+
+		length := types.Word(e.info.Length)
+		if e.prefixed {
+			length = 2
+		}
+		blk.to = pc + length - 1
+		next := pc + length
+		if e.handler == nil || endsBlock(e) || next < pc || crossesRegion(pc, next) {
+			break
+		}
+		pc = next
+	}
+	return blk
+}
+
+// endsBlock reports whether execution may not continue with the next
+// instruction in memory
+func endsBlock(e blockEntry) bool {
+	if e.prefixed {
+		return false
+	}
+	return branches[e.opcode] || e.info.Illegal()
+}
+
+// crossesRegion reports whether the instruction after pc lives in a region
+// that can be mapped independently
+func crossesRegion(pc, next types.Word) bool {
+	for _, boundary := range []types.Word{0x100, 0x4000, 0x8000, 0xA000, 0xC000, 0xE000, 0xFF80} {
+		if pc < boundary && next >= boundary {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package cpu
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// selfModifyingProgram writes a routine to WRAM, runs it, and flips its
+// first opcode between INC B and INC C on every pass
+var selfModifyingProgram = []byte{
+	0x21, 0x00, 0xC0, // 0100: LD HL,$C000
+	0x3E, 0x04, // 0103: LD A,$04 (INC B)
+	0x77,       // 0105: LD (HL),A
+	0x23,       // 0106: INC HL
+	0x3E, 0xC3, // 0107: LD A,$C3 (JP)
+	0x77,       // 0109: LD (HL),A
+	0x23,       // 010A: INC HL
+	0x3E, 0x16, // 010B: LD A,$16
+	0x77,       // 010D: LD (HL),A
+	0x23,       // 010E: INC HL
+	0x3E, 0x01, // 010F: LD A,$01
+	0x77,             // 0111: LD (HL),A
+	0xC3, 0x00, 0xC0, // 0112: JP $C000
+	0x00,             // 0115: NOP
+	0xFA, 0x00, 0xC0, // 0116: LD A,($C000)
+	0xEE, 0x08, // 0119: XOR $08
+	0xEA, 0x00, 0xC0, // 011B: LD ($C000),A
+	0xC3, 0x00, 0xC0, // 011E: JP $C000
+}
+
+func TestBlockCacheMatchesInterpreter(t *testing.T) {
+	run := func(blockCache bool) (*CPU, *ramBus) {
+		bus := &ramBus{}
+		copy(bus[0x100:], selfModifyingProgram)
+		c := &CPU{}
+		c.Init()
+		c.ConnectBus(bus)
+		c.EnableBlockCache(blockCache)
+		c.registers.PC.Set(0x100)
+		c.registers.SP.Set(0xFFFE)
+		for i := 0; i < 10000; i++ {
+			if _, err := c.Step(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return c, bus
+	}
+
+	interpreted, interpretedBus := run(false)
+	cached, cachedBus := run(true)
+
+	if got, want := cached.registers.AF.Get(), interpreted.registers.AF.Get(); got != want {
+		t.Errorf("AF = %04X, want %04X", got, want)
+	}
+	if got, want := cached.registers.BC.Get(), interpreted.registers.BC.Get(); got != want {
+		t.Errorf("BC = %04X, want %04X", got, want)
+	}
+	if got, want := cached.registers.HL.Get(), interpreted.registers.HL.Get(); got != want {
+		t.Errorf("HL = %04X, want %04X", got, want)
+	}
+	if got, want := cached.PC(), interpreted.PC(); got != want {
+		t.Errorf("PC = %04X, want %04X", got, want)
+	}
+	if got, want := cached.Cycles(), interpreted.Cycles(); got != want {
+		t.Errorf("%d cycles, want %d", got, want)
+	}
+	if *cachedBus != *interpretedBus {
+		t.Error("memory differs")
+	}
+	if r := interpreted.Registers(); r.B.Get() == 0 || r.C.Get() == 0 {
+		t.Fatalf("the routine was not rewritten, B=%d C=%d", r.B.Get(), r.C.Get())
+	}
+}
+
+// newBlockCPU runs program from start with the block cache on
+func newBlockCPU(start types.Word, program []types.Byte) *CPU {
+	bus := &ramBus{}
+	copy(bus[start:], program)
+	c := &CPU{}
+	c.Init()
+	c.ConnectBus(bus)
+	c.EnableBlockCache(true)
+	c.registers.PC.Set(start)
+	c.registers.SP.Set(0xCFFE)
+	return c
+}
+
+func stepN(t *testing.T, c *CPU, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if _, err := c.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBlockSpanningPagesIsDroppedFromBoth(t *testing.T) {
+	c := newBlockCPU(0xC0FD, []types.Byte{
+		0x04,             // C0FD: INC B
+		0x04,             // C0FE: INC B
+		0x04,             // C0FF: INC B
+		0x0C,             // C100: INC C
+		0xC3, 0xFD, 0xC0, // C101: JP $C0FD
+	})
+	stepN(t, c, 5)
+	blk := c.blocks.blocks[blockKey{address: 0xC0FD}]
+	if blk == nil {
+		t.Fatal("the loop was not cached")
+	}
+
+	c.blocks.Set(0xC180, 0)
+	for _, page := range []types.Word{0xC0, 0xC1} {
+		for _, other := range c.blocks.pages[page] {
+			if other == blk {
+				t.Errorf("page %02X still lists the dropped block", page)
+			}
+		}
+	}
+	if c.blocks.blocks[blk.key] != nil {
+		t.Error("the block is still cached")
+	}
+}
+
+func TestIOWritesKeepHRAMBlocks(t *testing.T) {
+	c := newBlockCPU(0xFF80, []types.Byte{
+		0xE0, 0x01, // FF80: LDH ($01),A
+		0x3C,       // FF82: INC A
+		0x18, 0xFB, // FF83: JR $FF80
+	})
+	stepN(t, c, 3)
+	blk := c.blocks.blocks[blockKey{address: 0xFF80}]
+	if blk == nil {
+		t.Fatal("the loop was not cached")
+	}
+	stepN(t, c, 30)
+	if got := c.blocks.blocks[blockKey{address: 0xFF80}]; got != blk {
+		t.Error("writing an I/O register dropped the HRAM block")
+	}
+
+	c.blocks.Set(0xFFF0, 0)
+	if c.blocks.blocks[blockKey{address: 0xFF80}] != nil {
+		t.Error("writing HRAM kept the block")
+	}
+}
+
+func TestEchoWritesDropWRAMBlocks(t *testing.T) {
+	c := newBlockCPU(0xC000, []types.Byte{
+		0x04,             // C000: INC B
+		0xC3, 0x00, 0xC0, // C001: JP $C000
+	})
+	stepN(t, c, 2)
+	if c.blocks.blocks[blockKey{address: 0xC000}] == nil {
+		t.Fatal("the loop was not cached")
+	}
+	c.blocks.Set(0xE000, 0x0C)
+	if c.blocks.blocks[blockKey{address: 0xC000}] != nil {
+		t.Error("writing echo RAM kept the block of the WRAM below")
+	}
+}
+
+func TestBranchesMatchMnemonics(t *testing.T) {
+	for opcode, info := range Opcodes {
+		want := false
+		for _, prefix := range []string{"JP", "JR", "CALL", "RET", "RST", "HALT", "STOP"} {
+			if strings.HasPrefix(info.Mnemonic, prefix) {
+				want = true
+			}
+		}
+		if branches[opcode] != want {
+			t.Errorf("%02X %s: branches is %v", opcode, info.Mnemonic, branches[opcode])
+		}
+	}
+}
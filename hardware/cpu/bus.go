@@ -0,0 +1,26 @@
+package cpu
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// Bus is everything the CPU reaches through its address and data lines. The
+// MMU is the bus of a real machine; tests can plug in something simpler.
+type Bus interface {
+	Get(address types.Word) types.Byte
+	Set(address types.Word, value types.Byte)
+	// Peek reads without side effects, for tracing and cycle lookups
+	Peek(address types.Word) types.Byte
+	// Tick advances whatever is on the bus by the cycles just executed
+	Tick(cycles int)
+}
+
+// ConnectBus replaces the MMU as the bus the CPU executes from. The devices
+// of the MMU stay where they are but no longer see the CPU.
+func (c *CPU) ConnectBus(b Bus) {
+	c.bus = b
+	if c.blocks != nil {
+		c.blocks = newBlockCache(b)
+		c.bus = c.blocks
+	}
+}
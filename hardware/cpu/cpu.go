@@ -1,24 +1,143 @@
 package cpu
 
 import (
-	"log"
+	"github.com/cgimenes/gomenes-boy/hardware/cartridge"
 	"github.com/cgimenes/gomenes-boy/hardware/cpu/registers"
+	"github.com/cgimenes/gomenes-boy/hardware/joypad"
 	"github.com/cgimenes/gomenes-boy/hardware/memory"
+	"github.com/cgimenes/gomenes-boy/hardware/model"
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+	"github.com/cgimenes/gomenes-boy/hardware/serial"
+	"github.com/cgimenes/gomenes-boy/hardware/sgb"
 	"github.com/cgimenes/gomenes-boy/hardware/types"
 )
 
-type Instruction struct {
-	cycles uint8
-	exec func()
+// Tracer is called before every instruction the CPU executes
+type Tracer interface {
+	BeforeInstruction(c *CPU)
 }
 
 type CPU struct {
 	mmu memory.MMU
+	bus Bus
 	registers registers.Registers
+
+	// interrupt master enable
+	ime bool
+	// set by EI, which enables interrupts after the next instruction
+	eiDelay bool
+	halted bool
+	// set by a HALT that did not halt, the next opcode byte is read twice
+	haltBug bool
+	// set by the illegal opcodes, only a reset clears it
+	locked bool
+	// set by conditional instructions whose condition held
+	branched bool
+	// decoded blocks, when the block cache is enabled
+	blocks *blockCache
+	// cycles executed since power on
+	cycles uint64
+	// cycles of the current Step the bus has already been ticked for
+	ticked int
+	// hardware to behave as
+	model model.Model
+
+	Tracer Tracer
 }
 
 func (c *CPU) Init() {
 	c.initRegisters()
+	c.bus = &c.mmu
+	c.blocks = nil
+}
+
+// ConnectSerial plugs a device into the link port
+func (c *CPU) ConnectSerial(d serial.Device) {
+	c.mmu.Serial.Device = d
+}
+
+// LoadCartridge inserts a cartridge
+func (c *CPU) LoadCartridge(cart *cartridge.Cartridge) {
+	c.mmu.Cartridge = cart
+	c.applyModel()
+}
+
+// SetModel picks the hardware to behave as. With model.Auto, the default,
+// it follows the cartridge header.
+func (c *CPU) SetModel(m model.Model) {
+	c.model = m
+	c.applyModel()
+}
+
+// Model returns the hardware the CPU behaves as, Auto resolved
+func (c *CPU) Model() model.Model {
+	return c.model.Resolve(c.mmu.Cartridge)
+}
+
+// applyModel turns on the Game Boy Color hardware for CGB games on a CGB
+// and the Super Game Boy for games on an SGB
+// @todo DMG games on a CGB run as on a DMG, without the compatibility palettes
+func (c *CPU) applyModel() {
+	m := c.Model()
+	cart := c.mmu.Cartridge
+	c.mmu.SetCGB(m == model.CGB && cart != nil && cart.Header.CGB())
+	c.mmu.SGB = nil
+	if m == model.SGB {
+		c.mmu.SGB = sgb.New()
+	}
+}
+
+func (c *CPU) Press(b joypad.Button) {
+	if c.mmu.Joypad.Press(b) {
+		c.mmu.RequestInterrupt(joypad.Interrupt)
+	}
+}
+
+func (c *CPU) Release(b joypad.Button) {
+	c.mmu.Joypad.Release(b)
+}
+
+// SetButtons holds down exactly the buttons set in buttons, a bit per
+// joypad.Button
+func (c *CPU) SetButtons(buttons types.Byte) {
+	if c.mmu.Joypad.SetButtons(buttons) {
+		c.mmu.RequestInterrupt(joypad.Interrupt)
+	}
+}
+
+func (c *CPU) PPU() *ppu.PPU {
+	return &c.mmu.PPU
+}
+
+func (c *CPU) PC() types.Word {
+	return c.registers.PC.Get()
+}
+
+func (c *CPU) Registers() *registers.Registers {
+	return &c.registers
+}
+
+func (c *CPU) MMU() *memory.MMU {
+	return &c.mmu
+}
+
+// IME reports whether interrupts are enabled
+func (c *CPU) IME() bool {
+	return c.ime
+}
+
+func (c *CPU) Halted() bool {
+	return c.halted
+}
+
+// Locked reports whether the CPU hung on an illegal opcode
+func (c *CPU) Locked() bool {
+	return c.locked
+}
+
+// Cycles returns the number of cycles executed since power on
+func (c *CPU) Cycles() uint64 {
+	return c.cycles
 }
 
 func (c *CPU) initRegisters() {
@@ -53,2068 +172,1523 @@ func (c *CPU) initRegisters() {
 	c.registers.Flags = registers.Flags{R: &c.registers.F}
 }
 
-func (c *CPU) Decode(opcode types.Byte) Instruction {
-	switch opcode {
-	case 0x06:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.B, c.FetchNextByte())
-		}, cycles: 8}
-	case 0x0E:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.C, c.FetchNextByte())
-		}, cycles: 8}
-	case 0x16:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.D, c.FetchNextByte())
-		}, cycles: 8}
-	case 0x1E:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.E, c.FetchNextByte())
-		}, cycles: 8}
-	case 0x26:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.H, c.FetchNextByte())
-		}, cycles: 8}
-	case 0x2E:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.L, c.FetchNextByte())
-		}, cycles: 8}
-	case 0x7F:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.A, c.registers.A.Get())
-		}, cycles: 4}
-	case 0x78:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.A, c.registers.B.Get())
-		}, cycles: 4}
-	case 0x79:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.A, c.registers.C.Get())
-		}, cycles: 4}
-	case 0x7A:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.A, c.registers.D.Get())
-		}, cycles: 4}
-	case 0x7B:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.A, c.registers.E.Get())
-		}, cycles: 4}
-	case 0x7C:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.A, c.registers.H.Get())
-		}, cycles: 4}
-	case 0x7D:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.A, c.registers.L.Get())
-		}, cycles: 4}
-	case 0x7E:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.A, c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 8}
-	case 0x40:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.B, c.registers.B.Get())
-		}, cycles: 4}
-	case 0x41:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.B, c.registers.C.Get())
-		}, cycles: 4}
-	case 0x42:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.B, c.registers.D.Get())
-		}, cycles: 4}
-	case 0x43:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.B, c.registers.E.Get())
-		}, cycles: 4}
-	case 0x44:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.B, c.registers.H.Get())
-		}, cycles: 4}
-	case 0x45:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.B, c.registers.L.Get())
-		}, cycles: 4}
-	case 0x46:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.B, c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 8}
-	case 0x48:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.C, c.registers.B.Get())
-		}, cycles: 4}
-	case 0x49:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.C, c.registers.C.Get())
-		}, cycles: 4}
-	case 0x4A:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.C, c.registers.D.Get())
-		}, cycles: 4}
-	case 0x4B:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.C, c.registers.E.Get())
-		}, cycles: 4}
-	case 0x4C:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.C, c.registers.H.Get())
-		}, cycles: 4}
-	case 0x4D:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.C, c.registers.L.Get())
-		}, cycles: 4}
-	case 0x4E:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.C, c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 8}
-	case 0x50:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.D, c.registers.B.Get())
-		}, cycles: 4}
-	case 0x51:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.D, c.registers.C.Get())
-		}, cycles: 4}
-	case 0x52:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.D, c.registers.D.Get())
-		}, cycles: 4}
-	case 0x53:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.D, c.registers.E.Get())
-		}, cycles: 4}
-	case 0x54:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.D, c.registers.H.Get())
-		}, cycles: 4}
-	case 0x55:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.D, c.registers.L.Get())
-		}, cycles: 4}
-	case 0x56:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.D, c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 8}
-	case 0x58:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.E, c.registers.B.Get())
-		}, cycles: 4}
-	case 0x59:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.E, c.registers.C.Get())
-		}, cycles: 4}
-	case 0x5A:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.E, c.registers.D.Get())
-		}, cycles: 4}
-	case 0x5B:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.E, c.registers.E.Get())
-		}, cycles: 4}
-	case 0x5C:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.E, c.registers.H.Get())
-		}, cycles: 4}
-	case 0x5D:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.E, c.registers.L.Get())
-		}, cycles: 4}
-	case 0x5E:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.E, c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 8}
-	case 0x60:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.H, c.registers.B.Get())
-		}, cycles: 4}
-	case 0x61:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.H, c.registers.C.Get())
-		}, cycles: 4}
-	case 0x62:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.D, c.registers.D.Get())
-		}, cycles: 4}
-	case 0x63:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.H, c.registers.E.Get())
-		}, cycles: 4}
-	case 0x64:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.H, c.registers.H.Get())
-		}, cycles: 4}
-	case 0x65:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.H, c.registers.L.Get())
-		}, cycles: 4}
-	case 0x66:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.H, c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 8}
-	case 0x68:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.L, c.registers.B.Get())
-		}, cycles: 4}
-	case 0x69:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.L, c.registers.C.Get())
-		}, cycles: 4}
-	case 0x6A:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.L, c.registers.D.Get())
-		}, cycles: 4}
-	case 0x6B:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.L, c.registers.E.Get())
-		}, cycles: 4}
-	case 0x6C:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.L, c.registers.H.Get())
-		}, cycles: 4}
-	case 0x6D:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.L, c.registers.L.Get())
-		}, cycles: 4}
-	case 0x6E:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.L, c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 8}
-	case 0x70:
-		return Instruction{exec: func() {
-			c.LDm8(c.registers.HL.Get(), c.registers.B.Get())
-		}, cycles: 8}
-	case 0x71:
-		return Instruction{exec: func() {
-			c.LDm8(c.registers.HL.Get(), c.registers.C.Get())
-		}, cycles: 8}
-	case 0x72:
-		return Instruction{exec: func() {
-			c.LDm8(c.registers.HL.Get(), c.registers.D.Get())
-		}, cycles: 8}
-	case 0x73:
-		return Instruction{exec: func() {
-			c.LDm8(c.registers.HL.Get(), c.registers.E.Get())
-		}, cycles: 8}
-	case 0x74:
-		return Instruction{exec: func() {
-			c.LDm8(c.registers.HL.Get(), c.registers.H.Get())
-		}, cycles: 8}
-	case 0x75:
-		return Instruction{exec: func() {
-			c.LDm8(c.registers.HL.Get(), c.registers.L.Get())
-		}, cycles: 8}
-	case 0x36:
-		return Instruction{exec: func() {
-			c.LDm8(c.registers.HL.Get(), c.FetchNextByte())
-		}, cycles: 12}
-	case 0x0A:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.A, c.mmu.Get(c.registers.BC.Get()))
-		}, cycles: 8}
-	case 0x1A:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.A, c.mmu.Get(c.registers.DE.Get()))
-		}, cycles: 8}
-	case 0xFA:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.A, c.mmu.Get(c.FetchNextWord()))
-		}, cycles: 16}
-	case 0x3E:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.A, c.FetchNextByte())
-		}, cycles: 8}
-	case 0x47:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.B, c.registers.A.Get())
-		}, cycles: 4}
-	case 0x4F:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.C, c.registers.A.Get())
-		}, cycles: 4}
-	case 0x57:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.D, c.registers.A.Get())
-		}, cycles: 4}
-	case 0x5F:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.E, c.registers.A.Get())
-		}, cycles: 4}
-	case 0x67:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.H, c.registers.A.Get())
-		}, cycles: 4}
-	case 0x6F:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.L, c.registers.A.Get())
-		}, cycles: 4}
-	case 0x02:
-		return Instruction{exec: func() {
-			c.LDm8(c.registers.BC.Get(), c.registers.A.Get())
-		}, cycles: 8}
-	case 0x12:
-		return Instruction{exec: func() {
-			c.LDm8(c.registers.DE.Get(), c.registers.A.Get())
-		}, cycles: 8}
-	case 0x77:
-		return Instruction{exec: func() {
-			c.LDm8(c.registers.HL.Get(), c.registers.A.Get())
-		}, cycles: 8}
-	case 0xEA:
-		return Instruction{exec: func() {
-			c.LDm8(c.FetchNextWord(), c.registers.A.Get())
-		}, cycles: 16}
-	case 0xF2:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.A, c.mmu.Get(0xFF00 + types.Word(c.registers.C.Get())))
-		}, cycles: 8}
-	case 0xE2:
-		return Instruction{exec: func() {
-			c.LDm8(0xFF00 + types.Word(c.registers.C.Get()), c.registers.A.Get())
-		}, cycles: 8}
-	case 0x3A:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.A, c.mmu.Get(c.registers.HL.Get()))
-			c.DEC16(&c.registers.HL)
-		}, cycles: 8}
-	case 0x32:
-		return Instruction{exec: func() {
-			c.LDm8(c.registers.HL.Get(), c.registers.A.Get())
-			c.DEC16(&c.registers.HL)
-		}, cycles: 8}
-	case 0x2A:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.A, c.mmu.Get(c.registers.HL.Get()))
-			c.INC16(&c.registers.HL)
-		}, cycles: 8}
-	case 0x22:
-		return Instruction{exec: func() {
-			c.LDm8(c.registers.HL.Get(), c.registers.A.Get())
-			c.INC16(&c.registers.HL)
-		}, cycles: 8}
-	case 0xE0:
-		return Instruction{exec: func() {
-			c.LDm8(0xFF00 + types.Word(c.FetchNextByte()), c.registers.A.Get())
-		}, cycles: 12}
-	case 0xF0:
-		return Instruction{exec: func() {
-			c.LDr8(c.registers.A, c.mmu.Get(0xFF00 + types.Word(c.FetchNextByte())))
-		}, cycles: 12}
-	case 0x01:
-		return Instruction{exec: func() {
-			c.LDr16(&c.registers.BC, c.FetchNextWord())
-		}, cycles: 12}
-	case 0x11:
-		return Instruction{exec: func() {
-			c.LDr16(&c.registers.DE, c.FetchNextWord())
-		}, cycles: 12}
-	case 0x21:
-		return Instruction{exec: func() {
-			c.LDr16(&c.registers.HL, c.FetchNextWord())
-		}, cycles: 12}
-	case 0x31:
-		return Instruction{exec: func() {
-			c.LDr16(&c.registers.SP, c.FetchNextWord())
-		}, cycles: 12}
-	case 0xF9:
-		return Instruction{exec: func() {
-			c.LDr16(&c.registers.SP, c.registers.HL.Get())
-		}, cycles: 8}
-	case 0xF8:
-		return Instruction{exec: func() {
-			var n = c.FetchNextByte()
-
-			// @todo what is this?
-			if n > 127 {
-				c.LDr16(&c.registers.HL, c.registers.SP.Get() - types.Word(-n))
-			} else {
-				c.LDr16(&c.registers.HL, c.registers.SP.Get() + types.Word(n))
-			}
-
-			var check = c.registers.SP.Get() ^ types.Word(n) ^ ((c.registers.SP.Get() + types.Word(n)) & 0xffff)
-
-			// @todo is this the carry alg?
-			if (check & 0x100) == 0x100 {
-				c.registers.Flags.Set(registers.C)
-			} else {
-				c.registers.Flags.Reset(registers.C)
-			}
-
-			// @todo is this the half carry alg?
-			if (check & 0x10) == 0x10 {
-				c.registers.Flags.Set(registers.H)
-			} else {
-				c.registers.Flags.Reset(registers.H)
-			}
-
-			c.registers.Flags.Reset(registers.Z)
-			c.registers.Flags.Reset(registers.N)
-		}, cycles: 12}
-	case 0x08:
-		return Instruction{exec: func() {
-			h, l := types.WordToBytes(c.registers.SP.Get())
-			addr := c.FetchNextWord()
-
-			c.mmu.Set(addr+1, h)
-			c.mmu.Set(addr, l)
-		}, cycles: 20}
-	case 0xF5:
-		return Instruction{exec: func() {
-			c.PushWord(c.registers.AF.Get())
-		}, cycles: 16}
-	case 0xC5:
-		return Instruction{exec: func() {
-			c.PushWord(c.registers.BC.Get())
-		}, cycles: 16}
-	case 0xD5:
-		return Instruction{exec: func() {
-			c.PushWord(c.registers.DE.Get())
-		}, cycles: 16}
-	case 0xE5:
-		return Instruction{exec: func() {
-			c.PushWord(c.registers.HL.Get())
-		}, cycles: 16}
-	case 0xF1:
-		return Instruction{exec: func() {
-			c.PopWord(&c.registers.AF)
-		}, cycles: 12}
-	case 0xC1:
-		return Instruction{exec: func() {
-			c.PopWord(&c.registers.BC)
-		}, cycles: 12}
-	case 0xD1:
-		return Instruction{exec: func() {
-			c.PopWord(&c.registers.DE)
-		}, cycles: 12}
-	case 0xE1:
-		return Instruction{exec: func() {
-			c.PopWord(&c.registers.HL)
-		}, cycles: 12}
+// handlers execute the unprefixed opcodes, their operands are fetched
+// from PC. Opcodes without a handler are not implemented.
+var handlers = [256]func(c *CPU){
+	0x06: func(c *CPU) {
+		c.LDr8(&c.registers.B, c.FetchNextByte())
+	},
+	0x0E: func(c *CPU) {
+		c.LDr8(&c.registers.C, c.FetchNextByte())
+	},
+	0x16: func(c *CPU) {
+		c.LDr8(&c.registers.D, c.FetchNextByte())
+	},
+	0x1E: func(c *CPU) {
+		c.LDr8(&c.registers.E, c.FetchNextByte())
+	},
+	0x26: func(c *CPU) {
+		c.LDr8(&c.registers.H, c.FetchNextByte())
+	},
+	0x2E: func(c *CPU) {
+		c.LDr8(&c.registers.L, c.FetchNextByte())
+	},
+	0x7F: func(c *CPU) {
+		c.LDr8(&c.registers.A, c.registers.A.Get())
+	},
+	0x78: func(c *CPU) {
+		c.LDr8(&c.registers.A, c.registers.B.Get())
+	},
+	0x79: func(c *CPU) {
+		c.LDr8(&c.registers.A, c.registers.C.Get())
+	},
+	0x7A: func(c *CPU) {
+		c.LDr8(&c.registers.A, c.registers.D.Get())
+	},
+	0x7B: func(c *CPU) {
+		c.LDr8(&c.registers.A, c.registers.E.Get())
+	},
+	0x7C: func(c *CPU) {
+		c.LDr8(&c.registers.A, c.registers.H.Get())
+	},
+	0x7D: func(c *CPU) {
+		c.LDr8(&c.registers.A, c.registers.L.Get())
+	},
+	0x7E: func(c *CPU) {
+		c.LDr8(&c.registers.A, c.read(c.registers.HL.Get()))
+	},
+	0x40: func(c *CPU) {
+		c.LDr8(&c.registers.B, c.registers.B.Get())
+	},
+	0x41: func(c *CPU) {
+		c.LDr8(&c.registers.B, c.registers.C.Get())
+	},
+	0x42: func(c *CPU) {
+		c.LDr8(&c.registers.B, c.registers.D.Get())
+	},
+	0x43: func(c *CPU) {
+		c.LDr8(&c.registers.B, c.registers.E.Get())
+	},
+	0x44: func(c *CPU) {
+		c.LDr8(&c.registers.B, c.registers.H.Get())
+	},
+	0x45: func(c *CPU) {
+		c.LDr8(&c.registers.B, c.registers.L.Get())
+	},
+	0x46: func(c *CPU) {
+		c.LDr8(&c.registers.B, c.read(c.registers.HL.Get()))
+	},
+	0x48: func(c *CPU) {
+		c.LDr8(&c.registers.C, c.registers.B.Get())
+	},
+	0x49: func(c *CPU) {
+		c.LDr8(&c.registers.C, c.registers.C.Get())
+	},
+	0x4A: func(c *CPU) {
+		c.LDr8(&c.registers.C, c.registers.D.Get())
+	},
+	0x4B: func(c *CPU) {
+		c.LDr8(&c.registers.C, c.registers.E.Get())
+	},
+	0x4C: func(c *CPU) {
+		c.LDr8(&c.registers.C, c.registers.H.Get())
+	},
+	0x4D: func(c *CPU) {
+		c.LDr8(&c.registers.C, c.registers.L.Get())
+	},
+	0x4E: func(c *CPU) {
+		c.LDr8(&c.registers.C, c.read(c.registers.HL.Get()))
+	},
+	0x50: func(c *CPU) {
+		c.LDr8(&c.registers.D, c.registers.B.Get())
+	},
+	0x51: func(c *CPU) {
+		c.LDr8(&c.registers.D, c.registers.C.Get())
+	},
+	0x52: func(c *CPU) {
+		c.LDr8(&c.registers.D, c.registers.D.Get())
+	},
+	0x53: func(c *CPU) {
+		c.LDr8(&c.registers.D, c.registers.E.Get())
+	},
+	0x54: func(c *CPU) {
+		c.LDr8(&c.registers.D, c.registers.H.Get())
+	},
+	0x55: func(c *CPU) {
+		c.LDr8(&c.registers.D, c.registers.L.Get())
+	},
+	0x56: func(c *CPU) {
+		c.LDr8(&c.registers.D, c.read(c.registers.HL.Get()))
+	},
+	0x58: func(c *CPU) {
+		c.LDr8(&c.registers.E, c.registers.B.Get())
+	},
+	0x59: func(c *CPU) {
+		c.LDr8(&c.registers.E, c.registers.C.Get())
+	},
+	0x5A: func(c *CPU) {
+		c.LDr8(&c.registers.E, c.registers.D.Get())
+	},
+	0x5B: func(c *CPU) {
+		c.LDr8(&c.registers.E, c.registers.E.Get())
+	},
+	0x5C: func(c *CPU) {
+		c.LDr8(&c.registers.E, c.registers.H.Get())
+	},
+	0x5D: func(c *CPU) {
+		c.LDr8(&c.registers.E, c.registers.L.Get())
+	},
+	0x5E: func(c *CPU) {
+		c.LDr8(&c.registers.E, c.read(c.registers.HL.Get()))
+	},
+	0x60: func(c *CPU) {
+		c.LDr8(&c.registers.H, c.registers.B.Get())
+	},
+	0x61: func(c *CPU) {
+		c.LDr8(&c.registers.H, c.registers.C.Get())
+	},
+	0x62: func(c *CPU) {
+		c.LDr8(&c.registers.H, c.registers.D.Get())
+	},
+	0x63: func(c *CPU) {
+		c.LDr8(&c.registers.H, c.registers.E.Get())
+	},
+	0x64: func(c *CPU) {
+		c.LDr8(&c.registers.H, c.registers.H.Get())
+	},
+	0x65: func(c *CPU) {
+		c.LDr8(&c.registers.H, c.registers.L.Get())
+	},
+	0x66: func(c *CPU) {
+		c.LDr8(&c.registers.H, c.read(c.registers.HL.Get()))
+	},
+	0x68: func(c *CPU) {
+		c.LDr8(&c.registers.L, c.registers.B.Get())
+	},
+	0x69: func(c *CPU) {
+		c.LDr8(&c.registers.L, c.registers.C.Get())
+	},
+	0x6A: func(c *CPU) {
+		c.LDr8(&c.registers.L, c.registers.D.Get())
+	},
+	0x6B: func(c *CPU) {
+		c.LDr8(&c.registers.L, c.registers.E.Get())
+	},
+	0x6C: func(c *CPU) {
+		c.LDr8(&c.registers.L, c.registers.H.Get())
+	},
+	0x6D: func(c *CPU) {
+		c.LDr8(&c.registers.L, c.registers.L.Get())
+	},
+	0x6E: func(c *CPU) {
+		c.LDr8(&c.registers.L, c.read(c.registers.HL.Get()))
+	},
+	0x70: func(c *CPU) {
+		c.LDm8(c.registers.HL.Get(), c.registers.B.Get())
+	},
+	0x71: func(c *CPU) {
+		c.LDm8(c.registers.HL.Get(), c.registers.C.Get())
+	},
+	0x72: func(c *CPU) {
+		c.LDm8(c.registers.HL.Get(), c.registers.D.Get())
+	},
+	0x73: func(c *CPU) {
+		c.LDm8(c.registers.HL.Get(), c.registers.E.Get())
+	},
+	0x74: func(c *CPU) {
+		c.LDm8(c.registers.HL.Get(), c.registers.H.Get())
+	},
+	0x75: func(c *CPU) {
+		c.LDm8(c.registers.HL.Get(), c.registers.L.Get())
+	},
+	0x36: func(c *CPU) {
+		c.LDm8(c.registers.HL.Get(), c.FetchNextByte())
+	},
+	0x0A: func(c *CPU) {
+		c.LDr8(&c.registers.A, c.read(c.registers.BC.Get()))
+	},
+	0x1A: func(c *CPU) {
+		c.LDr8(&c.registers.A, c.read(c.registers.DE.Get()))
+	},
+	0xFA: func(c *CPU) {
+		c.LDr8(&c.registers.A, c.read(c.FetchNextWord()))
+	},
+	0x3E: func(c *CPU) {
+		c.LDr8(&c.registers.A, c.FetchNextByte())
+	},
+	0x47: func(c *CPU) {
+		c.LDr8(&c.registers.B, c.registers.A.Get())
+	},
+	0x4F: func(c *CPU) {
+		c.LDr8(&c.registers.C, c.registers.A.Get())
+	},
+	0x57: func(c *CPU) {
+		c.LDr8(&c.registers.D, c.registers.A.Get())
+	},
+	0x5F: func(c *CPU) {
+		c.LDr8(&c.registers.E, c.registers.A.Get())
+	},
+	0x67: func(c *CPU) {
+		c.LDr8(&c.registers.H, c.registers.A.Get())
+	},
+	0x6F: func(c *CPU) {
+		c.LDr8(&c.registers.L, c.registers.A.Get())
+	},
+	0x02: func(c *CPU) {
+		c.LDm8(c.registers.BC.Get(), c.registers.A.Get())
+	},
+	0x12: func(c *CPU) {
+		c.LDm8(c.registers.DE.Get(), c.registers.A.Get())
+	},
+	0x77: func(c *CPU) {
+		c.LDm8(c.registers.HL.Get(), c.registers.A.Get())
+	},
+	0xEA: func(c *CPU) {
+		c.LDm8(c.FetchNextWord(), c.registers.A.Get())
+	},
+	0xF2: func(c *CPU) {
+		c.LDr8(&c.registers.A, c.read(0xFF00 + types.Word(c.registers.C.Get())))
+	},
+	0xE2: func(c *CPU) {
+		c.LDm8(0xFF00 + types.Word(c.registers.C.Get()), c.registers.A.Get())
+	},
+	0x3A: func(c *CPU) {
+		c.LDr8(&c.registers.A, c.read(c.registers.HL.Get()))
+		c.DEC16(&c.registers.HL)
+	},
+	0x32: func(c *CPU) {
+		c.LDm8(c.registers.HL.Get(), c.registers.A.Get())
+		c.DEC16(&c.registers.HL)
+	},
+	0x2A: func(c *CPU) {
+		c.LDr8(&c.registers.A, c.read(c.registers.HL.Get()))
+		c.INC16(&c.registers.HL)
+	},
+	0x22: func(c *CPU) {
+		c.LDm8(c.registers.HL.Get(), c.registers.A.Get())
+		c.INC16(&c.registers.HL)
+	},
+	0xE0: func(c *CPU) {
+		c.LDm8(0xFF00 + types.Word(c.FetchNextByte()), c.registers.A.Get())
+	},
+	0xF0: func(c *CPU) {
+		c.LDr8(&c.registers.A, c.read(0xFF00 + types.Word(c.FetchNextByte())))
+	},
+	0x01: func(c *CPU) {
+		c.LDr16(&c.registers.BC, c.FetchNextWord())
+	},
+	0x11: func(c *CPU) {
+		c.LDr16(&c.registers.DE, c.FetchNextWord())
+	},
+	0x21: func(c *CPU) {
+		c.LDr16(&c.registers.HL, c.FetchNextWord())
+	},
+	0x31: func(c *CPU) {
+		c.LDr16(&c.registers.SP, c.FetchNextWord())
+	},
+	0xF9: func(c *CPU) {
+		c.LDr16(&c.registers.SP, c.registers.HL.Get())
+	},
+	0xF8: func(c *CPU) {
+		c.LDr16(&c.registers.HL, c.addSP(c.FetchNextByte()))
+	},
+	0x08: func(c *CPU) {
+		h, l := types.WordToBytes(c.registers.SP.Get())
+		addr := c.FetchNextWord()
+
+		c.write(addr, l)
+		c.write(addr+1, h)
+	},
+	0xF5: func(c *CPU) {
+		c.PushWord(c.registers.AF.Get())
+	},
+	0xC5: func(c *CPU) {
+		c.PushWord(c.registers.BC.Get())
+	},
+	0xD5: func(c *CPU) {
+		c.PushWord(c.registers.DE.Get())
+	},
+	0xE5: func(c *CPU) {
+		c.PushWord(c.registers.HL.Get())
+	},
+	0xF1: func(c *CPU) {
+		c.PopWord(&c.registers.AF)
+		// the low nibble of F does not exist
+		c.registers.F.Set(c.registers.F.Get() & 0xF0)
+	},
+	0xC1: func(c *CPU) {
+		c.PopWord(&c.registers.BC)
+	},
+	0xD1: func(c *CPU) {
+		c.PopWord(&c.registers.DE)
+	},
+	0xE1: func(c *CPU) {
+		c.PopWord(&c.registers.HL)
+	},
 	// ALU start
-	case 0x87:
-		return Instruction{exec: func() {
-			c.ADD8(c.registers.A.Get())
-		}, cycles: 4}
-	case 0x80:
-		return Instruction{exec: func() {
-			c.ADD8(c.registers.B.Get())
-		}, cycles: 4}
-	case 0x81:
-		return Instruction{exec: func() {
-			c.ADD8(c.registers.C.Get())
-		}, cycles: 4}
-	case 0x82:
-		return Instruction{exec: func() {
-			c.ADD8(c.registers.D.Get())
-		}, cycles: 4}
-	case 0x83:
-		return Instruction{exec: func() {
-			c.ADD8(c.registers.E.Get())
-		}, cycles: 4}
-	case 0x84:
-		return Instruction{exec: func() {
-			c.ADD8(c.registers.H.Get())
-		}, cycles: 4}
-	case 0x85:
-		return Instruction{exec: func() {
-			c.ADD8(c.registers.L.Get())
-		}, cycles: 4}
-	case 0x86:
-		return Instruction{exec: func() {
-			c.ADD8(c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 8}
-	case 0xC6:
-		return Instruction{exec: func() {
-			c.ADD8(c.FetchNextByte())
-		}, cycles: 8}
-	case 0x8F:
-		return Instruction{exec: func() {
-			c.ADD8(c.registers.A.Get() + c.registers.Flags.Get(registers.C))
-		}, cycles: 4}
-	case 0x88:
-		return Instruction{exec: func() {
-			c.ADD8(c.registers.B.Get() + c.registers.Flags.Get(registers.C))
-		}, cycles: 4}
-	case 0x89:
-		return Instruction{exec: func() {
-			c.ADD8(c.registers.C.Get() + c.registers.Flags.Get(registers.C))
-		}, cycles: 4}
-	case 0x8A:
-		return Instruction{exec: func() {
-			c.ADD8(c.registers.D.Get() + c.registers.Flags.Get(registers.C))
-		}, cycles: 4}
-	case 0x8B:
-		return Instruction{exec: func() {
-			c.ADD8(c.registers.E.Get() + c.registers.Flags.Get(registers.C))
-		}, cycles: 4}
-	case 0x8C:
-		return Instruction{exec: func() {
-			c.ADD8(c.registers.H.Get() + c.registers.Flags.Get(registers.C))
-		}, cycles: 4}
-	case 0x8D:
-		return Instruction{exec: func() {
-			c.ADD8(c.registers.L.Get() + c.registers.Flags.Get(registers.C))
-		}, cycles: 4}
-	case 0x8E:
-		return Instruction{exec: func() {
-			c.ADD8(c.mmu.Get(c.registers.HL.Get()) + c.registers.Flags.Get(registers.C))
-		}, cycles: 8}
-	case 0xCE:
-		return Instruction{exec: func() {
-			c.ADD8(c.FetchNextByte() + c.registers.Flags.Get(registers.C))
-		}, cycles: 8}
-	case 0x97:
-		return Instruction{exec: func() {
-			c.SUB8(c.registers.A.Get())
-		}, cycles: 4}
-	case 0x90:
-		return Instruction{exec: func() {
-			c.SUB8(c.registers.B.Get())
-		}, cycles: 4}
-	case 0x91:
-		return Instruction{exec: func() {
-			c.SUB8(c.registers.C.Get())
-		}, cycles: 4}
-	case 0x92:
-		return Instruction{exec: func() {
-			c.SUB8(c.registers.D.Get())
-		}, cycles: 4}
-	case 0x93:
-		return Instruction{exec: func() {
-			c.SUB8(c.registers.E.Get())
-		}, cycles: 4}
-	case 0x94:
-		return Instruction{exec: func() {
-			c.SUB8(c.registers.H.Get())
-		}, cycles: 4}
-	case 0x95:
-		return Instruction{exec: func() {
-			c.SUB8(c.registers.L.Get())
-		}, cycles: 4}
-	case 0x96:
-		return Instruction{exec: func() {
-			c.SUB8(c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 8}
-	case 0xD6:
-		return Instruction{exec: func() {
-			c.SUB8(c.FetchNextByte())
-		}, cycles: 8}
-	case 0x9F:
-		return Instruction{exec: func() {
-			c.SUB8(c.registers.A.Get() + c.registers.Flags.Get(registers.C))
-		}, cycles: 4}
-	case 0x98:
-		return Instruction{exec: func() {
-			c.SUB8(c.registers.B.Get() + c.registers.Flags.Get(registers.C))
-		}, cycles: 4}
-	case 0x99:
-		return Instruction{exec: func() {
-			c.SUB8(c.registers.C.Get() + c.registers.Flags.Get(registers.C))
-		}, cycles: 4}
-	case 0x9A:
-		return Instruction{exec: func() {
-			c.SUB8(c.registers.D.Get() + c.registers.Flags.Get(registers.C))
-		}, cycles: 4}
-	case 0x9B:
-		return Instruction{exec: func() {
-			c.SUB8(c.registers.E.Get() + c.registers.Flags.Get(registers.C))
-		}, cycles: 4}
-	case 0x9C:
-		return Instruction{exec: func() {
-			c.SUB8(c.registers.H.Get() + c.registers.Flags.Get(registers.C))
-		}, cycles: 4}
-	case 0x9D:
-		return Instruction{exec: func() {
-			c.SUB8(c.registers.L.Get() + c.registers.Flags.Get(registers.C))
-		}, cycles: 4}
-	case 0x9E:
-		return Instruction{exec: func() {
-			c.SUB8(c.mmu.Get(c.registers.HL.Get()) + c.registers.Flags.Get(registers.C))
-		}, cycles: 8}
-	case 0xDE:
-		return Instruction{exec: func() {
-			c.SUB8(c.FetchNextByte() + c.registers.Flags.Get(registers.C))
-		}, cycles: 8}
-	case 0xA7:
-		return Instruction{exec: func() {
-			c.AND8(c.registers.A.Get())
-		}, cycles: 4}
-	case 0xA0:
-		return Instruction{exec: func() {
-			c.AND8(c.registers.B.Get())
-		}, cycles: 4}
-	case 0xA1:
-		return Instruction{exec: func() {
-			c.AND8(c.registers.C.Get())
-		}, cycles: 4}
-	case 0xA2:
-		return Instruction{exec: func() {
-			c.AND8(c.registers.D.Get())
-		}, cycles: 4}
-	case 0xA3:
-		return Instruction{exec: func() {
-			c.AND8(c.registers.E.Get())
-		}, cycles: 4}
-	case 0xA4:
-		return Instruction{exec: func() {
-			c.AND8(c.registers.H.Get())
-		}, cycles: 4}
-	case 0xA5:
-		return Instruction{exec: func() {
-			c.AND8(c.registers.L.Get())
-		}, cycles: 4}
-	case 0xA6:
-		return Instruction{exec: func() {
-			c.AND8(c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 8}
-	case 0xE6:
-		return Instruction{exec: func() {
-			c.AND8(c.FetchNextByte())
-		}, cycles: 8}
-	case 0xB7:
-		return Instruction{exec: func() {
-			c.OR8(c.registers.A.Get())
-		}, cycles: 4}
-	case 0xB0:
-		return Instruction{exec: func() {
-			c.OR8(c.registers.B.Get())
-		}, cycles: 4}
-	case 0xB1:
-		return Instruction{exec: func() {
-			c.OR8(c.registers.C.Get())
-		}, cycles: 4}
-	case 0xB2:
-		return Instruction{exec: func() {
-			c.OR8(c.registers.D.Get())
-		}, cycles: 4}
-	case 0xB3:
-		return Instruction{exec: func() {
-			c.OR8(c.registers.E.Get())
-		}, cycles: 4}
-	case 0xB4:
-		return Instruction{exec: func() {
-			c.OR8(c.registers.H.Get())
-		}, cycles: 4}
-	case 0xB5:
-		return Instruction{exec: func() {
-			c.OR8(c.registers.L.Get())
-		}, cycles: 4}
-	case 0xB6:
-		return Instruction{exec: func() {
-			c.OR8(c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 8}
-	case 0xF6:
-		return Instruction{exec: func() {
-			c.OR8(c.FetchNextByte())
-		}, cycles: 8}
-	case 0xAF:
-		return Instruction{exec: func() {
-			c.XOR8(c.registers.A.Get())
-		}, cycles: 4}
-	case 0xA8:
-		return Instruction{exec: func() {
-			c.XOR8(c.registers.B.Get())
-		}, cycles: 4}
-	case 0xA9:
-		return Instruction{exec: func() {
-			c.XOR8(c.registers.C.Get())
-		}, cycles: 4}
-	case 0xAA:
-		return Instruction{exec: func() {
-			c.XOR8(c.registers.D.Get())
-		}, cycles: 4}
-	case 0xAB:
-		return Instruction{exec: func() {
-			c.XOR8(c.registers.E.Get())
-		}, cycles: 4}
-	case 0xAC:
-		return Instruction{exec: func() {
-			c.XOR8(c.registers.H.Get())
-		}, cycles: 4}
-	case 0xAD:
-		return Instruction{exec: func() {
-			c.XOR8(c.registers.L.Get())
-		}, cycles: 4}
-	case 0xAE:
-		return Instruction{exec: func() {
-			c.XOR8(c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 8}
-	case 0xEE:
-		return Instruction{exec: func() {
-			c.XOR8(c.FetchNextByte())
-		}, cycles: 8}
-	case 0xBF:
-		return Instruction{exec: func() {
-			c.CP8(c.registers.A.Get())
-		}, cycles: 4}
-	case 0xB8:
-		return Instruction{exec: func() {
-			c.CP8(c.registers.B.Get())
-		}, cycles: 4}
-	case 0xB9:
-		return Instruction{exec: func() {
-			c.CP8(c.registers.C.Get())
-		}, cycles: 4}
-	case 0xBA:
-		return Instruction{exec: func() {
-			c.CP8(c.registers.D.Get())
-		}, cycles: 4}
-	case 0xBB:
-		return Instruction{exec: func() {
-			c.CP8(c.registers.E.Get())
-		}, cycles: 4}
-	case 0xBC:
-		return Instruction{exec: func() {
-			c.CP8(c.registers.H.Get())
-		}, cycles: 4}
-	case 0xBD:
-		return Instruction{exec: func() {
-			c.CP8(c.registers.L.Get())
-		}, cycles: 4}
-	case 0xBE:
-		return Instruction{exec: func() {
-			c.CP8(c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 8}
-	case 0xFE:
-		return Instruction{exec: func() {
-			c.CP8(c.FetchNextByte())
-		}, cycles: 8}
-	case 0x3C:
-		return Instruction{exec: func() {
-			c.INCr8(c.registers.A)
-		}, cycles: 4}
-	case 0x04:
-		return Instruction{exec: func() {
-			c.INCr8(c.registers.B)
-		}, cycles: 4}
-	case 0x0C:
-		return Instruction{exec: func() {
-			c.INCr8(c.registers.C)
-		}, cycles: 4}
-	case 0x14:
-		return Instruction{exec: func() {
-			c.INCr8(c.registers.D)
-		}, cycles: 4}
-	case 0x1C:
-		return Instruction{exec: func() {
-			c.INCr8(c.registers.E)
-		}, cycles: 4}
-	case 0x24:
-		return Instruction{exec: func() {
-			c.INCr8(c.registers.H)
-		}, cycles: 4}
-	case 0x2C:
-		return Instruction{exec: func() {
-			c.INCr8(c.registers.L)
-		}, cycles: 4}
-	case 0x34:
-		return Instruction{exec: func() {
-			c.INCm8(c.registers.HL.Get())
-		}, cycles: 12}
-	case 0x3D:
-		return Instruction{exec: func() {
-			c.DECr8(c.registers.A)
-		}, cycles: 4}
-	case 0x05:
-		return Instruction{exec: func() {
-			c.DECr8(c.registers.B)
-		}, cycles: 4}
-	case 0x0D:
-		return Instruction{exec: func() {
-			c.DECr8(c.registers.C)
-		}, cycles: 4}
-	case 0x15:
-		return Instruction{exec: func() {
-			c.DECr8(c.registers.D)
-		}, cycles: 4}
-	case 0x1D:
-		return Instruction{exec: func() {
-			c.DECr8(c.registers.E)
-		}, cycles: 4}
-	case 0x25:
-		return Instruction{exec: func() {
-			c.DECr8(c.registers.H)
-		}, cycles: 4}
-	case 0x2D:
-		return Instruction{exec: func() {
-			c.DECr8(c.registers.L)
-		}, cycles: 4}
-	case 0x35:
-		return Instruction{exec: func() {
-			c.DECm8(c.registers.HL.Get())
-		}, cycles: 12}
-	case 0x09:
-		return Instruction{exec: func() {
-			c.ADD16(c.registers.BC.Get())
-		}, cycles: 8}
-	case 0x19:
-		return Instruction{exec: func() {
-			c.ADD16(c.registers.DE.Get())
-		}, cycles: 8}
-	case 0x29:
-		return Instruction{exec: func() {
-			c.ADD16(c.registers.HL.Get())
-		}, cycles: 8}
-	case 0x39:
-		return Instruction{exec: func() {
-			c.ADD16(c.registers.SP.Get())
-		}, cycles: 8}
-	case 0xE8:
-		return Instruction{exec: func() {
-			var n = c.FetchNextByte()
-
-			// @todo what is this?
-			if n > 127 {
-				c.LDr16(&c.registers.SP, c.registers.SP.Get() - types.Word(-n))
-			} else {
-				c.LDr16(&c.registers.SP, c.registers.SP.Get() + types.Word(n))
-			}
-
-			var check = c.registers.SP.Get() ^ types.Word(n) ^ ((c.registers.SP.Get() + types.Word(n)) & 0xffff)
-
-			// @todo is this the carry alg?
-			if (check & 0x100) == 0x100 {
-				c.registers.Flags.Set(registers.C)
-			} else {
-				c.registers.Flags.Reset(registers.C)
-			}
-
-			// @todo is this the half carry alg?
-			if (check & 0x10) == 0x10 {
-				c.registers.Flags.Set(registers.H)
-			} else {
-				c.registers.Flags.Reset(registers.H)
-			}
-
-			c.registers.Flags.Reset(registers.Z)
-			c.registers.Flags.Reset(registers.N)
-		}, cycles: 16}
-	case 0x03:
-		return Instruction{exec: func() {
-			c.INC16(&c.registers.BC)
-		}, cycles: 8}
-	case 0x13:
-		return Instruction{exec: func() {
-			c.INC16(&c.registers.DE)
-		}, cycles: 8}
-	case 0x23:
-		return Instruction{exec: func() {
-			c.INC16(&c.registers.HL)
-		}, cycles: 8}
-	case 0x33:
-		return Instruction{exec: func() {
-			c.INC16(&c.registers.SP)
-		}, cycles: 8}
-	case 0x0B:
-		return Instruction{exec: func() {
-			c.DEC16(&c.registers.BC)
-		}, cycles: 8}
-	case 0x1B:
-		return Instruction{exec: func() {
-			c.DEC16(&c.registers.DE)
-		}, cycles: 8}
-	case 0x2B:
-		return Instruction{exec: func() {
-			c.DEC16(&c.registers.HL)
-		}, cycles: 8}
-	case 0x3B:
-		return Instruction{exec: func() {
-			c.DEC16(&c.registers.SP)
-		}, cycles: 8}
+	0x87: func(c *CPU) {
+		c.ADD8(c.registers.A.Get())
+	},
+	0x80: func(c *CPU) {
+		c.ADD8(c.registers.B.Get())
+	},
+	0x81: func(c *CPU) {
+		c.ADD8(c.registers.C.Get())
+	},
+	0x82: func(c *CPU) {
+		c.ADD8(c.registers.D.Get())
+	},
+	0x83: func(c *CPU) {
+		c.ADD8(c.registers.E.Get())
+	},
+	0x84: func(c *CPU) {
+		c.ADD8(c.registers.H.Get())
+	},
+	0x85: func(c *CPU) {
+		c.ADD8(c.registers.L.Get())
+	},
+	0x86: func(c *CPU) {
+		c.ADD8(c.read(c.registers.HL.Get()))
+	},
+	0xC6: func(c *CPU) {
+		c.ADD8(c.FetchNextByte())
+	},
+	0x8F: func(c *CPU) {
+		c.ADC8(c.registers.A.Get())
+	},
+	0x88: func(c *CPU) {
+		c.ADC8(c.registers.B.Get())
+	},
+	0x89: func(c *CPU) {
+		c.ADC8(c.registers.C.Get())
+	},
+	0x8A: func(c *CPU) {
+		c.ADC8(c.registers.D.Get())
+	},
+	0x8B: func(c *CPU) {
+		c.ADC8(c.registers.E.Get())
+	},
+	0x8C: func(c *CPU) {
+		c.ADC8(c.registers.H.Get())
+	},
+	0x8D: func(c *CPU) {
+		c.ADC8(c.registers.L.Get())
+	},
+	0x8E: func(c *CPU) {
+		c.ADC8(c.read(c.registers.HL.Get()))
+	},
+	0xCE: func(c *CPU) {
+		c.ADC8(c.FetchNextByte())
+	},
+	0x97: func(c *CPU) {
+		c.SUB8(c.registers.A.Get())
+	},
+	0x90: func(c *CPU) {
+		c.SUB8(c.registers.B.Get())
+	},
+	0x91: func(c *CPU) {
+		c.SUB8(c.registers.C.Get())
+	},
+	0x92: func(c *CPU) {
+		c.SUB8(c.registers.D.Get())
+	},
+	0x93: func(c *CPU) {
+		c.SUB8(c.registers.E.Get())
+	},
+	0x94: func(c *CPU) {
+		c.SUB8(c.registers.H.Get())
+	},
+	0x95: func(c *CPU) {
+		c.SUB8(c.registers.L.Get())
+	},
+	0x96: func(c *CPU) {
+		c.SUB8(c.read(c.registers.HL.Get()))
+	},
+	0xD6: func(c *CPU) {
+		c.SUB8(c.FetchNextByte())
+	},
+	0x9F: func(c *CPU) {
+		c.SBC8(c.registers.A.Get())
+	},
+	0x98: func(c *CPU) {
+		c.SBC8(c.registers.B.Get())
+	},
+	0x99: func(c *CPU) {
+		c.SBC8(c.registers.C.Get())
+	},
+	0x9A: func(c *CPU) {
+		c.SBC8(c.registers.D.Get())
+	},
+	0x9B: func(c *CPU) {
+		c.SBC8(c.registers.E.Get())
+	},
+	0x9C: func(c *CPU) {
+		c.SBC8(c.registers.H.Get())
+	},
+	0x9D: func(c *CPU) {
+		c.SBC8(c.registers.L.Get())
+	},
+	0x9E: func(c *CPU) {
+		c.SBC8(c.read(c.registers.HL.Get()))
+	},
+	0xDE: func(c *CPU) {
+		c.SBC8(c.FetchNextByte())
+	},
+	0xA7: func(c *CPU) {
+		c.AND8(c.registers.A.Get())
+	},
+	0xA0: func(c *CPU) {
+		c.AND8(c.registers.B.Get())
+	},
+	0xA1: func(c *CPU) {
+		c.AND8(c.registers.C.Get())
+	},
+	0xA2: func(c *CPU) {
+		c.AND8(c.registers.D.Get())
+	},
+	0xA3: func(c *CPU) {
+		c.AND8(c.registers.E.Get())
+	},
+	0xA4: func(c *CPU) {
+		c.AND8(c.registers.H.Get())
+	},
+	0xA5: func(c *CPU) {
+		c.AND8(c.registers.L.Get())
+	},
+	0xA6: func(c *CPU) {
+		c.AND8(c.read(c.registers.HL.Get()))
+	},
+	0xE6: func(c *CPU) {
+		c.AND8(c.FetchNextByte())
+	},
+	0xB7: func(c *CPU) {
+		c.OR8(c.registers.A.Get())
+	},
+	0xB0: func(c *CPU) {
+		c.OR8(c.registers.B.Get())
+	},
+	0xB1: func(c *CPU) {
+		c.OR8(c.registers.C.Get())
+	},
+	0xB2: func(c *CPU) {
+		c.OR8(c.registers.D.Get())
+	},
+	0xB3: func(c *CPU) {
+		c.OR8(c.registers.E.Get())
+	},
+	0xB4: func(c *CPU) {
+		c.OR8(c.registers.H.Get())
+	},
+	0xB5: func(c *CPU) {
+		c.OR8(c.registers.L.Get())
+	},
+	0xB6: func(c *CPU) {
+		c.OR8(c.read(c.registers.HL.Get()))
+	},
+	0xF6: func(c *CPU) {
+		c.OR8(c.FetchNextByte())
+	},
+	0xAF: func(c *CPU) {
+		c.XOR8(c.registers.A.Get())
+	},
+	0xA8: func(c *CPU) {
+		c.XOR8(c.registers.B.Get())
+	},
+	0xA9: func(c *CPU) {
+		c.XOR8(c.registers.C.Get())
+	},
+	0xAA: func(c *CPU) {
+		c.XOR8(c.registers.D.Get())
+	},
+	0xAB: func(c *CPU) {
+		c.XOR8(c.registers.E.Get())
+	},
+	0xAC: func(c *CPU) {
+		c.XOR8(c.registers.H.Get())
+	},
+	0xAD: func(c *CPU) {
+		c.XOR8(c.registers.L.Get())
+	},
+	0xAE: func(c *CPU) {
+		c.XOR8(c.read(c.registers.HL.Get()))
+	},
+	0xEE: func(c *CPU) {
+		c.XOR8(c.FetchNextByte())
+	},
+	0xBF: func(c *CPU) {
+		c.CP8(c.registers.A.Get())
+	},
+	0xB8: func(c *CPU) {
+		c.CP8(c.registers.B.Get())
+	},
+	0xB9: func(c *CPU) {
+		c.CP8(c.registers.C.Get())
+	},
+	0xBA: func(c *CPU) {
+		c.CP8(c.registers.D.Get())
+	},
+	0xBB: func(c *CPU) {
+		c.CP8(c.registers.E.Get())
+	},
+	0xBC: func(c *CPU) {
+		c.CP8(c.registers.H.Get())
+	},
+	0xBD: func(c *CPU) {
+		c.CP8(c.registers.L.Get())
+	},
+	0xBE: func(c *CPU) {
+		c.CP8(c.read(c.registers.HL.Get()))
+	},
+	0xFE: func(c *CPU) {
+		c.CP8(c.FetchNextByte())
+	},
+	0x3C: func(c *CPU) {
+		c.INCr8(&c.registers.A)
+	},
+	0x04: func(c *CPU) {
+		c.INCr8(&c.registers.B)
+	},
+	0x0C: func(c *CPU) {
+		c.INCr8(&c.registers.C)
+	},
+	0x14: func(c *CPU) {
+		c.INCr8(&c.registers.D)
+	},
+	0x1C: func(c *CPU) {
+		c.INCr8(&c.registers.E)
+	},
+	0x24: func(c *CPU) {
+		c.INCr8(&c.registers.H)
+	},
+	0x2C: func(c *CPU) {
+		c.INCr8(&c.registers.L)
+	},
+	0x34: func(c *CPU) {
+		c.INCm8(c.registers.HL.Get())
+	},
+	0x3D: func(c *CPU) {
+		c.DECr8(&c.registers.A)
+	},
+	0x05: func(c *CPU) {
+		c.DECr8(&c.registers.B)
+	},
+	0x0D: func(c *CPU) {
+		c.DECr8(&c.registers.C)
+	},
+	0x15: func(c *CPU) {
+		c.DECr8(&c.registers.D)
+	},
+	0x1D: func(c *CPU) {
+		c.DECr8(&c.registers.E)
+	},
+	0x25: func(c *CPU) {
+		c.DECr8(&c.registers.H)
+	},
+	0x2D: func(c *CPU) {
+		c.DECr8(&c.registers.L)
+	},
+	0x35: func(c *CPU) {
+		c.DECm8(c.registers.HL.Get())
+	},
+	0x09: func(c *CPU) {
+		c.ADD16(c.registers.BC.Get())
+	},
+	0x19: func(c *CPU) {
+		c.ADD16(c.registers.DE.Get())
+	},
+	0x29: func(c *CPU) {
+		c.ADD16(c.registers.HL.Get())
+	},
+	0x39: func(c *CPU) {
+		c.ADD16(c.registers.SP.Get())
+	},
+	0xE8: func(c *CPU) {
+		c.LDr16(&c.registers.SP, c.addSP(c.FetchNextByte()))
+	},
+	0x03: func(c *CPU) {
+		c.INC16(&c.registers.BC)
+	},
+	0x13: func(c *CPU) {
+		c.INC16(&c.registers.DE)
+	},
+	0x23: func(c *CPU) {
+		c.INC16(&c.registers.HL)
+	},
+	0x33: func(c *CPU) {
+		c.INC16(&c.registers.SP)
+	},
+	0x0B: func(c *CPU) {
+		c.DEC16(&c.registers.BC)
+	},
+	0x1B: func(c *CPU) {
+		c.DEC16(&c.registers.DE)
+	},
+	0x2B: func(c *CPU) {
+		c.DEC16(&c.registers.HL)
+	},
+	0x3B: func(c *CPU) {
+		c.DEC16(&c.registers.SP)
+	},
 	// ALU end
-	case 0x27:
-		return Instruction{exec: c.DAA, cycles: 4}
-	case 0x2F:
-		return Instruction{exec: c.CPL, cycles: 4}
-	case 0x3F:
-		return Instruction{exec: c.CCF, cycles: 4}
-	case 0x37:
-		return Instruction{exec: c.SCF, cycles: 4}
-	case 0x00:
-		return Instruction{exec: c.NOP, cycles: 4}
-	case 0x76:
-		return Instruction{exec: c.HALT, cycles: 4}
-	case 0x10:
-		return Instruction{exec: c.STOP, cycles: 4}
-	case 0xF3:
-		return Instruction{exec: c.DI, cycles: 4}
-	case 0xFB:
-		return Instruction{exec: c.EI, cycles: 4}
-	case 0x07:
-		return Instruction{exec: func() {
-			c.RLCr8(c.registers.A)
-		}, cycles: 4}
-	case 0x17:
-		return Instruction{exec: func() {
-			c.RLr8(c.registers.A)
-		}, cycles: 4}
-	case 0x0F:
-		return Instruction{exec: func() {
-			c.RRCr8(c.registers.A)
-		}, cycles: 4}
-	case 0x1F:
-		return Instruction{exec: func() {
-			c.RRr8(c.registers.A)
-		}, cycles: 4}
-	case 0xC3:
-		return Instruction{exec: func() {
-			c.JP(c.FetchNextWord())
-		}, cycles: 12}
-	case 0xC2:
-		return Instruction{exec: func() {
-			c.JPc(registers.N, 0x0, c.FetchNextWord())
-		}, cycles: 12}
-	case 0xCA:
-		return Instruction{exec: func() {
-			c.JPc(registers.Z, 0x1, c.FetchNextWord())
-		}, cycles: 12}
-	case 0xD2:
-		return Instruction{exec: func() {
-			c.JPc(registers.C, 0x0, c.FetchNextWord())
-		}, cycles: 12}
-	case 0xDA:
-		return Instruction{exec: func() {
-			c.JPc(registers.C, 0x1, c.FetchNextWord())
-		}, cycles: 12}
-	case 0xE9:
-		return Instruction{exec: func() {
-			c.JP(c.registers.HL.Get())
-		}, cycles: 4}
-	case 0x18:
-		return Instruction{exec: func() {
-			c.JR(c.FetchNextByte())
-		}, cycles: 8}
-	case 0x20:
-		return Instruction{exec: func() {
-			c.JRc(registers.N, 0x0, c.FetchNextByte())
-		}, cycles: 8}
-	case 0x28:
-		return Instruction{exec: func() {
-			c.JRc(registers.Z, 0x1, c.FetchNextByte())
-		}, cycles: 8}
-	case 0x30:
-		return Instruction{exec: func() {
-			c.JRc(registers.C, 0x0, c.FetchNextByte())
-		}, cycles: 8}
-	case 0x38:
-		return Instruction{exec: func() {
-			c.JRc(registers.C, 0x1, c.FetchNextByte())
-		}, cycles: 8}
-	case 0xCD:
-		return Instruction{exec: func() {
-			c.CALL(c.FetchNextWord())
-		}, cycles: 12}
-	case 0xC4:
-		return Instruction{exec: func() {
-			c.CALLc(registers.N, 0x0, c.FetchNextWord())
-		}, cycles: 12}
-	case 0xCC:
-		return Instruction{exec: func() {
-			c.CALLc(registers.Z, 0x1, c.FetchNextWord())
-		}, cycles: 12}
-	case 0xD4:
-		return Instruction{exec: func() {
-			c.CALLc(registers.C, 0x0, c.FetchNextWord())
-		}, cycles: 12}
-	case 0xDC:
-		return Instruction{exec: func() {
-			c.CALLc(registers.C, 0x1, c.FetchNextWord())
-		}, cycles: 12}
-	case 0xC7:
-		return Instruction{exec: func() {
-			c.RST(0x0)
-		}, cycles: 16}
-	case 0xCF:
-		return Instruction{exec: func() {
-			c.RST(0x8)
-		}, cycles: 16}
-	case 0xD7:
-		return Instruction{exec: func() {
-			c.RST(0x10)
-		}, cycles: 16}
-	case 0xDF:
-		return Instruction{exec: func() {
-			c.RST(0x18)
-		}, cycles: 16}
-	case 0xE7:
-		return Instruction{exec: func() {
-			c.RST(0x20)
-		}, cycles: 16}
-	case 0xEF:
-		return Instruction{exec: func() {
-			c.RST(0x28)
-		}, cycles: 16}
-	case 0xF7:
-		return Instruction{exec: func() {
-			c.RST(0x30)
-		}, cycles: 16}
-	case 0xFF:
-		return Instruction{exec: func() {
-			c.RST(0x38)
-		}, cycles: 16}
-	case 0xC9:
-		return Instruction{exec: func() {
-			c.RET()
-		}, cycles: 8}
-	case 0xC0:
-		return Instruction{exec: func() {
-			c.RETc(registers.N, 0x0)
-		}, cycles: 8}
-	case 0xC8:
-		return Instruction{exec: func() {
-			c.RETc(registers.Z, 0x1)
-		}, cycles: 8}
-	case 0xD0:
-		return Instruction{exec: func() {
-			c.RETc(registers.C, 0x0)
-		}, cycles: 8}
-	case 0xD8:
-		return Instruction{exec: func() {
-			c.RETc(registers.C, 0x1)
-		}, cycles: 8}
-	case 0xD9:
-		return Instruction{exec: c.RETI, cycles: 8}
-	case 0xCB:
-		return Instruction{exec: func() {
-			opcode := c.FetchNextByte()
-			inst := c.DecodeCB(opcode)
-			inst.exec()
-		}, cycles: 4}
-	default:
-		log.Fatalf("OpCode 0x%02X not implemented", opcode)
-		return Instruction{}
-	}
-}
-
-func (c *CPU) DecodeCB(opcode types.Byte) Instruction {
-	switch opcode {
-	case 0x37:
-		return Instruction{exec: func() {
-			c.SWAPr8(c.registers.A)
-		}, cycles: 8}
-	case 0x30:
-		return Instruction{exec: func() {
-			c.SWAPr8(c.registers.B)
-		}, cycles: 8}
-	case 0x31:
-		return Instruction{exec: func() {
-			c.SWAPr8(c.registers.C)
-		}, cycles: 8}
-	case 0x32:
-		return Instruction{exec: func() {
-			c.SWAPr8(c.registers.D)
-		}, cycles: 8}
-	case 0x33:
-		return Instruction{exec: func() {
-			c.SWAPr8(c.registers.E)
-		}, cycles: 8}
-	case 0x34:
-		return Instruction{exec: func() {
-			c.SWAPr8(c.registers.H)
-		}, cycles: 8}
-	case 0x35:
-		return Instruction{exec: func() {
-			c.SWAPr8(c.registers.L)
-		}, cycles: 8}
-	case 0x36:
-		return Instruction{exec: func() {
-			c.SWAPm8(c.registers.HL.Get())
-		}, cycles: 16}
-	case 0x07:
-		return Instruction{exec: func() {
-			c.RLCr8(c.registers.A)
-		}, cycles: 8}
-	case 0x00:
-		return Instruction{exec: func() {
-			c.RLCr8(c.registers.B)
-		}, cycles: 8}
-	case 0x01:
-		return Instruction{exec: func() {
-			c.RLCr8(c.registers.C)
-		}, cycles: 8}
-	case 0x02:
-		return Instruction{exec: func() {
-			c.RLCr8(c.registers.D)
-		}, cycles: 8}
-	case 0x03:
-		return Instruction{exec: func() {
-			c.RLCr8(c.registers.E)
-		}, cycles: 8}
-	case 0x04:
-		return Instruction{exec: func() {
-			c.RLCr8(c.registers.H)
-		}, cycles: 8}
-	case 0x05:
-		return Instruction{exec: func() {
-			c.RLCr8(c.registers.L)
-		}, cycles: 8}
-	case 0x06:
-		return Instruction{exec: func() {
-			c.RLCm8(c.registers.HL.Get())
-		}, cycles: 16}
-	case 0x17:
-		return Instruction{exec: func() {
-			c.RLr8(c.registers.A)
-		}, cycles: 8}
-	case 0x10:
-		return Instruction{exec: func() {
-			c.RLr8(c.registers.B)
-		}, cycles: 8}
-	case 0x11:
-		return Instruction{exec: func() {
-			c.RLr8(c.registers.C)
-		}, cycles: 8}
-	case 0x12:
-		return Instruction{exec: func() {
-			c.RLr8(c.registers.D)
-		}, cycles: 8}
-	case 0x13:
-		return Instruction{exec: func() {
-			c.RLr8(c.registers.E)
-		}, cycles: 8}
-	case 0x14:
-		return Instruction{exec: func() {
-			c.RLr8(c.registers.H)
-		}, cycles: 8}
-	case 0x15:
-		return Instruction{exec: func() {
-			c.RLr8(c.registers.L)
-		}, cycles: 8}
-	case 0x16:
-		return Instruction{exec: func() {
-			c.RLm8(c.registers.HL.Get())
-		}, cycles: 16}
-	case 0x0F:
-		return Instruction{exec: func() {
-			c.RRCr8(c.registers.A)
-		}, cycles: 8}
-	case 0x08:
-		return Instruction{exec: func() {
-			c.RRCr8(c.registers.B)
-		}, cycles: 8}
-	case 0x09:
-		return Instruction{exec: func() {
-			c.RRCr8(c.registers.C)
-		}, cycles: 8}
-	case 0x0A:
-		return Instruction{exec: func() {
-			c.RRCr8(c.registers.D)
-		}, cycles: 8}
-	case 0x0B:
-		return Instruction{exec: func() {
-			c.RRCr8(c.registers.E)
-		}, cycles: 8}
-	case 0x0C:
-		return Instruction{exec: func() {
-			c.RRCr8(c.registers.H)
-		}, cycles: 8}
-	case 0x0D:
-		return Instruction{exec: func() {
-			c.RRCr8(c.registers.L)
-		}, cycles: 8}
-	case 0x0E:
-		return Instruction{exec: func() {
-			c.RRCm8(c.registers.HL.Get())
-		}, cycles: 16}
-	case 0x1F:
-		return Instruction{exec: func() {
-			c.RRr8(c.registers.A)
-		}, cycles: 8}
-	case 0x18:
-		return Instruction{exec: func() {
-			c.RRr8(c.registers.B)
-		}, cycles: 8}
-	case 0x19:
-		return Instruction{exec: func() {
-			c.RRr8(c.registers.C)
-		}, cycles: 8}
-	case 0x1A:
-		return Instruction{exec: func() {
-			c.RRr8(c.registers.D)
-		}, cycles: 8}
-	case 0x1B:
-		return Instruction{exec: func() {
-			c.RRr8(c.registers.E)
-		}, cycles: 8}
-	case 0x1C:
-		return Instruction{exec: func() {
-			c.RRr8(c.registers.H)
-		}, cycles: 8}
-	case 0x1D:
-		return Instruction{exec: func() {
-			c.RRr8(c.registers.L)
-		}, cycles: 8}
-	case 0x1E:
-		return Instruction{exec: func() {
-			c.RRm8(c.registers.HL.Get())
-		}, cycles: 16}
-	case 0x27:
-		return Instruction{exec: func() {
-			c.SLAr8(c.registers.A)
-		}, cycles: 8}
-	case 0x20:
-		return Instruction{exec: func() {
-			c.SLAr8(c.registers.B)
-		}, cycles: 8}
-	case 0x21:
-		return Instruction{exec: func() {
-			c.SLAr8(c.registers.C)
-		}, cycles: 8}
-	case 0x22:
-		return Instruction{exec: func() {
-			c.SLAr8(c.registers.D)
-		}, cycles: 8}
-	case 0x23:
-		return Instruction{exec: func() {
-			c.SLAr8(c.registers.E)
-		}, cycles: 8}
-	case 0x24:
-		return Instruction{exec: func() {
-			c.SLAr8(c.registers.H)
-		}, cycles: 8}
-	case 0x25:
-		return Instruction{exec: func() {
-			c.SLAr8(c.registers.L)
-		}, cycles: 8}
-	case 0x26:
-		return Instruction{exec: func() {
-			c.SLAm8(c.registers.HL.Get())
-		}, cycles: 16}
-	case 0x2F:
-		return Instruction{exec: func() {
-			c.SRAr8(c.registers.A)
-		}, cycles: 8}
-	case 0x28:
-		return Instruction{exec: func() {
-			c.SRAr8(c.registers.B)
-		}, cycles: 8}
-	case 0x29:
-		return Instruction{exec: func() {
-			c.SRAr8(c.registers.C)
-		}, cycles: 8}
-	case 0x2A:
-		return Instruction{exec: func() {
-			c.SRAr8(c.registers.D)
-		}, cycles: 8}
-	case 0x2B:
-		return Instruction{exec: func() {
-			c.SRAr8(c.registers.E)
-		}, cycles: 8}
-	case 0x2C:
-		return Instruction{exec: func() {
-			c.SRAr8(c.registers.H)
-		}, cycles: 8}
-	case 0x2D:
-		return Instruction{exec: func() {
-			c.SRAr8(c.registers.L)
-		}, cycles: 8}
-	case 0x2E:
-		return Instruction{exec: func() {
-			c.SRAm8(c.registers.HL.Get())
-		}, cycles: 16}
-	case 0x3F:
-		return Instruction{exec: func() {
-			c.SRLr8(c.registers.A)
-		}, cycles: 8}
-	case 0x38:
-		return Instruction{exec: func() {
-			c.SRLr8(c.registers.B)
-		}, cycles: 8}
-	case 0x39:
-		return Instruction{exec: func() {
-			c.SRLr8(c.registers.C)
-		}, cycles: 8}
-	case 0x3A:
-		return Instruction{exec: func() {
-			c.SRLr8(c.registers.D)
-		}, cycles: 8}
-	case 0x3B:
-		return Instruction{exec: func() {
-			c.SRLr8(c.registers.E)
-		}, cycles: 8}
-	case 0x3C:
-		return Instruction{exec: func() {
-			c.SRLr8(c.registers.H)
-		}, cycles: 8}
-	case 0x3D:
-		return Instruction{exec: func() {
-			c.SRLr8(c.registers.L)
-		}, cycles: 8}
-	case 0x3E:
-		return Instruction{exec: func() {
-			c.SRLm8(c.registers.HL.Get())
-		}, cycles: 16}
-	case 0x47:
-		return Instruction{exec: func() {
-			c.BIT(0, c.registers.A.Get())
-		}, cycles: 8}
-	case 0x40:
-		return Instruction{exec: func() {
-			c.BIT(0, c.registers.B.Get())
-		}, cycles: 8}
-	case 0x41:
-		return Instruction{exec: func() {
-			c.BIT(0, c.registers.C.Get())
-		}, cycles: 8}
-	case 0x42:
-		return Instruction{exec: func() {
-			c.BIT(0, c.registers.D.Get())
-		}, cycles: 8}
-	case 0x43:
-		return Instruction{exec: func() {
-			c.BIT(0, c.registers.E.Get())
-		}, cycles: 8}
-	case 0x44:
-		return Instruction{exec: func() {
-			c.BIT(0, c.registers.H.Get())
-		}, cycles: 8}
-	case 0x45:
-		return Instruction{exec: func() {
-			c.BIT(0, c.registers.L.Get())
-		}, cycles: 8}
-	case 0x46:
-		return Instruction{exec: func() {
-			c.BIT(0, c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 16}
-	case 0x48:
-		return Instruction{exec: func() {
-			c.BIT(1, c.registers.A.Get())
-		}, cycles: 8}
-	case 0x49:
-		return Instruction{exec: func() {
-			c.BIT(1, c.registers.B.Get())
-		}, cycles: 8}
-	case 0x4A:
-		return Instruction{exec: func() {
-			c.BIT(1, c.registers.C.Get())
-		}, cycles: 8}
-	case 0x4B:
-		return Instruction{exec: func() {
-			c.BIT(1, c.registers.D.Get())
-		}, cycles: 8}
-	case 0x4C:
-		return Instruction{exec: func() {
-			c.BIT(1, c.registers.E.Get())
-		}, cycles: 8}
-	case 0x4D:
-		return Instruction{exec: func() {
-			c.BIT(1, c.registers.H.Get())
-		}, cycles: 8}
-	case 0x4E:
-		return Instruction{exec: func() {
-			c.BIT(1, c.registers.L.Get())
-		}, cycles: 8}
-	case 0x4F:
-		return Instruction{exec: func() {
-			c.BIT(1, c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 16}
-	case 0x57:
-		return Instruction{exec: func() {
-			c.BIT(2, c.registers.A.Get())
-		}, cycles: 8}
-	case 0x50:
-		return Instruction{exec: func() {
-			c.BIT(2, c.registers.B.Get())
-		}, cycles: 8}
-	case 0x51:
-		return Instruction{exec: func() {
-			c.BIT(2, c.registers.C.Get())
-		}, cycles: 8}
-	case 0x52:
-		return Instruction{exec: func() {
-			c.BIT(2, c.registers.D.Get())
-		}, cycles: 8}
-	case 0x53:
-		return Instruction{exec: func() {
-			c.BIT(2, c.registers.E.Get())
-		}, cycles: 8}
-	case 0x54:
-		return Instruction{exec: func() {
-			c.BIT(2, c.registers.H.Get())
-		}, cycles: 8}
-	case 0x55:
-		return Instruction{exec: func() {
-			c.BIT(2, c.registers.L.Get())
-		}, cycles: 8}
-	case 0x56:
-		return Instruction{exec: func() {
-			c.BIT(2, c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 16}
-	case 0x58:
-		return Instruction{exec: func() {
-			c.BIT(3, c.registers.A.Get())
-		}, cycles: 8}
-	case 0x59:
-		return Instruction{exec: func() {
-			c.BIT(3, c.registers.B.Get())
-		}, cycles: 8}
-	case 0x5A:
-		return Instruction{exec: func() {
-			c.BIT(3, c.registers.C.Get())
-		}, cycles: 8}
-	case 0x5B:
-		return Instruction{exec: func() {
-			c.BIT(3, c.registers.D.Get())
-		}, cycles: 8}
-	case 0x5C:
-		return Instruction{exec: func() {
-			c.BIT(3, c.registers.E.Get())
-		}, cycles: 8}
-	case 0x5D:
-		return Instruction{exec: func() {
-			c.BIT(3, c.registers.H.Get())
-		}, cycles: 8}
-	case 0x5E:
-		return Instruction{exec: func() {
-			c.BIT(3, c.registers.L.Get())
-		}, cycles: 8}
-	case 0x5F:
-		return Instruction{exec: func() {
-			c.BIT(3, c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 16}
-	case 0x67:
-		return Instruction{exec: func() {
-			c.BIT(4, c.registers.A.Get())
-		}, cycles: 8}
-	case 0x60:
-		return Instruction{exec: func() {
-			c.BIT(4, c.registers.B.Get())
-		}, cycles: 8}
-	case 0x61:
-		return Instruction{exec: func() {
-			c.BIT(4, c.registers.C.Get())
-		}, cycles: 8}
-	case 0x62:
-		return Instruction{exec: func() {
-			c.BIT(4, c.registers.D.Get())
-		}, cycles: 8}
-	case 0x63:
-		return Instruction{exec: func() {
-			c.BIT(4, c.registers.E.Get())
-		}, cycles: 8}
-	case 0x64:
-		return Instruction{exec: func() {
-			c.BIT(4, c.registers.H.Get())
-		}, cycles: 8}
-	case 0x65:
-		return Instruction{exec: func() {
-			c.BIT(4, c.registers.L.Get())
-		}, cycles: 8}
-	case 0x66:
-		return Instruction{exec: func() {
-			c.BIT(4, c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 16}
-	case 0x68:
-		return Instruction{exec: func() {
-			c.BIT(5, c.registers.A.Get())
-		}, cycles: 8}
-	case 0x69:
-		return Instruction{exec: func() {
-			c.BIT(5, c.registers.B.Get())
-		}, cycles: 8}
-	case 0x6A:
-		return Instruction{exec: func() {
-			c.BIT(5, c.registers.C.Get())
-		}, cycles: 8}
-	case 0x6B:
-		return Instruction{exec: func() {
-			c.BIT(5, c.registers.D.Get())
-		}, cycles: 8}
-	case 0x6C:
-		return Instruction{exec: func() {
-			c.BIT(5, c.registers.E.Get())
-		}, cycles: 8}
-	case 0x6D:
-		return Instruction{exec: func() {
-			c.BIT(5, c.registers.H.Get())
-		}, cycles: 8}
-	case 0x6E:
-		return Instruction{exec: func() {
-			c.BIT(5, c.registers.L.Get())
-		}, cycles: 8}
-	case 0x6F:
-		return Instruction{exec: func() {
-			c.BIT(5, c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 16}
-	case 0x77:
-		return Instruction{exec: func() {
-			c.BIT(6, c.registers.A.Get())
-		}, cycles: 8}
-	case 0x70:
-		return Instruction{exec: func() {
-			c.BIT(6, c.registers.B.Get())
-		}, cycles: 8}
-	case 0x71:
-		return Instruction{exec: func() {
-			c.BIT(6, c.registers.C.Get())
-		}, cycles: 8}
-	case 0x72:
-		return Instruction{exec: func() {
-			c.BIT(6, c.registers.D.Get())
-		}, cycles: 8}
-	case 0x73:
-		return Instruction{exec: func() {
-			c.BIT(6, c.registers.E.Get())
-		}, cycles: 8}
-	case 0x74:
-		return Instruction{exec: func() {
-			c.BIT(6, c.registers.H.Get())
-		}, cycles: 8}
-	case 0x75:
-		return Instruction{exec: func() {
-			c.BIT(6, c.registers.L.Get())
-		}, cycles: 8}
-	case 0x76:
-		return Instruction{exec: func() {
-			c.BIT(6, c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 16}
-	case 0x78:
-		return Instruction{exec: func() {
-			c.BIT(7, c.registers.A.Get())
-		}, cycles: 8}
-	case 0x79:
-		return Instruction{exec: func() {
-			c.BIT(7, c.registers.B.Get())
-		}, cycles: 8}
-	case 0x7A:
-		return Instruction{exec: func() {
-			c.BIT(7, c.registers.C.Get())
-		}, cycles: 8}
-	case 0x7B:
-		return Instruction{exec: func() {
-			c.BIT(7, c.registers.D.Get())
-		}, cycles: 8}
-	case 0x7C:
-		return Instruction{exec: func() {
-			c.BIT(7, c.registers.E.Get())
-		}, cycles: 8}
-	case 0x7D:
-		return Instruction{exec: func() {
-			c.BIT(7, c.registers.H.Get())
-		}, cycles: 8}
-	case 0x7E:
-		return Instruction{exec: func() {
-			c.BIT(7, c.registers.L.Get())
-		}, cycles: 8}
-	case 0x7F:
-		return Instruction{exec: func() {
-			c.BIT(7, c.mmu.Get(c.registers.HL.Get()))
-		}, cycles: 16}
-	case 0xC7:
-		return Instruction{exec: func() {
-			c.SETr8(0, c.registers.A)
-		}, cycles: 8}
-	case 0xC0:
-		return Instruction{exec: func() {
-			c.SETr8(0, c.registers.B)
-		}, cycles: 8}
-	case 0xC1:
-		return Instruction{exec: func() {
-			c.SETr8(0, c.registers.C)
-		}, cycles: 8}
-	case 0xC2:
-		return Instruction{exec: func() {
-			c.SETr8(0, c.registers.D)
-		}, cycles: 8}
-	case 0xC3:
-		return Instruction{exec: func() {
-			c.SETr8(0, c.registers.E)
-		}, cycles: 8}
-	case 0xC4:
-		return Instruction{exec: func() {
-			c.SETr8(0, c.registers.H)
-		}, cycles: 8}
-	case 0xC5:
-		return Instruction{exec: func() {
-			c.SETr8(0, c.registers.L)
-		}, cycles: 8}
-	case 0xC6:
-		return Instruction{exec: func() {
-			c.SETm8(0, c.registers.HL.Get())
-		}, cycles: 16}
-	case 0xC8:
-		return Instruction{exec: func() {
-			c.SETr8(1, c.registers.A)
-		}, cycles: 8}
-	case 0xC9:
-		return Instruction{exec: func() {
-			c.SETr8(1, c.registers.B)
-		}, cycles: 8}
-	case 0xCA:
-		return Instruction{exec: func() {
-			c.SETr8(1, c.registers.C)
-		}, cycles: 8}
-	case 0xCB:
-		return Instruction{exec: func() {
-			c.SETr8(1, c.registers.D)
-		}, cycles: 8}
-	case 0xCC:
-		return Instruction{exec: func() {
-			c.SETr8(1, c.registers.E)
-		}, cycles: 8}
-	case 0xCD:
-		return Instruction{exec: func() {
-			c.SETr8(1, c.registers.H)
-		}, cycles: 8}
-	case 0xCE:
-		return Instruction{exec: func() {
-			c.SETr8(1, c.registers.L)
-		}, cycles: 8}
-	case 0xCF:
-		return Instruction{exec: func() {
-			c.SETm8(1, c.registers.HL.Get())
-		}, cycles: 16}
-	case 0xD7:
-		return Instruction{exec: func() {
-			c.SETr8(2, c.registers.A)
-		}, cycles: 8}
-	case 0xD0:
-		return Instruction{exec: func() {
-			c.SETr8(2, c.registers.B)
-		}, cycles: 8}
-	case 0xD1:
-		return Instruction{exec: func() {
-			c.SETr8(2, c.registers.C)
-		}, cycles: 8}
-	case 0xD2:
-		return Instruction{exec: func() {
-			c.SETr8(2, c.registers.D)
-		}, cycles: 8}
-	case 0xD3:
-		return Instruction{exec: func() {
-			c.SETr8(2, c.registers.E)
-		}, cycles: 8}
-	case 0xD4:
-		return Instruction{exec: func() {
-			c.SETr8(2, c.registers.H)
-		}, cycles: 8}
-	case 0xD5:
-		return Instruction{exec: func() {
-			c.SETr8(2, c.registers.L)
-		}, cycles: 8}
-	case 0xD6:
-		return Instruction{exec: func() {
-			c.SETm8(2, c.registers.HL.Get())
-		}, cycles: 16}
-	case 0xD8:
-		return Instruction{exec: func() {
-			c.SETr8(3, c.registers.A)
-		}, cycles: 8}
-	case 0xD9:
-		return Instruction{exec: func() {
-			c.SETr8(3, c.registers.B)
-		}, cycles: 8}
-	case 0xDA:
-		return Instruction{exec: func() {
-			c.SETr8(3, c.registers.C)
-		}, cycles: 8}
-	case 0xDB:
-		return Instruction{exec: func() {
-			c.SETr8(3, c.registers.D)
-		}, cycles: 8}
-	case 0xDC:
-		return Instruction{exec: func() {
-			c.SETr8(3, c.registers.E)
-		}, cycles: 8}
-	case 0xDD:
-		return Instruction{exec: func() {
-			c.SETr8(3, c.registers.H)
-		}, cycles: 8}
-	case 0xDE:
-		return Instruction{exec: func() {
-			c.SETr8(3, c.registers.L)
-		}, cycles: 8}
-	case 0xDF:
-		return Instruction{exec: func() {
-			c.SETm8(3, c.registers.HL.Get())
-		}, cycles: 16}
-	case 0xE7:
-		return Instruction{exec: func() {
-			c.SETr8(4, c.registers.A)
-		}, cycles: 8}
-	case 0xE0:
-		return Instruction{exec: func() {
-			c.SETr8(4, c.registers.B)
-		}, cycles: 8}
-	case 0xE1:
-		return Instruction{exec: func() {
-			c.SETr8(4, c.registers.C)
-		}, cycles: 8}
-	case 0xE2:
-		return Instruction{exec: func() {
-			c.SETr8(4, c.registers.D)
-		}, cycles: 8}
-	case 0xE3:
-		return Instruction{exec: func() {
-			c.SETr8(4, c.registers.E)
-		}, cycles: 8}
-	case 0xE4:
-		return Instruction{exec: func() {
-			c.SETr8(4, c.registers.H)
-		}, cycles: 8}
-	case 0xE5:
-		return Instruction{exec: func() {
-			c.SETr8(4, c.registers.L)
-		}, cycles: 8}
-	case 0xE6:
-		return Instruction{exec: func() {
-			c.SETm8(4, c.registers.HL.Get())
-		}, cycles: 16}
-	case 0xE8:
-		return Instruction{exec: func() {
-			c.SETr8(5, c.registers.A)
-		}, cycles: 8}
-	case 0xE9:
-		return Instruction{exec: func() {
-			c.SETr8(5, c.registers.B)
-		}, cycles: 8}
-	case 0xEA:
-		return Instruction{exec: func() {
-			c.SETr8(5, c.registers.C)
-		}, cycles: 8}
-	case 0xEB:
-		return Instruction{exec: func() {
-			c.SETr8(5, c.registers.D)
-		}, cycles: 8}
-	case 0xEC:
-		return Instruction{exec: func() {
-			c.SETr8(5, c.registers.E)
-		}, cycles: 8}
-	case 0xED:
-		return Instruction{exec: func() {
-			c.SETr8(5, c.registers.H)
-		}, cycles: 8}
-	case 0xEE:
-		return Instruction{exec: func() {
-			c.SETr8(5, c.registers.L)
-		}, cycles: 8}
-	case 0xEF:
-		return Instruction{exec: func() {
-			c.SETm8(5, c.registers.HL.Get())
-		}, cycles: 16}
-	case 0xF7:
-		return Instruction{exec: func() {
-			c.SETr8(6, c.registers.A)
-		}, cycles: 8}
-	case 0xF0:
-		return Instruction{exec: func() {
-			c.SETr8(6, c.registers.B)
-		}, cycles: 8}
-	case 0xF1:
-		return Instruction{exec: func() {
-			c.SETr8(6, c.registers.C)
-		}, cycles: 8}
-	case 0xF2:
-		return Instruction{exec: func() {
-			c.SETr8(6, c.registers.D)
-		}, cycles: 8}
-	case 0xF3:
-		return Instruction{exec: func() {
-			c.SETr8(6, c.registers.E)
-		}, cycles: 8}
-	case 0xF4:
-		return Instruction{exec: func() {
-			c.SETr8(6, c.registers.H)
-		}, cycles: 8}
-	case 0xF5:
-		return Instruction{exec: func() {
-			c.SETr8(6, c.registers.L)
-		}, cycles: 8}
-	case 0xF6:
-		return Instruction{exec: func() {
-			c.SETm8(6, c.registers.HL.Get())
-		}, cycles: 16}
-	case 0xF8:
-		return Instruction{exec: func() {
-			c.SETr8(7, c.registers.A)
-		}, cycles: 8}
-	case 0xF9:
-		return Instruction{exec: func() {
-			c.SETr8(7, c.registers.B)
-		}, cycles: 8}
-	case 0xFA:
-		return Instruction{exec: func() {
-			c.SETr8(7, c.registers.C)
-		}, cycles: 8}
-	case 0xFB:
-		return Instruction{exec: func() {
-			c.SETr8(7, c.registers.D)
-		}, cycles: 8}
-	case 0xFC:
-		return Instruction{exec: func() {
-			c.SETr8(7, c.registers.E)
-		}, cycles: 8}
-	case 0xFD:
-		return Instruction{exec: func() {
-			c.SETr8(7, c.registers.H)
-		}, cycles: 8}
-	case 0xFE:
-		return Instruction{exec: func() {
-			c.SETr8(7, c.registers.L)
-		}, cycles: 8}
-	case 0xFF:
-		return Instruction{exec: func() {
-			c.SETm8(7, c.registers.HL.Get())
-		}, cycles: 16}
-	case 0x87:
-		return Instruction{exec: func() {
-			c.RESr8(0, c.registers.A)
-		}, cycles: 8}
-	case 0x80:
-		return Instruction{exec: func() {
-			c.RESr8(0, c.registers.B)
-		}, cycles: 8}
-	case 0x81:
-		return Instruction{exec: func() {
-			c.RESr8(0, c.registers.C)
-		}, cycles: 8}
-	case 0x82:
-		return Instruction{exec: func() {
-			c.RESr8(0, c.registers.D)
-		}, cycles: 8}
-	case 0x83:
-		return Instruction{exec: func() {
-			c.RESr8(0, c.registers.E)
-		}, cycles: 8}
-	case 0x84:
-		return Instruction{exec: func() {
-			c.RESr8(0, c.registers.H)
-		}, cycles: 8}
-	case 0x85:
-		return Instruction{exec: func() {
-			c.RESr8(0, c.registers.L)
-		}, cycles: 8}
-	case 0x86:
-		return Instruction{exec: func() {
-			c.RESm8(0, c.registers.HL.Get())
-		}, cycles: 16}
-	case 0x88:
-		return Instruction{exec: func() {
-			c.RESr8(1, c.registers.A)
-		}, cycles: 8}
-	case 0x89:
-		return Instruction{exec: func() {
-			c.RESr8(1, c.registers.B)
-		}, cycles: 8}
-	case 0x8A:
-		return Instruction{exec: func() {
-			c.RESr8(1, c.registers.C)
-		}, cycles: 8}
-	case 0x8B:
-		return Instruction{exec: func() {
-			c.RESr8(1, c.registers.D)
-		}, cycles: 8}
-	case 0x8C:
-		return Instruction{exec: func() {
-			c.RESr8(1, c.registers.E)
-		}, cycles: 8}
-	case 0x8D:
-		return Instruction{exec: func() {
-			c.RESr8(1, c.registers.H)
-		}, cycles: 8}
-	case 0x8E:
-		return Instruction{exec: func() {
-			c.RESr8(1, c.registers.L)
-		}, cycles: 8}
-	case 0x8F:
-		return Instruction{exec: func() {
-			c.RESm8(1, c.registers.HL.Get())
-		}, cycles: 16}
-	case 0x97:
-		return Instruction{exec: func() {
-			c.RESr8(2, c.registers.A)
-		}, cycles: 8}
-	case 0x90:
-		return Instruction{exec: func() {
-			c.RESr8(2, c.registers.B)
-		}, cycles: 8}
-	case 0x91:
-		return Instruction{exec: func() {
-			c.RESr8(2, c.registers.C)
-		}, cycles: 8}
-	case 0x92:
-		return Instruction{exec: func() {
-			c.RESr8(2, c.registers.D)
-		}, cycles: 8}
-	case 0x93:
-		return Instruction{exec: func() {
-			c.RESr8(2, c.registers.E)
-		}, cycles: 8}
-	case 0x94:
-		return Instruction{exec: func() {
-			c.RESr8(2, c.registers.H)
-		}, cycles: 8}
-	case 0x95:
-		return Instruction{exec: func() {
-			c.RESr8(2, c.registers.L)
-		}, cycles: 8}
-	case 0x96:
-		return Instruction{exec: func() {
-			c.RESm8(2, c.registers.HL.Get())
-		}, cycles: 16}
-	case 0x98:
-		return Instruction{exec: func() {
-			c.RESr8(3, c.registers.A)
-		}, cycles: 8}
-	case 0x99:
-		return Instruction{exec: func() {
-			c.RESr8(3, c.registers.B)
-		}, cycles: 8}
-	case 0x9A:
-		return Instruction{exec: func() {
-			c.RESr8(3, c.registers.C)
-		}, cycles: 8}
-	case 0x9B:
-		return Instruction{exec: func() {
-			c.RESr8(3, c.registers.D)
-		}, cycles: 8}
-	case 0x9C:
-		return Instruction{exec: func() {
-			c.RESr8(3, c.registers.E)
-		}, cycles: 8}
-	case 0x9D:
-		return Instruction{exec: func() {
-			c.RESr8(3, c.registers.H)
-		}, cycles: 8}
-	case 0x9E:
-		return Instruction{exec: func() {
-			c.RESr8(3, c.registers.L)
-		}, cycles: 8}
-	case 0x9F:
-		return Instruction{exec: func() {
-			c.RESm8(3, c.registers.HL.Get())
-		}, cycles: 16}
-	case 0xA7:
-		return Instruction{exec: func() {
-			c.RESr8(4, c.registers.A)
-		}, cycles: 8}
-	case 0xA0:
-		return Instruction{exec: func() {
-			c.RESr8(4, c.registers.B)
-		}, cycles: 8}
-	case 0xA1:
-		return Instruction{exec: func() {
-			c.RESr8(4, c.registers.C)
-		}, cycles: 8}
-	case 0xA2:
-		return Instruction{exec: func() {
-			c.RESr8(4, c.registers.D)
-		}, cycles: 8}
-	case 0xA3:
-		return Instruction{exec: func() {
-			c.RESr8(4, c.registers.E)
-		}, cycles: 8}
-	case 0xA4:
-		return Instruction{exec: func() {
-			c.RESr8(4, c.registers.H)
-		}, cycles: 8}
-	case 0xA5:
-		return Instruction{exec: func() {
-			c.RESr8(4, c.registers.L)
-		}, cycles: 8}
-	case 0xA6:
-		return Instruction{exec: func() {
-			c.RESm8(4, c.registers.HL.Get())
-		}, cycles: 16}
-	case 0xA8:
-		return Instruction{exec: func() {
-			c.RESr8(5, c.registers.A)
-		}, cycles: 8}
-	case 0xA9:
-		return Instruction{exec: func() {
-			c.RESr8(5, c.registers.B)
-		}, cycles: 8}
-	case 0xAA:
-		return Instruction{exec: func() {
-			c.RESr8(5, c.registers.C)
-		}, cycles: 8}
-	case 0xAB:
-		return Instruction{exec: func() {
-			c.RESr8(5, c.registers.D)
-		}, cycles: 8}
-	case 0xAC:
-		return Instruction{exec: func() {
-			c.RESr8(5, c.registers.E)
-		}, cycles: 8}
-	case 0xAD:
-		return Instruction{exec: func() {
-			c.RESr8(5, c.registers.H)
-		}, cycles: 8}
-	case 0xAE:
-		return Instruction{exec: func() {
-			c.RESr8(5, c.registers.L)
-		}, cycles: 8}
-	case 0xAF:
-		return Instruction{exec: func() {
-			c.RESm8(5, c.registers.HL.Get())
-		}, cycles: 16}
-	case 0xB7:
-		return Instruction{exec: func() {
-			c.RESr8(6, c.registers.A)
-		}, cycles: 8}
-	case 0xB0:
-		return Instruction{exec: func() {
-			c.RESr8(6, c.registers.B)
-		}, cycles: 8}
-	case 0xB1:
-		return Instruction{exec: func() {
-			c.RESr8(6, c.registers.C)
-		}, cycles: 8}
-	case 0xB2:
-		return Instruction{exec: func() {
-			c.RESr8(6, c.registers.D)
-		}, cycles: 8}
-	case 0xB3:
-		return Instruction{exec: func() {
-			c.RESr8(6, c.registers.E)
-		}, cycles: 8}
-	case 0xB4:
-		return Instruction{exec: func() {
-			c.RESr8(6, c.registers.H)
-		}, cycles: 8}
-	case 0xB5:
-		return Instruction{exec: func() {
-			c.RESr8(6, c.registers.L)
-		}, cycles: 8}
-	case 0xB6:
-		return Instruction{exec: func() {
-			c.RESm8(6, c.registers.HL.Get())
-		}, cycles: 16}
-	case 0xB8:
-		return Instruction{exec: func() {
-			c.RESr8(7, c.registers.A)
-		}, cycles: 8}
-	case 0xB9:
-		return Instruction{exec: func() {
-			c.RESr8(7, c.registers.B)
-		}, cycles: 8}
-	case 0xBA:
-		return Instruction{exec: func() {
-			c.RESr8(7, c.registers.C)
-		}, cycles: 8}
-	case 0xBB:
-		return Instruction{exec: func() {
-			c.RESr8(7, c.registers.D)
-		}, cycles: 8}
-	case 0xBC:
-		return Instruction{exec: func() {
-			c.RESr8(7, c.registers.E)
-		}, cycles: 8}
-	case 0xBD:
-		return Instruction{exec: func() {
-			c.RESr8(7, c.registers.H)
-		}, cycles: 8}
-	case 0xBE:
-		return Instruction{exec: func() {
-			c.RESr8(7, c.registers.L)
-		}, cycles: 8}
-	case 0xBF:
-		return Instruction{exec: func() {
-			c.RESm8(7, c.registers.HL.Get())
-		}, cycles: 16}
-	default:
-		log.Fatalf("OpCode 0xCB%02X not implemented", opcode)
-		return Instruction{}
-	}
+	0x27: (*CPU).DAA,
+	0x2F: (*CPU).CPL,
+	0x3F: (*CPU).CCF,
+	0x37: (*CPU).SCF,
+	0x00: (*CPU).NOP,
+	0x76: (*CPU).HALT,
+	0x10: (*CPU).STOP,
+	0xF3: (*CPU).DI,
+	0xFB: (*CPU).EI,
+	// the rotations of A always clear Z, unlike their 0xCB counterparts
+	0x07: func(c *CPU) {
+		c.RLCr8(&c.registers.A)
+		c.registers.Flags.Reset(registers.Z)
+	},
+	0x17: func(c *CPU) {
+		c.RLr8(&c.registers.A)
+		c.registers.Flags.Reset(registers.Z)
+	},
+	0x0F: func(c *CPU) {
+		c.RRCr8(&c.registers.A)
+		c.registers.Flags.Reset(registers.Z)
+	},
+	0x1F: func(c *CPU) {
+		c.RRr8(&c.registers.A)
+		c.registers.Flags.Reset(registers.Z)
+	},
+	0xC3: func(c *CPU) {
+		c.JP(c.FetchNextWord())
+	},
+	0xC2: func(c *CPU) {
+		c.JPc(registers.Z, 0x0, c.FetchNextWord())
+	},
+	0xCA: func(c *CPU) {
+		c.JPc(registers.Z, 0x1, c.FetchNextWord())
+	},
+	0xD2: func(c *CPU) {
+		c.JPc(registers.C, 0x0, c.FetchNextWord())
+	},
+	0xDA: func(c *CPU) {
+		c.JPc(registers.C, 0x1, c.FetchNextWord())
+	},
+	0xE9: func(c *CPU) {
+		c.JP(c.registers.HL.Get())
+	},
+	0x18: func(c *CPU) {
+		c.JR(c.FetchNextByte())
+	},
+	0x20: func(c *CPU) {
+		c.JRc(registers.Z, 0x0, c.FetchNextByte())
+	},
+	0x28: func(c *CPU) {
+		c.JRc(registers.Z, 0x1, c.FetchNextByte())
+	},
+	0x30: func(c *CPU) {
+		c.JRc(registers.C, 0x0, c.FetchNextByte())
+	},
+	0x38: func(c *CPU) {
+		c.JRc(registers.C, 0x1, c.FetchNextByte())
+	},
+	0xCD: func(c *CPU) {
+		c.CALL(c.FetchNextWord())
+	},
+	0xC4: func(c *CPU) {
+		c.CALLc(registers.Z, 0x0, c.FetchNextWord())
+	},
+	0xCC: func(c *CPU) {
+		c.CALLc(registers.Z, 0x1, c.FetchNextWord())
+	},
+	0xD4: func(c *CPU) {
+		c.CALLc(registers.C, 0x0, c.FetchNextWord())
+	},
+	0xDC: func(c *CPU) {
+		c.CALLc(registers.C, 0x1, c.FetchNextWord())
+	},
+	0xC7: func(c *CPU) {
+		c.RST(0x0)
+	},
+	0xCF: func(c *CPU) {
+		c.RST(0x8)
+	},
+	0xD7: func(c *CPU) {
+		c.RST(0x10)
+	},
+	0xDF: func(c *CPU) {
+		c.RST(0x18)
+	},
+	0xE7: func(c *CPU) {
+		c.RST(0x20)
+	},
+	0xEF: func(c *CPU) {
+		c.RST(0x28)
+	},
+	0xF7: func(c *CPU) {
+		c.RST(0x30)
+	},
+	0xFF: func(c *CPU) {
+		c.RST(0x38)
+	},
+	0xC9: func(c *CPU) {
+		c.RET()
+	},
+	0xC0: func(c *CPU) {
+		c.RETc(registers.Z, 0x0)
+	},
+	0xC8: func(c *CPU) {
+		c.RETc(registers.Z, 0x1)
+	},
+	0xD0: func(c *CPU) {
+		c.RETc(registers.C, 0x0)
+	},
+	0xD8: func(c *CPU) {
+		c.RETc(registers.C, 0x1)
+	},
+	0xD9: (*CPU).RETI,
+
+	// the illegal opcodes hang the CPU
+	0xD3: (*CPU).lockUp,
+	0xDB: (*CPU).lockUp,
+	0xDD: (*CPU).lockUp,
+	0xE3: (*CPU).lockUp,
+	0xE4: (*CPU).lockUp,
+	0xEB: (*CPU).lockUp,
+	0xEC: (*CPU).lockUp,
+	0xED: (*CPU).lockUp,
+	0xF4: (*CPU).lockUp,
+	0xFC: (*CPU).lockUp,
+	0xFD: (*CPU).lockUp,
+}
+
+// cbHandlers execute the opcodes prefixed by 0xCB
+var cbHandlers = [256]func(c *CPU){
+	0x37: func(c *CPU) {
+		c.SWAPr8(&c.registers.A)
+	},
+	0x30: func(c *CPU) {
+		c.SWAPr8(&c.registers.B)
+	},
+	0x31: func(c *CPU) {
+		c.SWAPr8(&c.registers.C)
+	},
+	0x32: func(c *CPU) {
+		c.SWAPr8(&c.registers.D)
+	},
+	0x33: func(c *CPU) {
+		c.SWAPr8(&c.registers.E)
+	},
+	0x34: func(c *CPU) {
+		c.SWAPr8(&c.registers.H)
+	},
+	0x35: func(c *CPU) {
+		c.SWAPr8(&c.registers.L)
+	},
+	0x36: func(c *CPU) {
+		c.SWAPm8(c.registers.HL.Get())
+	},
+	0x07: func(c *CPU) {
+		c.RLCr8(&c.registers.A)
+	},
+	0x00: func(c *CPU) {
+		c.RLCr8(&c.registers.B)
+	},
+	0x01: func(c *CPU) {
+		c.RLCr8(&c.registers.C)
+	},
+	0x02: func(c *CPU) {
+		c.RLCr8(&c.registers.D)
+	},
+	0x03: func(c *CPU) {
+		c.RLCr8(&c.registers.E)
+	},
+	0x04: func(c *CPU) {
+		c.RLCr8(&c.registers.H)
+	},
+	0x05: func(c *CPU) {
+		c.RLCr8(&c.registers.L)
+	},
+	0x06: func(c *CPU) {
+		c.RLCm8(c.registers.HL.Get())
+	},
+	0x17: func(c *CPU) {
+		c.RLr8(&c.registers.A)
+	},
+	0x10: func(c *CPU) {
+		c.RLr8(&c.registers.B)
+	},
+	0x11: func(c *CPU) {
+		c.RLr8(&c.registers.C)
+	},
+	0x12: func(c *CPU) {
+		c.RLr8(&c.registers.D)
+	},
+	0x13: func(c *CPU) {
+		c.RLr8(&c.registers.E)
+	},
+	0x14: func(c *CPU) {
+		c.RLr8(&c.registers.H)
+	},
+	0x15: func(c *CPU) {
+		c.RLr8(&c.registers.L)
+	},
+	0x16: func(c *CPU) {
+		c.RLm8(c.registers.HL.Get())
+	},
+	0x0F: func(c *CPU) {
+		c.RRCr8(&c.registers.A)
+	},
+	0x08: func(c *CPU) {
+		c.RRCr8(&c.registers.B)
+	},
+	0x09: func(c *CPU) {
+		c.RRCr8(&c.registers.C)
+	},
+	0x0A: func(c *CPU) {
+		c.RRCr8(&c.registers.D)
+	},
+	0x0B: func(c *CPU) {
+		c.RRCr8(&c.registers.E)
+	},
+	0x0C: func(c *CPU) {
+		c.RRCr8(&c.registers.H)
+	},
+	0x0D: func(c *CPU) {
+		c.RRCr8(&c.registers.L)
+	},
+	0x0E: func(c *CPU) {
+		c.RRCm8(c.registers.HL.Get())
+	},
+	0x1F: func(c *CPU) {
+		c.RRr8(&c.registers.A)
+	},
+	0x18: func(c *CPU) {
+		c.RRr8(&c.registers.B)
+	},
+	0x19: func(c *CPU) {
+		c.RRr8(&c.registers.C)
+	},
+	0x1A: func(c *CPU) {
+		c.RRr8(&c.registers.D)
+	},
+	0x1B: func(c *CPU) {
+		c.RRr8(&c.registers.E)
+	},
+	0x1C: func(c *CPU) {
+		c.RRr8(&c.registers.H)
+	},
+	0x1D: func(c *CPU) {
+		c.RRr8(&c.registers.L)
+	},
+	0x1E: func(c *CPU) {
+		c.RRm8(c.registers.HL.Get())
+	},
+	0x27: func(c *CPU) {
+		c.SLAr8(&c.registers.A)
+	},
+	0x20: func(c *CPU) {
+		c.SLAr8(&c.registers.B)
+	},
+	0x21: func(c *CPU) {
+		c.SLAr8(&c.registers.C)
+	},
+	0x22: func(c *CPU) {
+		c.SLAr8(&c.registers.D)
+	},
+	0x23: func(c *CPU) {
+		c.SLAr8(&c.registers.E)
+	},
+	0x24: func(c *CPU) {
+		c.SLAr8(&c.registers.H)
+	},
+	0x25: func(c *CPU) {
+		c.SLAr8(&c.registers.L)
+	},
+	0x26: func(c *CPU) {
+		c.SLAm8(c.registers.HL.Get())
+	},
+	0x2F: func(c *CPU) {
+		c.SRAr8(&c.registers.A)
+	},
+	0x28: func(c *CPU) {
+		c.SRAr8(&c.registers.B)
+	},
+	0x29: func(c *CPU) {
+		c.SRAr8(&c.registers.C)
+	},
+	0x2A: func(c *CPU) {
+		c.SRAr8(&c.registers.D)
+	},
+	0x2B: func(c *CPU) {
+		c.SRAr8(&c.registers.E)
+	},
+	0x2C: func(c *CPU) {
+		c.SRAr8(&c.registers.H)
+	},
+	0x2D: func(c *CPU) {
+		c.SRAr8(&c.registers.L)
+	},
+	0x2E: func(c *CPU) {
+		c.SRAm8(c.registers.HL.Get())
+	},
+	0x3F: func(c *CPU) {
+		c.SRLr8(&c.registers.A)
+	},
+	0x38: func(c *CPU) {
+		c.SRLr8(&c.registers.B)
+	},
+	0x39: func(c *CPU) {
+		c.SRLr8(&c.registers.C)
+	},
+	0x3A: func(c *CPU) {
+		c.SRLr8(&c.registers.D)
+	},
+	0x3B: func(c *CPU) {
+		c.SRLr8(&c.registers.E)
+	},
+	0x3C: func(c *CPU) {
+		c.SRLr8(&c.registers.H)
+	},
+	0x3D: func(c *CPU) {
+		c.SRLr8(&c.registers.L)
+	},
+	0x3E: func(c *CPU) {
+		c.SRLm8(c.registers.HL.Get())
+	},
+	0x47: func(c *CPU) {
+		c.BIT(0, c.registers.A.Get())
+	},
+	0x40: func(c *CPU) {
+		c.BIT(0, c.registers.B.Get())
+	},
+	0x41: func(c *CPU) {
+		c.BIT(0, c.registers.C.Get())
+	},
+	0x42: func(c *CPU) {
+		c.BIT(0, c.registers.D.Get())
+	},
+	0x43: func(c *CPU) {
+		c.BIT(0, c.registers.E.Get())
+	},
+	0x44: func(c *CPU) {
+		c.BIT(0, c.registers.H.Get())
+	},
+	0x45: func(c *CPU) {
+		c.BIT(0, c.registers.L.Get())
+	},
+	0x46: func(c *CPU) {
+		c.BIT(0, c.read(c.registers.HL.Get()))
+	},
+	0x4F: func(c *CPU) {
+		c.BIT(1, c.registers.A.Get())
+	},
+	0x48: func(c *CPU) {
+		c.BIT(1, c.registers.B.Get())
+	},
+	0x49: func(c *CPU) {
+		c.BIT(1, c.registers.C.Get())
+	},
+	0x4A: func(c *CPU) {
+		c.BIT(1, c.registers.D.Get())
+	},
+	0x4B: func(c *CPU) {
+		c.BIT(1, c.registers.E.Get())
+	},
+	0x4C: func(c *CPU) {
+		c.BIT(1, c.registers.H.Get())
+	},
+	0x4D: func(c *CPU) {
+		c.BIT(1, c.registers.L.Get())
+	},
+	0x4E: func(c *CPU) {
+		c.BIT(1, c.read(c.registers.HL.Get()))
+	},
+	0x57: func(c *CPU) {
+		c.BIT(2, c.registers.A.Get())
+	},
+	0x50: func(c *CPU) {
+		c.BIT(2, c.registers.B.Get())
+	},
+	0x51: func(c *CPU) {
+		c.BIT(2, c.registers.C.Get())
+	},
+	0x52: func(c *CPU) {
+		c.BIT(2, c.registers.D.Get())
+	},
+	0x53: func(c *CPU) {
+		c.BIT(2, c.registers.E.Get())
+	},
+	0x54: func(c *CPU) {
+		c.BIT(2, c.registers.H.Get())
+	},
+	0x55: func(c *CPU) {
+		c.BIT(2, c.registers.L.Get())
+	},
+	0x56: func(c *CPU) {
+		c.BIT(2, c.read(c.registers.HL.Get()))
+	},
+	0x5F: func(c *CPU) {
+		c.BIT(3, c.registers.A.Get())
+	},
+	0x58: func(c *CPU) {
+		c.BIT(3, c.registers.B.Get())
+	},
+	0x59: func(c *CPU) {
+		c.BIT(3, c.registers.C.Get())
+	},
+	0x5A: func(c *CPU) {
+		c.BIT(3, c.registers.D.Get())
+	},
+	0x5B: func(c *CPU) {
+		c.BIT(3, c.registers.E.Get())
+	},
+	0x5C: func(c *CPU) {
+		c.BIT(3, c.registers.H.Get())
+	},
+	0x5D: func(c *CPU) {
+		c.BIT(3, c.registers.L.Get())
+	},
+	0x5E: func(c *CPU) {
+		c.BIT(3, c.read(c.registers.HL.Get()))
+	},
+	0x67: func(c *CPU) {
+		c.BIT(4, c.registers.A.Get())
+	},
+	0x60: func(c *CPU) {
+		c.BIT(4, c.registers.B.Get())
+	},
+	0x61: func(c *CPU) {
+		c.BIT(4, c.registers.C.Get())
+	},
+	0x62: func(c *CPU) {
+		c.BIT(4, c.registers.D.Get())
+	},
+	0x63: func(c *CPU) {
+		c.BIT(4, c.registers.E.Get())
+	},
+	0x64: func(c *CPU) {
+		c.BIT(4, c.registers.H.Get())
+	},
+	0x65: func(c *CPU) {
+		c.BIT(4, c.registers.L.Get())
+	},
+	0x66: func(c *CPU) {
+		c.BIT(4, c.read(c.registers.HL.Get()))
+	},
+	0x6F: func(c *CPU) {
+		c.BIT(5, c.registers.A.Get())
+	},
+	0x68: func(c *CPU) {
+		c.BIT(5, c.registers.B.Get())
+	},
+	0x69: func(c *CPU) {
+		c.BIT(5, c.registers.C.Get())
+	},
+	0x6A: func(c *CPU) {
+		c.BIT(5, c.registers.D.Get())
+	},
+	0x6B: func(c *CPU) {
+		c.BIT(5, c.registers.E.Get())
+	},
+	0x6C: func(c *CPU) {
+		c.BIT(5, c.registers.H.Get())
+	},
+	0x6D: func(c *CPU) {
+		c.BIT(5, c.registers.L.Get())
+	},
+	0x6E: func(c *CPU) {
+		c.BIT(5, c.read(c.registers.HL.Get()))
+	},
+	0x77: func(c *CPU) {
+		c.BIT(6, c.registers.A.Get())
+	},
+	0x70: func(c *CPU) {
+		c.BIT(6, c.registers.B.Get())
+	},
+	0x71: func(c *CPU) {
+		c.BIT(6, c.registers.C.Get())
+	},
+	0x72: func(c *CPU) {
+		c.BIT(6, c.registers.D.Get())
+	},
+	0x73: func(c *CPU) {
+		c.BIT(6, c.registers.E.Get())
+	},
+	0x74: func(c *CPU) {
+		c.BIT(6, c.registers.H.Get())
+	},
+	0x75: func(c *CPU) {
+		c.BIT(6, c.registers.L.Get())
+	},
+	0x76: func(c *CPU) {
+		c.BIT(6, c.read(c.registers.HL.Get()))
+	},
+	0x7F: func(c *CPU) {
+		c.BIT(7, c.registers.A.Get())
+	},
+	0x78: func(c *CPU) {
+		c.BIT(7, c.registers.B.Get())
+	},
+	0x79: func(c *CPU) {
+		c.BIT(7, c.registers.C.Get())
+	},
+	0x7A: func(c *CPU) {
+		c.BIT(7, c.registers.D.Get())
+	},
+	0x7B: func(c *CPU) {
+		c.BIT(7, c.registers.E.Get())
+	},
+	0x7C: func(c *CPU) {
+		c.BIT(7, c.registers.H.Get())
+	},
+	0x7D: func(c *CPU) {
+		c.BIT(7, c.registers.L.Get())
+	},
+	0x7E: func(c *CPU) {
+		c.BIT(7, c.read(c.registers.HL.Get()))
+	},
+	0xC7: func(c *CPU) {
+		c.SETr8(0, &c.registers.A)
+	},
+	0xC0: func(c *CPU) {
+		c.SETr8(0, &c.registers.B)
+	},
+	0xC1: func(c *CPU) {
+		c.SETr8(0, &c.registers.C)
+	},
+	0xC2: func(c *CPU) {
+		c.SETr8(0, &c.registers.D)
+	},
+	0xC3: func(c *CPU) {
+		c.SETr8(0, &c.registers.E)
+	},
+	0xC4: func(c *CPU) {
+		c.SETr8(0, &c.registers.H)
+	},
+	0xC5: func(c *CPU) {
+		c.SETr8(0, &c.registers.L)
+	},
+	0xC6: func(c *CPU) {
+		c.SETm8(0, c.registers.HL.Get())
+	},
+	0xCF: func(c *CPU) {
+		c.SETr8(1, &c.registers.A)
+	},
+	0xC8: func(c *CPU) {
+		c.SETr8(1, &c.registers.B)
+	},
+	0xC9: func(c *CPU) {
+		c.SETr8(1, &c.registers.C)
+	},
+	0xCA: func(c *CPU) {
+		c.SETr8(1, &c.registers.D)
+	},
+	0xCB: func(c *CPU) {
+		c.SETr8(1, &c.registers.E)
+	},
+	0xCC: func(c *CPU) {
+		c.SETr8(1, &c.registers.H)
+	},
+	0xCD: func(c *CPU) {
+		c.SETr8(1, &c.registers.L)
+	},
+	0xCE: func(c *CPU) {
+		c.SETm8(1, c.registers.HL.Get())
+	},
+	0xD7: func(c *CPU) {
+		c.SETr8(2, &c.registers.A)
+	},
+	0xD0: func(c *CPU) {
+		c.SETr8(2, &c.registers.B)
+	},
+	0xD1: func(c *CPU) {
+		c.SETr8(2, &c.registers.C)
+	},
+	0xD2: func(c *CPU) {
+		c.SETr8(2, &c.registers.D)
+	},
+	0xD3: func(c *CPU) {
+		c.SETr8(2, &c.registers.E)
+	},
+	0xD4: func(c *CPU) {
+		c.SETr8(2, &c.registers.H)
+	},
+	0xD5: func(c *CPU) {
+		c.SETr8(2, &c.registers.L)
+	},
+	0xD6: func(c *CPU) {
+		c.SETm8(2, c.registers.HL.Get())
+	},
+	0xDF: func(c *CPU) {
+		c.SETr8(3, &c.registers.A)
+	},
+	0xD8: func(c *CPU) {
+		c.SETr8(3, &c.registers.B)
+	},
+	0xD9: func(c *CPU) {
+		c.SETr8(3, &c.registers.C)
+	},
+	0xDA: func(c *CPU) {
+		c.SETr8(3, &c.registers.D)
+	},
+	0xDB: func(c *CPU) {
+		c.SETr8(3, &c.registers.E)
+	},
+	0xDC: func(c *CPU) {
+		c.SETr8(3, &c.registers.H)
+	},
+	0xDD: func(c *CPU) {
+		c.SETr8(3, &c.registers.L)
+	},
+	0xDE: func(c *CPU) {
+		c.SETm8(3, c.registers.HL.Get())
+	},
+	0xE7: func(c *CPU) {
+		c.SETr8(4, &c.registers.A)
+	},
+	0xE0: func(c *CPU) {
+		c.SETr8(4, &c.registers.B)
+	},
+	0xE1: func(c *CPU) {
+		c.SETr8(4, &c.registers.C)
+	},
+	0xE2: func(c *CPU) {
+		c.SETr8(4, &c.registers.D)
+	},
+	0xE3: func(c *CPU) {
+		c.SETr8(4, &c.registers.E)
+	},
+	0xE4: func(c *CPU) {
+		c.SETr8(4, &c.registers.H)
+	},
+	0xE5: func(c *CPU) {
+		c.SETr8(4, &c.registers.L)
+	},
+	0xE6: func(c *CPU) {
+		c.SETm8(4, c.registers.HL.Get())
+	},
+	0xEF: func(c *CPU) {
+		c.SETr8(5, &c.registers.A)
+	},
+	0xE8: func(c *CPU) {
+		c.SETr8(5, &c.registers.B)
+	},
+	0xE9: func(c *CPU) {
+		c.SETr8(5, &c.registers.C)
+	},
+	0xEA: func(c *CPU) {
+		c.SETr8(5, &c.registers.D)
+	},
+	0xEB: func(c *CPU) {
+		c.SETr8(5, &c.registers.E)
+	},
+	0xEC: func(c *CPU) {
+		c.SETr8(5, &c.registers.H)
+	},
+	0xED: func(c *CPU) {
+		c.SETr8(5, &c.registers.L)
+	},
+	0xEE: func(c *CPU) {
+		c.SETm8(5, c.registers.HL.Get())
+	},
+	0xF7: func(c *CPU) {
+		c.SETr8(6, &c.registers.A)
+	},
+	0xF0: func(c *CPU) {
+		c.SETr8(6, &c.registers.B)
+	},
+	0xF1: func(c *CPU) {
+		c.SETr8(6, &c.registers.C)
+	},
+	0xF2: func(c *CPU) {
+		c.SETr8(6, &c.registers.D)
+	},
+	0xF3: func(c *CPU) {
+		c.SETr8(6, &c.registers.E)
+	},
+	0xF4: func(c *CPU) {
+		c.SETr8(6, &c.registers.H)
+	},
+	0xF5: func(c *CPU) {
+		c.SETr8(6, &c.registers.L)
+	},
+	0xF6: func(c *CPU) {
+		c.SETm8(6, c.registers.HL.Get())
+	},
+	0xFF: func(c *CPU) {
+		c.SETr8(7, &c.registers.A)
+	},
+	0xF8: func(c *CPU) {
+		c.SETr8(7, &c.registers.B)
+	},
+	0xF9: func(c *CPU) {
+		c.SETr8(7, &c.registers.C)
+	},
+	0xFA: func(c *CPU) {
+		c.SETr8(7, &c.registers.D)
+	},
+	0xFB: func(c *CPU) {
+		c.SETr8(7, &c.registers.E)
+	},
+	0xFC: func(c *CPU) {
+		c.SETr8(7, &c.registers.H)
+	},
+	0xFD: func(c *CPU) {
+		c.SETr8(7, &c.registers.L)
+	},
+	0xFE: func(c *CPU) {
+		c.SETm8(7, c.registers.HL.Get())
+	},
+	0x87: func(c *CPU) {
+		c.RESr8(0, &c.registers.A)
+	},
+	0x80: func(c *CPU) {
+		c.RESr8(0, &c.registers.B)
+	},
+	0x81: func(c *CPU) {
+		c.RESr8(0, &c.registers.C)
+	},
+	0x82: func(c *CPU) {
+		c.RESr8(0, &c.registers.D)
+	},
+	0x83: func(c *CPU) {
+		c.RESr8(0, &c.registers.E)
+	},
+	0x84: func(c *CPU) {
+		c.RESr8(0, &c.registers.H)
+	},
+	0x85: func(c *CPU) {
+		c.RESr8(0, &c.registers.L)
+	},
+	0x86: func(c *CPU) {
+		c.RESm8(0, c.registers.HL.Get())
+	},
+	0x8F: func(c *CPU) {
+		c.RESr8(1, &c.registers.A)
+	},
+	0x88: func(c *CPU) {
+		c.RESr8(1, &c.registers.B)
+	},
+	0x89: func(c *CPU) {
+		c.RESr8(1, &c.registers.C)
+	},
+	0x8A: func(c *CPU) {
+		c.RESr8(1, &c.registers.D)
+	},
+	0x8B: func(c *CPU) {
+		c.RESr8(1, &c.registers.E)
+	},
+	0x8C: func(c *CPU) {
+		c.RESr8(1, &c.registers.H)
+	},
+	0x8D: func(c *CPU) {
+		c.RESr8(1, &c.registers.L)
+	},
+	0x8E: func(c *CPU) {
+		c.RESm8(1, c.registers.HL.Get())
+	},
+	0x97: func(c *CPU) {
+		c.RESr8(2, &c.registers.A)
+	},
+	0x90: func(c *CPU) {
+		c.RESr8(2, &c.registers.B)
+	},
+	0x91: func(c *CPU) {
+		c.RESr8(2, &c.registers.C)
+	},
+	0x92: func(c *CPU) {
+		c.RESr8(2, &c.registers.D)
+	},
+	0x93: func(c *CPU) {
+		c.RESr8(2, &c.registers.E)
+	},
+	0x94: func(c *CPU) {
+		c.RESr8(2, &c.registers.H)
+	},
+	0x95: func(c *CPU) {
+		c.RESr8(2, &c.registers.L)
+	},
+	0x96: func(c *CPU) {
+		c.RESm8(2, c.registers.HL.Get())
+	},
+	0x9F: func(c *CPU) {
+		c.RESr8(3, &c.registers.A)
+	},
+	0x98: func(c *CPU) {
+		c.RESr8(3, &c.registers.B)
+	},
+	0x99: func(c *CPU) {
+		c.RESr8(3, &c.registers.C)
+	},
+	0x9A: func(c *CPU) {
+		c.RESr8(3, &c.registers.D)
+	},
+	0x9B: func(c *CPU) {
+		c.RESr8(3, &c.registers.E)
+	},
+	0x9C: func(c *CPU) {
+		c.RESr8(3, &c.registers.H)
+	},
+	0x9D: func(c *CPU) {
+		c.RESr8(3, &c.registers.L)
+	},
+	0x9E: func(c *CPU) {
+		c.RESm8(3, c.registers.HL.Get())
+	},
+	0xA7: func(c *CPU) {
+		c.RESr8(4, &c.registers.A)
+	},
+	0xA0: func(c *CPU) {
+		c.RESr8(4, &c.registers.B)
+	},
+	0xA1: func(c *CPU) {
+		c.RESr8(4, &c.registers.C)
+	},
+	0xA2: func(c *CPU) {
+		c.RESr8(4, &c.registers.D)
+	},
+	0xA3: func(c *CPU) {
+		c.RESr8(4, &c.registers.E)
+	},
+	0xA4: func(c *CPU) {
+		c.RESr8(4, &c.registers.H)
+	},
+	0xA5: func(c *CPU) {
+		c.RESr8(4, &c.registers.L)
+	},
+	0xA6: func(c *CPU) {
+		c.RESm8(4, c.registers.HL.Get())
+	},
+	0xAF: func(c *CPU) {
+		c.RESr8(5, &c.registers.A)
+	},
+	0xA8: func(c *CPU) {
+		c.RESr8(5, &c.registers.B)
+	},
+	0xA9: func(c *CPU) {
+		c.RESr8(5, &c.registers.C)
+	},
+	0xAA: func(c *CPU) {
+		c.RESr8(5, &c.registers.D)
+	},
+	0xAB: func(c *CPU) {
+		c.RESr8(5, &c.registers.E)
+	},
+	0xAC: func(c *CPU) {
+		c.RESr8(5, &c.registers.H)
+	},
+	0xAD: func(c *CPU) {
+		c.RESr8(5, &c.registers.L)
+	},
+	0xAE: func(c *CPU) {
+		c.RESm8(5, c.registers.HL.Get())
+	},
+	0xB7: func(c *CPU) {
+		c.RESr8(6, &c.registers.A)
+	},
+	0xB0: func(c *CPU) {
+		c.RESr8(6, &c.registers.B)
+	},
+	0xB1: func(c *CPU) {
+		c.RESr8(6, &c.registers.C)
+	},
+	0xB2: func(c *CPU) {
+		c.RESr8(6, &c.registers.D)
+	},
+	0xB3: func(c *CPU) {
+		c.RESr8(6, &c.registers.E)
+	},
+	0xB4: func(c *CPU) {
+		c.RESr8(6, &c.registers.H)
+	},
+	0xB5: func(c *CPU) {
+		c.RESr8(6, &c.registers.L)
+	},
+	0xB6: func(c *CPU) {
+		c.RESm8(6, c.registers.HL.Get())
+	},
+	0xBF: func(c *CPU) {
+		c.RESr8(7, &c.registers.A)
+	},
+	0xB8: func(c *CPU) {
+		c.RESr8(7, &c.registers.B)
+	},
+	0xB9: func(c *CPU) {
+		c.RESr8(7, &c.registers.C)
+	},
+	0xBA: func(c *CPU) {
+		c.RESr8(7, &c.registers.D)
+	},
+	0xBB: func(c *CPU) {
+		c.RESr8(7, &c.registers.E)
+	},
+	0xBC: func(c *CPU) {
+		c.RESr8(7, &c.registers.H)
+	},
+	0xBD: func(c *CPU) {
+		c.RESr8(7, &c.registers.L)
+	},
+	0xBE: func(c *CPU) {
+		c.RESm8(7, c.registers.HL.Get())
+	},
 }
 
 // Load a byte into a register
@@ -2123,29 +1697,174 @@ func (c *CPU) LDr16(r registers.WordRegister, w types.Word)  {
 }
 
 // Load a byte into a register
-func (c *CPU) LDr8(r types.ByteRegister, b types.Byte)  {
+func (c *CPU) LDr8(r *types.ByteRegister, b types.Byte)  {
 	r.Set(b)
 }
 
 // Load a byte into a memory address
 func (c *CPU) LDm8(address types.Word, b types.Byte)  {
-	c.mmu.Set(address, b)
+	c.write(address, b)
 }
 
 func (c *CPU) NOP() {
 }
 
-func (c *CPU) Run() {
+// Run executes instructions until one fails
+func (c *CPU) Run() error {
 	for {
-		opcode := c.FetchNextByte()
-		inst := c.Decode(opcode)
-		inst.exec()
+		if _, err := c.Step(); err != nil {
+			return err
+		}
+	}
+}
+
+// Step executes a single instruction, or dispatches an interrupt, and
+// returns the cycles it took. An opcode the CPU does not implement is
+// reported as ErrUnimplementedOpcode, with PC left on it.
+//
+// The bus is ticked a machine cycle at a time as the instruction reads and
+// writes memory, so the devices see each access at the right moment.
+func (c *CPU) Step() (int, error) {
+	c.ticked = 0
+	switch {
+	case c.locked:
+		// nothing but a reset brings the CPU back
+		c.tick(4)
+	case c.serviceInterrupt():
+	case c.halted:
+		c.tick(4)
+	default:
+		if err := c.execute(); err != nil {
+			return 0, err
+		}
+	}
+
+	// DMA into VRAM keeps the CPU waiting, while everything else runs on
+	for stall := c.mmu.TakeStall(); stall > 0; stall = c.mmu.TakeStall() {
+		c.tick(stall)
+	}
+	return c.ticked, nil
+}
+
+// execute fetches and runs the instruction at PC
+func (c *CPU) execute() error {
+	if c.Tracer != nil {
+		c.Tracer.BeforeInstruction(c)
+	}
+	// EI takes effect once the instruction after it has started
+	if c.eiDelay {
+		c.eiDelay = false
+		c.ime = true
+	}
+
+	pc := c.registers.PC.Get()
+	var opcode types.Byte
+	var handler func(c *CPU)
+	var info *Opcode
+	var prefixed bool
+	if e := c.cachedInstruction(pc); e != nil {
+		opcode, handler, info, prefixed = e.opcode, e.handler, e.info, e.prefixed
+		// the opcode fetches still take their cycles
+		c.tick(4)
+		if prefixed {
+			c.tick(4)
+		}
+	} else {
+		// fetched through the bus, which may not show what Peek does, as
+		// during an OAM DMA
+		opcode = c.FetchNextByte()
+		handler, info = handlers[opcode], &Opcodes[opcode]
+		prefixed = opcode == 0xCB
+		if prefixed {
+			opcode = c.FetchNextByte()
+			handler, info = cbHandlers[opcode], &CBOpcodes[opcode]
+		}
+		if handler == nil {
+			c.registers.PC.Set(pc)
+			return ErrUnimplementedOpcode{Opcode: opcode, PC: pc, Prefixed: prefixed}
+		}
 	}
+
+	c.branched = false
+	handler(c)
+	cycles := info.Cycles
+	if c.branched {
+		cycles = info.CyclesTaken
+	}
+	// whatever the instruction spent without touching the bus
+	c.tick(cycles - c.ticked)
+	return nil
+}
+
+// tick advances the devices on the bus
+func (c *CPU) tick(cycles int) {
+	if cycles <= 0 {
+		return
+	}
+	c.ticked += cycles
+	c.cycles += uint64(cycles)
+	c.bus.Tick(cycles)
+}
+
+// read is a memory read, which takes a machine cycle
+func (c *CPU) read(address types.Word) types.Byte {
+	c.tick(4)
+	return c.bus.Get(address)
+}
+
+// write is a memory write, which takes a machine cycle
+func (c *CPU) write(address types.Word, value types.Byte) {
+	c.tick(4)
+	c.bus.Set(address, value)
+}
+
+// lockUp is what the illegal opcodes do: the CPU stops fetching for good and
+// ignores interrupts, while the rest of the machine keeps running
+func (c *CPU) lockUp() {
+	c.locked = true
+}
+
+// serviceInterrupt wakes the CPU up when an interrupt is pending and, if
+// interrupts are enabled, jumps to the vector of the highest priority one.
+// It reports whether it dispatched one.
+func (c *CPU) serviceInterrupt() bool {
+	if c.bus.Peek(memory.IE)&c.bus.Peek(memory.IF)&0x1F == 0x0 {
+		return false
+	}
+	c.halted = false
+	if !c.ime {
+		return false
+	}
+
+	c.ime = false
+	c.tick(8)
+	pc := c.registers.PC.Get()
+	h, l := types.WordToBytes(pc)
+	c.PushByte(h)
+	// the vector is picked after the high byte is pushed, which may have
+	// overwritten IE. With nothing left pending the CPU jumps to 0x0000.
+	pending := c.bus.Peek(memory.IE) & c.bus.Peek(memory.IF) & 0x1F
+	c.PushByte(l)
+	c.JP(0x0000)
+	for bit := byte(0); bit < 5; bit++ {
+		if types.GetBit(bit, pending) == 0x1 {
+			c.bus.Set(memory.IF, types.ResetBit(bit, c.bus.Peek(memory.IF)))
+			c.JP(0x40 + types.Word(bit)*0x8)
+			break
+		}
+	}
+	c.tick(4)
+	return true
 }
 
 func (c *CPU) FetchNextByte() types.Byte {
-	b := c.mmu.Get(c.registers.PC.Get())
-	c.INC16(&c.registers.PC)
+	b := c.read(c.registers.PC.Get())
+	if c.haltBug {
+		// PC fails to move past the byte
+		c.haltBug = false
+	} else {
+		c.INC16(&c.registers.PC)
+	}
 	return b
 }
 
@@ -2155,22 +1874,25 @@ func (c *CPU) FetchNextWord() types.Word {
 	return types.WordFromBytes(h, l)
 }
 
+// PushWord pushes the high byte first. SP is decremented in a cycle of its
+// own before the writes.
 func (c *CPU) PushWord(w types.Word) {
 	h, l := types.WordToBytes(w)
+	c.tick(4)
 	c.PushByte(h)
 	c.PushByte(l)
 }
 
 func (c *CPU) PushByte(b types.Byte) {
 	c.DEC16(&c.registers.SP)
-	c.mmu.Set(c.registers.SP.Get(), b)
+	c.write(c.registers.SP.Get(), b)
 }
 
 func (c *CPU) DEC16(r registers.WordRegister) {
 	r.Set(r.Get() - 0x1)
 }
 
-func (c *CPU) DECr8(r types.ByteRegister) {
+func (c *CPU) DECr8(r *types.ByteRegister) {
 	result := r.Get() - 0x1
 
 	c.registers.Flags.Set(registers.N)
@@ -2191,30 +1913,17 @@ func (c *CPU) DECr8(r types.ByteRegister) {
 }
 
 func (c *CPU) DECm8(address types.Word) {
-	result := c.mmu.Get(address) - 0x1
-
-	c.registers.Flags.Set(registers.N)
-
-	if result == 0x0 {
-		c.registers.Flags.Set(registers.Z)
-	} else {
-		c.registers.Flags.Reset(registers.Z)
-	}
-
-	if (result^0x01^ c.mmu.Get(address))&0x10 == 0x10 {
-		c.registers.Flags.Set(registers.H)
-	} else {
-		c.registers.Flags.Reset(registers.H)
-	}
-
-	c.mmu.Set(address, result)
+	var r types.ByteRegister
+	r.Set(c.read(address))
+	c.DECr8(&r)
+	c.write(address, r.Get())
 }
 
 func (c *CPU) INC16(r registers.WordRegister) {
 	r.Set(r.Get() + 0x1)
 }
 
-func (c *CPU) INCr8(r types.ByteRegister) {
+func (c *CPU) INCr8(r *types.ByteRegister) {
 	result := r.Get() + 0x1
 
 	c.registers.Flags.Reset(registers.N)
@@ -2235,41 +1944,38 @@ func (c *CPU) INCr8(r types.ByteRegister) {
 }
 
 func (c *CPU) INCm8(address types.Word) {
-	result := c.mmu.Get(address) + 0x1
-
-	c.registers.Flags.Reset(registers.N)
-
-	if result == 0x0 {
-		c.registers.Flags.Set(registers.Z)
-	} else {
-		c.registers.Flags.Reset(registers.Z)
-	}
-
-	if (result^0x01^c.mmu.Get(address))&0x10 == 0x10 {
-		c.registers.Flags.Set(registers.H)
-	} else {
-		c.registers.Flags.Reset(registers.H)
-	}
-
-	c.mmu.Set(address, result)
+	var r types.ByteRegister
+	r.Set(c.read(address))
+	c.INCr8(&r)
+	c.write(address, r.Get())
 }
 
 func (c *CPU) PopWord(r registers.WordRegister) {
-	l := c.mmu.Get(c.registers.SP.Get())
+	l := c.read(c.registers.SP.Get())
 	c.INC16(&c.registers.SP)
-	h := c.mmu.Get(c.registers.SP.Get())
+	h := c.read(c.registers.SP.Get())
 	c.INC16(&c.registers.SP)
 
 	r.Set(types.WordFromBytes(h, l))
 }
 
-func (c *CPU) PopByte(r types.ByteRegister) {
-	r.Set(c.mmu.Get(c.registers.SP.Get()))
+func (c *CPU) PopByte(r *types.ByteRegister) {
+	r.Set(c.read(c.registers.SP.Get()))
 	c.INC16(&c.registers.SP)
 }
 
 func (c *CPU) ADD8(b types.Byte) {
-	result := c.registers.A.Get() + b
+	c.add8(b, 0)
+}
+
+// ADC8 adds b and the carry flag to A
+func (c *CPU) ADC8(b types.Byte) {
+	c.add8(b, c.registers.Flags.Get(registers.C))
+}
+
+func (c *CPU) add8(b, carry types.Byte) {
+	a := c.registers.A.Get()
+	result := a + b + carry
 
 	c.registers.Flags.Reset(registers.N)
 
@@ -2279,13 +1985,13 @@ func (c *CPU) ADD8(b types.Byte) {
 		c.registers.Flags.Reset(registers.Z)
 	}
 
-	if (result ^ b ^ c.registers.A.Get()) & 0x10 == 0x10 {
+	if a&0x0F+b&0x0F+carry > 0x0F {
 		c.registers.Flags.Set(registers.H)
 	} else {
 		c.registers.Flags.Reset(registers.H)
 	}
 
-	if result < c.registers.A.Get() {
+	if int(a)+int(b)+int(carry) > 0xFF {
 		c.registers.Flags.Set(registers.C)
 	} else {
 		c.registers.Flags.Reset(registers.C)
@@ -2294,17 +2000,12 @@ func (c *CPU) ADD8(b types.Byte) {
 	c.registers.A.Set(result)
 }
 
+// ADD16 adds b to HL, leaving Z alone
 func (c *CPU) ADD16(b types.Word) {
 	result := c.registers.HL.Get() + b
 
 	c.registers.Flags.Reset(registers.N)
 
-	if result == 0x0 {
-		c.registers.Flags.Set(registers.Z)
-	} else {
-		c.registers.Flags.Reset(registers.Z)
-	}
-
 	if (result ^ b ^ c.registers.HL.Get()) & 0x1000 == 0x1000 {
 		c.registers.Flags.Set(registers.H)
 	} else {
@@ -2320,8 +2021,43 @@ func (c *CPU) ADD16(b types.Word) {
 	c.registers.HL.Set(result)
 }
 
+// addSP returns SP plus the signed offset e. H and C come from the unsigned
+// addition of e to the low byte of SP.
+func (c *CPU) addSP(e types.Byte) types.Word {
+	sp := c.registers.SP.Get()
+	result := sp + types.Word(int8(e))
+
+	c.registers.Flags.Reset(registers.Z)
+	c.registers.Flags.Reset(registers.N)
+
+	if sp&0x0F+types.Word(e)&0x0F > 0x0F {
+		c.registers.Flags.Set(registers.H)
+	} else {
+		c.registers.Flags.Reset(registers.H)
+	}
+
+	if sp&0xFF+types.Word(e) > 0xFF {
+		c.registers.Flags.Set(registers.C)
+	} else {
+		c.registers.Flags.Reset(registers.C)
+	}
+
+	return result
+}
+
 func (c *CPU) SUB8(b types.Byte) {
-	result := c.registers.A.Get() - b
+	c.registers.A.Set(c.sub8(b, 0))
+}
+
+// SBC8 subtracts b and the carry flag from A
+func (c *CPU) SBC8(b types.Byte) {
+	c.registers.A.Set(c.sub8(b, c.registers.Flags.Get(registers.C)))
+}
+
+// sub8 returns A minus b and carry, setting the flags
+func (c *CPU) sub8(b, carry types.Byte) types.Byte {
+	a := c.registers.A.Get()
+	result := a - b - carry
 
 	c.registers.Flags.Set(registers.N)
 
@@ -2331,19 +2067,19 @@ func (c *CPU) SUB8(b types.Byte) {
 		c.registers.Flags.Reset(registers.Z)
 	}
 
-	if (int(c.registers.A.Get()) & 0xF) < (int(b) & 0xF) {
+	if int(a&0xF) < int(b&0xF)+int(carry) {
 		c.registers.Flags.Set(registers.H)
 	} else {
 		c.registers.Flags.Reset(registers.H)
 	}
 
-	if (int(c.registers.A.Get()) & 0xFF) < (int(b) & 0xFF) {
+	if int(a) < int(b)+int(carry) {
 		c.registers.Flags.Set(registers.C)
 	} else {
 		c.registers.Flags.Reset(registers.C)
 	}
 
-	c.registers.A.Set(result)
+	return result
 }
 
 func (c *CPU) AND8(b types.Byte) {
@@ -2394,12 +2130,9 @@ func (c *CPU) XOR8(b types.Byte) {
 	c.registers.A.Set(result)
 }
 
+// CP8 compares b with A, setting the flags as SUB8 would without changing A
 func (c *CPU) CP8(b types.Byte) {
-	result := c.registers.A.Get() - b
-
-	// @todo
-
-	c.registers.A.Set(result)
+	c.sub8(b, 0)
 }
 
 func (c *CPU) BIT(bit byte, b types.Byte) {
@@ -2412,23 +2145,23 @@ func (c *CPU) BIT(bit byte, b types.Byte) {
 	}
 }
 
-func (c *CPU) SETr8(bit byte, r types.ByteRegister) {
+func (c *CPU) SETr8(bit byte, r *types.ByteRegister) {
 	r.Set(types.SetBit(bit, r.Get()))
 }
 
 func (c *CPU) SETm8(bit byte, address types.Word) {
-	c.mmu.Set(address, types.SetBit(bit, c.mmu.Get(address)))
+	c.write(address, types.SetBit(bit, c.read(address)))
 }
 
-func (c *CPU) RESr8(bit byte, r types.ByteRegister) {
+func (c *CPU) RESr8(bit byte, r *types.ByteRegister) {
 	r.Set(types.ResetBit(bit, r.Get()))
 }
 
 func (c *CPU) RESm8(bit byte, address types.Word) {
-	c.mmu.Set(address, types.ResetBit(bit, c.mmu.Get(address)))
+	c.write(address, types.ResetBit(bit, c.read(address)))
 }
 
-func (c *CPU) SWAPr8(r types.ByteRegister) {
+func (c *CPU) SWAPr8(r *types.ByteRegister) {
 	c.registers.Flags.Reset(registers.N)
 	c.registers.Flags.Reset(registers.H)
 	c.registers.Flags.Reset(registers.C)
@@ -2444,18 +2177,10 @@ func (c *CPU) SWAPr8(r types.ByteRegister) {
 }
 
 func (c *CPU) SWAPm8(address types.Word) {
-	c.registers.Flags.Reset(registers.N)
-	c.registers.Flags.Reset(registers.H)
-	c.registers.Flags.Reset(registers.C)
-
-	result := ((uint16(c.mmu.Get(address)) << 4) | (uint16(c.mmu.Get(address)) >> 4)) & 0xFF
-	c.mmu.Set(address, types.Byte(result))
-
-	if result == 0x0 {
-		c.registers.Flags.Set(registers.Z)
-	} else {
-		c.registers.Flags.Reset(registers.Z)
-	}
+	var r types.ByteRegister
+	r.Set(c.read(address))
+	c.SWAPr8(&r)
+	c.write(address, r.Get())
 }
 
 func (c *CPU) CCF() {
@@ -2474,37 +2199,84 @@ func (c *CPU) SCF() {
 	c.registers.Flags.Reset(registers.H)
 }
 
+// DAA adjusts A back to binary coded decimal after an addition or a
+// subtraction, using the N, H and C flags it left
 func (c *CPU) DAA() {
-	// @todo
+	a := c.registers.A.Get()
+	var correction types.Byte
+	carry := c.registers.Flags.Get(registers.C) == 1
+
+	if c.registers.Flags.Get(registers.H) == 1 || (c.registers.Flags.Get(registers.N) == 0 && a&0x0F > 0x09) {
+		correction |= 0x06
+	}
+	if carry || (c.registers.Flags.Get(registers.N) == 0 && a > 0x99) {
+		correction |= 0x60
+		carry = true
+	}
+	if c.registers.Flags.Get(registers.N) == 1 {
+		a -= correction
+	} else {
+		a += correction
+	}
+
+	if a == 0x0 {
+		c.registers.Flags.Set(registers.Z)
+	} else {
+		c.registers.Flags.Reset(registers.Z)
+	}
+	c.registers.Flags.Reset(registers.H)
+	if carry {
+		c.registers.Flags.Set(registers.C)
+	} else {
+		c.registers.Flags.Reset(registers.C)
+	}
+	c.registers.A.Set(a)
 }
 
 func (c *CPU) CPL() {
-	// @todo
+	c.registers.A.Set(^c.registers.A.Get())
+	c.registers.Flags.Set(registers.N)
+	c.registers.Flags.Set(registers.H)
 }
 
+// HALT waits for an interrupt. With interrupts disabled and one already
+// pending it does not halt, and the next opcode byte is read twice.
 func (c *CPU) HALT() {
-	// @todo
+	if !c.ime && c.bus.Peek(memory.IE)&c.bus.Peek(memory.IF)&0x1F != 0 {
+		c.haltBug = true
+		return
+	}
+	c.halted = true
 }
 
 func (c *CPU) STOP() {
+	// an armed CGB speed switch is all STOP is used for in practice
+	if c.mmu.SwitchSpeed() {
+		return
+	}
 	// @todo
 	c.HALT()
 }
 
 func (c *CPU) DI() {
-	// @todo
+	c.ime = false
+	c.eiDelay = false
 }
 
+// EI enables interrupts once the next instruction has started, so EI
+// followed by RET returns before any interrupt is taken
 func (c *CPU) EI() {
-	// @todo
+	c.eiDelay = true
 }
 
-func (c *CPU) RLCr8(r types.ByteRegister) {
+func (c *CPU) RLCr8(r *types.ByteRegister) {
 	c.registers.Flags.Reset(registers.N)
 	c.registers.Flags.Reset(registers.H)
 
 	if r.Get() & 0x80 == 0x80 {
 		c.registers.Flags.Set(registers.C)
+	} else {
+		c.registers.Flags.Reset(registers.C)
 	}
 
 	result := (r.Get() << 1) | c.registers.Flags.Get(registers.C)
@@ -2517,7 +2289,7 @@ func (c *CPU) RLCr8(r types.ByteRegister) {
 	r.Set(result)
 }
 
-func (c *CPU) RLr8(r types.ByteRegister) {
+func (c *CPU) RLr8(r *types.ByteRegister) {
 	c.registers.Flags.Reset(registers.N)
 	c.registers.Flags.Reset(registers.H)
 
@@ -2537,12 +2309,14 @@ func (c *CPU) RLr8(r types.ByteRegister) {
 	r.Set(result)
 }
 
-func (c *CPU) RRCr8(r types.ByteRegister) {
+func (c *CPU) RRCr8(r *types.ByteRegister) {
 	c.registers.Flags.Reset(registers.N)
 	c.registers.Flags.Reset(registers.H)
 
 	if r.Get() & 0x01 == 0x01 {
 		c.registers.Flags.Set(registers.C)
+	} else {
+		c.registers.Flags.Reset(registers.C)
 	}
 
 	result := (r.Get() >> 1) | (c.registers.Flags.Get(registers.C) << 7)
@@ -2555,7 +2329,7 @@ func (c *CPU) RRCr8(r types.ByteRegister) {
 	r.Set(result)
 }
 
-func (c *CPU) RRr8(r types.ByteRegister) {
+func (c *CPU) RRr8(r *types.ByteRegister) {
 	c.registers.Flags.Reset(registers.N)
 	c.registers.Flags.Reset(registers.H)
 
@@ -2576,82 +2350,34 @@ func (c *CPU) RRr8(r types.ByteRegister) {
 }
 
 func (c *CPU) RLCm8(address types.Word) {
-	c.registers.Flags.Reset(registers.N)
-	c.registers.Flags.Reset(registers.H)
-
-	if c.mmu.Get(address) & 0x80 == 0x80 {
-		c.registers.Flags.Set(registers.C)
-	}
-
-	result := (c.mmu.Get(address) << 1) | c.registers.Flags.Get(registers.C)
-
-	if result == 0x0 {
-		c.registers.Flags.Set(registers.Z)
-	} else {
-		c.registers.Flags.Reset(registers.Z)
-	}
-	c.mmu.Set(address, result)
+	var r types.ByteRegister
+	r.Set(c.read(address))
+	c.RLCr8(&r)
+	c.write(address, r.Get())
 }
 
 func (c *CPU) RLm8(address types.Word) {
-	c.registers.Flags.Reset(registers.N)
-	c.registers.Flags.Reset(registers.H)
-
-	result := (c.mmu.Get(address) << 1) | c.registers.Flags.Get(registers.C)
-
-	if c.mmu.Get(address) & 0x80 == 0x80 {
-		c.registers.Flags.Set(registers.C)
-	} else {
-		c.registers.Flags.Reset(registers.C)
-	}
-
-	if result == 0x0 {
-		c.registers.Flags.Set(registers.Z)
-	} else {
-		c.registers.Flags.Reset(registers.Z)
-	}
-	c.mmu.Set(address, result)
+	var r types.ByteRegister
+	r.Set(c.read(address))
+	c.RLr8(&r)
+	c.write(address, r.Get())
 }
 
 func (c *CPU) RRCm8(address types.Word) {
-	c.registers.Flags.Reset(registers.N)
-	c.registers.Flags.Reset(registers.H)
-
-	if c.mmu.Get(address) & 0x01 == 0x01 {
-		c.registers.Flags.Set(registers.C)
-	}
-
-	result := (c.mmu.Get(address) >> 1) | (c.registers.Flags.Get(registers.C) << 7)
-
-	if result == 0x0 {
-		c.registers.Flags.Set(registers.Z)
-	} else {
-		c.registers.Flags.Reset(registers.Z)
-	}
-	c.mmu.Set(address, result)
+	var r types.ByteRegister
+	r.Set(c.read(address))
+	c.RRCr8(&r)
+	c.write(address, r.Get())
 }
 
 func (c *CPU) RRm8(address types.Word) {
-	c.registers.Flags.Reset(registers.N)
-	c.registers.Flags.Reset(registers.H)
-
-	result := (c.mmu.Get(address) >> 1) | (c.registers.Flags.Get(registers.C) << 7)
-
-	if c.mmu.Get(address) & 0x01 == 0x01 {
-		c.registers.Flags.Set(registers.C)
-	} else {
-		c.registers.Flags.Reset(registers.C)
-	}
-
-	if result == 0x0 {
-		c.registers.Flags.Set(registers.Z)
-	} else {
-		c.registers.Flags.Reset(registers.Z)
-	}
-	c.mmu.Set(address, result)
+	var r types.ByteRegister
+	r.Set(c.read(address))
+	c.RRr8(&r)
+	c.write(address, r.Get())
 }
 
-func (c *CPU) SLAr8(r types.ByteRegister) {
+func (c *CPU) SLAr8(r *types.ByteRegister) {
 	c.registers.Flags.Reset(registers.N)
 	c.registers.Flags.Reset(registers.H)
 
@@ -2672,32 +2398,19 @@ func (c *CPU) SLAr8(r types.ByteRegister) {
 }
 
 func (c *CPU) SLAm8(address types.Word) {
-	c.registers.Flags.Reset(registers.N)
-	c.registers.Flags.Reset(registers.H)
-
-	result := c.mmu.Get(address) << 1
-
-	if c.mmu.Get(address) & 0x80 == 0x80 {
-		c.registers.Flags.Set(registers.C)
-	} else {
-		c.registers.Flags.Reset(registers.C)
-	}
-
-	if result == 0x0 {
-		c.registers.Flags.Set(registers.Z)
-	} else {
-		c.registers.Flags.Reset(registers.Z)
-	}
-	c.mmu.Set(address, result)
+	var r types.ByteRegister
+	r.Set(c.read(address))
+	c.SLAr8(&r)
+	c.write(address, r.Get())
 }
 
-func (c *CPU) SRAr8(r types.ByteRegister) {
+func (c *CPU) SRAr8(r *types.ByteRegister) {
 	c.registers.Flags.Reset(registers.N)
 	c.registers.Flags.Reset(registers.H)
 
 	result := (r.Get() >> 1) | (r.Get() & 0x80)
 
-	if r.Get() & 0x80 == 0x80 {
+	if r.Get() & 0x01 == 0x01 {
 		c.registers.Flags.Set(registers.C)
 	} else {
 		c.registers.Flags.Reset(registers.C)
@@ -2712,32 +2425,19 @@ func (c *CPU) SRAr8(r types.ByteRegister) {
 }
 
 func (c *CPU) SRAm8(address types.Word) {
-	c.registers.Flags.Reset(registers.N)
-	c.registers.Flags.Reset(registers.H)
-
-	result := (c.mmu.Get(address) >> 1) | (c.mmu.Get(address) & 0x80)
-
-	if c.mmu.Get(address) & 0x80 == 0x80 {
-		c.registers.Flags.Set(registers.C)
-	} else {
-		c.registers.Flags.Reset(registers.C)
-	}
-
-	if result == 0x0 {
-		c.registers.Flags.Set(registers.Z)
-	} else {
-		c.registers.Flags.Reset(registers.Z)
-	}
-	c.mmu.Set(address, result)
+	var r types.ByteRegister
+	r.Set(c.read(address))
+	c.SRAr8(&r)
+	c.write(address, r.Get())
 }
 
-func (c *CPU) SRLr8(r types.ByteRegister) {
+func (c *CPU) SRLr8(r *types.ByteRegister) {
 	c.registers.Flags.Reset(registers.N)
 	c.registers.Flags.Reset(registers.H)
 
 	result := r.Get() >> 1
 
-	if r.Get() & 0x80 == 0x80 {
+	if r.Get() & 0x01 == 0x01 {
 		c.registers.Flags.Set(registers.C)
 	} else {
 		c.registers.Flags.Reset(registers.C)
@@ -2752,58 +2452,51 @@ func (c *CPU) SRLr8(r types.ByteRegister) {
 }
 
 func (c *CPU) SRLm8(address types.Word) {
-	c.registers.Flags.Reset(registers.N)
-	c.registers.Flags.Reset(registers.H)
-
-	result := c.mmu.Get(address) >> 1
-
-	if c.mmu.Get(address) & 0x80 == 0x80 {
-		c.registers.Flags.Set(registers.C)
-	} else {
-		c.registers.Flags.Reset(registers.C)
-	}
-
-	if result == 0x0 {
-		c.registers.Flags.Set(registers.Z)
-	} else {
-		c.registers.Flags.Reset(registers.Z)
-	}
-	c.mmu.Set(address, result)
+	var r types.ByteRegister
+	r.Set(c.read(address))
+	c.SRLr8(&r)
+	c.write(address, r.Get())
 }
 
 func (c *CPU) JP(address types.Word) {
 	c.registers.PC.Set(address)
 }
 
-func (c *CPU) JPc(flag int, flagValue types.Byte, address types.Word) {
+func (c *CPU) JPc(flag registers.Flag, flagValue types.Byte, address types.Word) {
 	if c.registers.Flags.Get(flag) == flagValue {
+		c.branched = true
 		c.JP(address)
 	}
 }
 
-func (c *CPU) JR(b types.Byte) {
-	c.JP(c.registers.PC.Get() + types.Word(b))
+// JR jumps by the signed offset e, relative to the next instruction
+func (c *CPU) JR(e types.Byte) {
+	c.JP(c.registers.PC.Get() + types.Word(int8(e)))
 }
 
-func (c *CPU) JRc(flag int, flagValue types.Byte, b types.Byte) {
+func (c *CPU) JRc(flag registers.Flag, flagValue types.Byte, b types.Byte) {
 	if c.registers.Flags.Get(flag) == flagValue {
+		c.branched = true
 		c.JR(b)
 	}
 }
 
+// CALL pushes the address of the next instruction, where PC already is once
+// the operand has been fetched
 func (c *CPU) CALL(address types.Word) {
-	c.PushWord(c.registers.PC.Get() + 0x3)
+	c.PushWord(c.registers.PC.Get())
 	c.JP(address)
 }
 
-func (c *CPU) CALLc(flag int, flagValue types.Byte, address types.Word) {
+func (c *CPU) CALLc(flag registers.Flag, flagValue types.Byte, address types.Word) {
 	if c.registers.Flags.Get(flag) == flagValue {
+		c.branched = true
 		c.CALL(address)
 	}
 }
 
 func (c *CPU) RST(address types.Word) {
-	c.PushWord(c.registers.PC.Get() + 0x1)
+	c.PushWord(c.registers.PC.Get())
 	c.JP(address)
 }
 
@@ -2811,13 +2504,17 @@ func (c *CPU) RET() {
 	c.PopWord(&c.registers.PC)
 }
 
-func (c *CPU) RETc(flag int, flagValue types.Byte) {
+// RETc spends a cycle on the condition before popping
+func (c *CPU) RETc(flag registers.Flag, flagValue types.Byte) {
+	c.tick(4)
 	if c.registers.Flags.Get(flag) == flagValue {
+		c.branched = true
 		c.RET()
 	}
 }
 
+// RETI enables interrupts right away, without the delay of EI
 func (c *CPU) RETI() {
 	c.RET()
-	c.EI()
-}
\ No newline at end of file
+	c.ime = true
+}
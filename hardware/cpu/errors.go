@@ -0,0 +1,22 @@
+package cpu
+
+import (
+	"fmt"
+
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// ErrUnimplementedOpcode is returned when the CPU decodes an opcode it cannot
+// execute
+type ErrUnimplementedOpcode struct {
+	Opcode   types.Byte
+	PC       types.Word
+	Prefixed bool
+}
+
+func (e ErrUnimplementedOpcode) Error() string {
+	if e.Prefixed {
+		return fmt.Sprintf("OpCode 0xCB%02X not implemented at 0x%04X", e.Opcode, e.PC)
+	}
+	return fmt.Sprintf("OpCode 0x%02X not implemented at 0x%04X", e.Opcode, e.PC)
+}
@@ -0,0 +1,323 @@
+package cpu
+
+// Opcode describes an instruction. Operands in the mnemonic are written as
+// n8/n16 (immediate data), a8/a16 (addresses, a8 is relative to 0xFF00) and
+// e8 (signed offset).
+type Opcode struct {
+	Mnemonic string
+	// Length in bytes, prefix and operands included
+	Length int
+	Cycles int
+	// CyclesTaken is the duration of a conditional instruction when its
+	// condition holds
+	CyclesTaken int
+}
+
+// Illegal reports whether the opcode does not exist on the SM83
+func (o Opcode) Illegal() bool {
+	return o.Mnemonic == ""
+}
+
+// Opcodes describes every opcode, illegal ones are left empty
+var Opcodes = [256]Opcode{
+	0x00: {"NOP", 1, 4, 4},
+	0x01: {"LD BC,n16", 3, 12, 12},
+	0x02: {"LD (BC),A", 1, 8, 8},
+	0x03: {"INC BC", 1, 8, 8},
+	0x04: {"INC B", 1, 4, 4},
+	0x05: {"DEC B", 1, 4, 4},
+	0x06: {"LD B,n8", 2, 8, 8},
+	0x07: {"RLCA", 1, 4, 4},
+	0x08: {"LD (a16),SP", 3, 20, 20},
+	0x09: {"ADD HL,BC", 1, 8, 8},
+	0x0A: {"LD A,(BC)", 1, 8, 8},
+	0x0B: {"DEC BC", 1, 8, 8},
+	0x0C: {"INC C", 1, 4, 4},
+	0x0D: {"DEC C", 1, 4, 4},
+	0x0E: {"LD C,n8", 2, 8, 8},
+	0x0F: {"RRCA", 1, 4, 4},
+	0x10: {"STOP", 2, 4, 4},
+	0x11: {"LD DE,n16", 3, 12, 12},
+	0x12: {"LD (DE),A", 1, 8, 8},
+	0x13: {"INC DE", 1, 8, 8},
+	0x14: {"INC D", 1, 4, 4},
+	0x15: {"DEC D", 1, 4, 4},
+	0x16: {"LD D,n8", 2, 8, 8},
+	0x17: {"RLA", 1, 4, 4},
+	0x18: {"JR e8", 2, 12, 12},
+	0x19: {"ADD HL,DE", 1, 8, 8},
+	0x1A: {"LD A,(DE)", 1, 8, 8},
+	0x1B: {"DEC DE", 1, 8, 8},
+	0x1C: {"INC E", 1, 4, 4},
+	0x1D: {"DEC E", 1, 4, 4},
+	0x1E: {"LD E,n8", 2, 8, 8},
+	0x1F: {"RRA", 1, 4, 4},
+	0x20: {"JR NZ,e8", 2, 8, 12},
+	0x21: {"LD HL,n16", 3, 12, 12},
+	0x22: {"LD (HL+),A", 1, 8, 8},
+	0x23: {"INC HL", 1, 8, 8},
+	0x24: {"INC H", 1, 4, 4},
+	0x25: {"DEC H", 1, 4, 4},
+	0x26: {"LD H,n8", 2, 8, 8},
+	0x27: {"DAA", 1, 4, 4},
+	0x28: {"JR Z,e8", 2, 8, 12},
+	0x29: {"ADD HL,HL", 1, 8, 8},
+	0x2A: {"LD A,(HL+)", 1, 8, 8},
+	0x2B: {"DEC HL", 1, 8, 8},
+	0x2C: {"INC L", 1, 4, 4},
+	0x2D: {"DEC L", 1, 4, 4},
+	0x2E: {"LD L,n8", 2, 8, 8},
+	0x2F: {"CPL", 1, 4, 4},
+	0x30: {"JR NC,e8", 2, 8, 12},
+	0x31: {"LD SP,n16", 3, 12, 12},
+	0x32: {"LD (HL-),A", 1, 8, 8},
+	0x33: {"INC SP", 1, 8, 8},
+	0x34: {"INC (HL)", 1, 12, 12},
+	0x35: {"DEC (HL)", 1, 12, 12},
+	0x36: {"LD (HL),n8", 2, 12, 12},
+	0x37: {"SCF", 1, 4, 4},
+	0x38: {"JR C,e8", 2, 8, 12},
+	0x39: {"ADD HL,SP", 1, 8, 8},
+	0x3A: {"LD A,(HL-)", 1, 8, 8},
+	0x3B: {"DEC SP", 1, 8, 8},
+	0x3C: {"INC A", 1, 4, 4},
+	0x3D: {"DEC A", 1, 4, 4},
+	0x3E: {"LD A,n8", 2, 8, 8},
+	0x3F: {"CCF", 1, 4, 4},
+	0x40: {"LD B,B", 1, 4, 4},
+	0x41: {"LD B,C", 1, 4, 4},
+	0x42: {"LD B,D", 1, 4, 4},
+	0x43: {"LD B,E", 1, 4, 4},
+	0x44: {"LD B,H", 1, 4, 4},
+	0x45: {"LD B,L", 1, 4, 4},
+	0x46: {"LD B,(HL)", 1, 8, 8},
+	0x47: {"LD B,A", 1, 4, 4},
+	0x48: {"LD C,B", 1, 4, 4},
+	0x49: {"LD C,C", 1, 4, 4},
+	0x4A: {"LD C,D", 1, 4, 4},
+	0x4B: {"LD C,E", 1, 4, 4},
+	0x4C: {"LD C,H", 1, 4, 4},
+	0x4D: {"LD C,L", 1, 4, 4},
+	0x4E: {"LD C,(HL)", 1, 8, 8},
+	0x4F: {"LD C,A", 1, 4, 4},
+	0x50: {"LD D,B", 1, 4, 4},
+	0x51: {"LD D,C", 1, 4, 4},
+	0x52: {"LD D,D", 1, 4, 4},
+	0x53: {"LD D,E", 1, 4, 4},
+	0x54: {"LD D,H", 1, 4, 4},
+	0x55: {"LD D,L", 1, 4, 4},
+	0x56: {"LD D,(HL)", 1, 8, 8},
+	0x57: {"LD D,A", 1, 4, 4},
+	0x58: {"LD E,B", 1, 4, 4},
+	0x59: {"LD E,C", 1, 4, 4},
+	0x5A: {"LD E,D", 1, 4, 4},
+	0x5B: {"LD E,E", 1, 4, 4},
+	0x5C: {"LD E,H", 1, 4, 4},
+	0x5D: {"LD E,L", 1, 4, 4},
+	0x5E: {"LD E,(HL)", 1, 8, 8},
+	0x5F: {"LD E,A", 1, 4, 4},
+	0x60: {"LD H,B", 1, 4, 4},
+	0x61: {"LD H,C", 1, 4, 4},
+	0x62: {"LD H,D", 1, 4, 4},
+	0x63: {"LD H,E", 1, 4, 4},
+	0x64: {"LD H,H", 1, 4, 4},
+	0x65: {"LD H,L", 1, 4, 4},
+	0x66: {"LD H,(HL)", 1, 8, 8},
+	0x67: {"LD H,A", 1, 4, 4},
+	0x68: {"LD L,B", 1, 4, 4},
+	0x69: {"LD L,C", 1, 4, 4},
+	0x6A: {"LD L,D", 1, 4, 4},
+	0x6B: {"LD L,E", 1, 4, 4},
+	0x6C: {"LD L,H", 1, 4, 4},
+	0x6D: {"LD L,L", 1, 4, 4},
+	0x6E: {"LD L,(HL)", 1, 8, 8},
+	0x6F: {"LD L,A", 1, 4, 4},
+	0x70: {"LD (HL),B", 1, 8, 8},
+	0x71: {"LD (HL),C", 1, 8, 8},
+	0x72: {"LD (HL),D", 1, 8, 8},
+	0x73: {"LD (HL),E", 1, 8, 8},
+	0x74: {"LD (HL),H", 1, 8, 8},
+	0x75: {"LD (HL),L", 1, 8, 8},
+	0x76: {"HALT", 1, 4, 4},
+	0x77: {"LD (HL),A", 1, 8, 8},
+	0x78: {"LD A,B", 1, 4, 4},
+	0x79: {"LD A,C", 1, 4, 4},
+	0x7A: {"LD A,D", 1, 4, 4},
+	0x7B: {"LD A,E", 1, 4, 4},
+	0x7C: {"LD A,H", 1, 4, 4},
+	0x7D: {"LD A,L", 1, 4, 4},
+	0x7E: {"LD A,(HL)", 1, 8, 8},
+	0x7F: {"LD A,A", 1, 4, 4},
+	0x80: {"ADD A,B", 1, 4, 4},
+	0x81: {"ADD A,C", 1, 4, 4},
+	0x82: {"ADD A,D", 1, 4, 4},
+	0x83: {"ADD A,E", 1, 4, 4},
+	0x84: {"ADD A,H", 1, 4, 4},
+	0x85: {"ADD A,L", 1, 4, 4},
+	0x86: {"ADD A,(HL)", 1, 8, 8},
+	0x87: {"ADD A,A", 1, 4, 4},
+	0x88: {"ADC A,B", 1, 4, 4},
+	0x89: {"ADC A,C", 1, 4, 4},
+	0x8A: {"ADC A,D", 1, 4, 4},
+	0x8B: {"ADC A,E", 1, 4, 4},
+	0x8C: {"ADC A,H", 1, 4, 4},
+	0x8D: {"ADC A,L", 1, 4, 4},
+	0x8E: {"ADC A,(HL)", 1, 8, 8},
+	0x8F: {"ADC A,A", 1, 4, 4},
+	0x90: {"SUB B", 1, 4, 4},
+	0x91: {"SUB C", 1, 4, 4},
+	0x92: {"SUB D", 1, 4, 4},
+	0x93: {"SUB E", 1, 4, 4},
+	0x94: {"SUB H", 1, 4, 4},
+	0x95: {"SUB L", 1, 4, 4},
+	0x96: {"SUB (HL)", 1, 8, 8},
+	0x97: {"SUB A", 1, 4, 4},
+	0x98: {"SBC A,B", 1, 4, 4},
+	0x99: {"SBC A,C", 1, 4, 4},
+	0x9A: {"SBC A,D", 1, 4, 4},
+	0x9B: {"SBC A,E", 1, 4, 4},
+	0x9C: {"SBC A,H", 1, 4, 4},
+	0x9D: {"SBC A,L", 1, 4, 4},
+	0x9E: {"SBC A,(HL)", 1, 8, 8},
+	0x9F: {"SBC A,A", 1, 4, 4},
+	0xA0: {"AND B", 1, 4, 4},
+	0xA1: {"AND C", 1, 4, 4},
+	0xA2: {"AND D", 1, 4, 4},
+	0xA3: {"AND E", 1, 4, 4},
+	0xA4: {"AND H", 1, 4, 4},
+	0xA5: {"AND L", 1, 4, 4},
+	0xA6: {"AND (HL)", 1, 8, 8},
+	0xA7: {"AND A", 1, 4, 4},
+	0xA8: {"XOR B", 1, 4, 4},
+	0xA9: {"XOR C", 1, 4, 4},
+	0xAA: {"XOR D", 1, 4, 4},
+	0xAB: {"XOR E", 1, 4, 4},
+	0xAC: {"XOR H", 1, 4, 4},
+	0xAD: {"XOR L", 1, 4, 4},
+	0xAE: {"XOR (HL)", 1, 8, 8},
+	0xAF: {"XOR A", 1, 4, 4},
+	0xB0: {"OR B", 1, 4, 4},
+	0xB1: {"OR C", 1, 4, 4},
+	0xB2: {"OR D", 1, 4, 4},
+	0xB3: {"OR E", 1, 4, 4},
+	0xB4: {"OR H", 1, 4, 4},
+	0xB5: {"OR L", 1, 4, 4},
+	0xB6: {"OR (HL)", 1, 8, 8},
+	0xB7: {"OR A", 1, 4, 4},
+	0xB8: {"CP B", 1, 4, 4},
+	0xB9: {"CP C", 1, 4, 4},
+	0xBA: {"CP D", 1, 4, 4},
+	0xBB: {"CP E", 1, 4, 4},
+	0xBC: {"CP H", 1, 4, 4},
+	0xBD: {"CP L", 1, 4, 4},
+	0xBE: {"CP (HL)", 1, 8, 8},
+	0xBF: {"CP A", 1, 4, 4},
+	0xC0: {"RET NZ", 1, 8, 20},
+	0xC1: {"POP BC", 1, 12, 12},
+	0xC2: {"JP NZ,a16", 3, 12, 16},
+	0xC3: {"JP a16", 3, 16, 16},
+	0xC4: {"CALL NZ,a16", 3, 12, 24},
+	0xC5: {"PUSH BC", 1, 16, 16},
+	0xC6: {"ADD A,n8", 2, 8, 8},
+	0xC7: {"RST $00", 1, 16, 16},
+	0xC8: {"RET Z", 1, 8, 20},
+	0xC9: {"RET", 1, 16, 16},
+	0xCA: {"JP Z,a16", 3, 12, 16},
+	0xCB: {"PREFIX CB", 1, 4, 4},
+	0xCC: {"CALL Z,a16", 3, 12, 24},
+	0xCD: {"CALL a16", 3, 24, 24},
+	0xCE: {"ADC A,n8", 2, 8, 8},
+	0xCF: {"RST $08", 1, 16, 16},
+	0xD0: {"RET NC", 1, 8, 20},
+	0xD1: {"POP DE", 1, 12, 12},
+	0xD2: {"JP NC,a16", 3, 12, 16},
+	0xD4: {"CALL NC,a16", 3, 12, 24},
+	0xD5: {"PUSH DE", 1, 16, 16},
+	0xD6: {"SUB n8", 2, 8, 8},
+	0xD7: {"RST $10", 1, 16, 16},
+	0xD8: {"RET C", 1, 8, 20},
+	0xD9: {"RETI", 1, 16, 16},
+	0xDA: {"JP C,a16", 3, 12, 16},
+	0xDC: {"CALL C,a16", 3, 12, 24},
+	0xDE: {"SBC A,n8", 2, 8, 8},
+	0xDF: {"RST $18", 1, 16, 16},
+	0xE0: {"LDH (a8),A", 2, 12, 12},
+	0xE1: {"POP HL", 1, 12, 12},
+	0xE2: {"LD ($FF00+C),A", 1, 8, 8},
+	0xE5: {"PUSH HL", 1, 16, 16},
+	0xE6: {"AND n8", 2, 8, 8},
+	0xE7: {"RST $20", 1, 16, 16},
+	0xE8: {"ADD SP,e8", 2, 16, 16},
+	0xE9: {"JP HL", 1, 4, 4},
+	0xEA: {"LD (a16),A", 3, 16, 16},
+	0xEE: {"XOR n8", 2, 8, 8},
+	0xEF: {"RST $28", 1, 16, 16},
+	0xF0: {"LDH A,(a8)", 2, 12, 12},
+	0xF1: {"POP AF", 1, 12, 12},
+	0xF2: {"LD A,($FF00+C)", 1, 8, 8},
+	0xF3: {"DI", 1, 4, 4},
+	0xF5: {"PUSH AF", 1, 16, 16},
+	0xF6: {"OR n8", 2, 8, 8},
+	0xF7: {"RST $30", 1, 16, 16},
+	0xF8: {"LD HL,SP+e8", 2, 12, 12},
+	0xF9: {"LD SP,HL", 1, 8, 8},
+	0xFA: {"LD A,(a16)", 3, 16, 16},
+	0xFB: {"EI", 1, 4, 4},
+	0xFE: {"CP n8", 2, 8, 8},
+	0xFF: {"RST $38", 1, 16, 16},
+}
+
+// branches marks the opcodes after which execution may not continue with
+// the next instruction in memory: jumps, calls, returns, restarts, HALT and
+// STOP. None of the 0xCB opcodes branch.
+var branches = [256]bool{
+	// STOP and HALT
+	0x10: true, 0x76: true,
+	// JR
+	0x18: true, 0x20: true, 0x28: true, 0x30: true, 0x38: true,
+	// JP
+	0xC2: true, 0xC3: true, 0xCA: true, 0xD2: true, 0xDA: true, 0xE9: true,
+	// CALL
+	0xC4: true, 0xCC: true, 0xCD: true, 0xD4: true, 0xDC: true,
+	// RET and RETI
+	0xC0: true, 0xC8: true, 0xC9: true, 0xD0: true, 0xD8: true, 0xD9: true,
+	// RST
+	0xC7: true, 0xCF: true, 0xD7: true, 0xDF: true, 0xE7: true, 0xEF: true, 0xF7: true, 0xFF: true,
+}
+
+// CBOpcodes describes the opcodes following the 0xCB prefix
+var CBOpcodes = cbOpcodes()
+
+func cbOpcodes() [256]Opcode {
+	var opcodes [256]Opcode
+	operands := [8]string{"B", "C", "D", "E", "H", "L", "(HL)", "A"}
+	shifts := [8]string{"RLC", "RRC", "RL", "RR", "SLA", "SRA", "SWAP", "SRL"}
+
+	for i := range opcodes {
+		operand := operands[i%8]
+		bit := i / 8 % 8
+
+		var mnemonic string
+		switch i / 64 {
+		case 0:
+			mnemonic = shifts[i/8] + " " + operand
+		case 1:
+			mnemonic = "BIT " + string(rune('0'+bit)) + "," + operand
+		case 2:
+			mnemonic = "RES " + string(rune('0'+bit)) + "," + operand
+		case 3:
+			mnemonic = "SET " + string(rune('0'+bit)) + "," + operand
+		}
+
+		cycles := 8
+		if operand == "(HL)" {
+			cycles = 16
+			if i/64 == 1 {
+				// BIT only reads (HL)
+				cycles = 12
+			}
+		}
+		opcodes[i] = Opcode{mnemonic, 2, cycles, cycles}
+	}
+	return opcodes
+}
@@ -0,0 +1,45 @@
+package cpu
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/model"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// registers A, F, B, C, D, E, H and L as each boot ROM leaves them
+var postBootRegisters = map[model.Model][8]types.Byte{
+	model.DMG0: {0x01, 0x00, 0xFF, 0x13, 0x00, 0xC1, 0x84, 0x03},
+	model.DMG:  {0x01, 0xB0, 0x00, 0x13, 0x00, 0xD8, 0x01, 0x4D},
+	model.MGB:  {0xFF, 0xB0, 0x00, 0x13, 0x00, 0xD8, 0x01, 0x4D},
+	model.SGB:  {0x01, 0x00, 0x00, 0x14, 0x00, 0x00, 0xC0, 0x60},
+	model.CGB:  {0x11, 0x80, 0x00, 0x00, 0xFF, 0x56, 0x00, 0x0D},
+}
+
+// the CGB boot ROM leaves other values behind when it starts a DMG game
+var postBootCGBForDMG = [8]types.Byte{0x11, 0x80, 0x00, 0x00, 0x00, 0x08, 0x00, 0x7C}
+
+// SkipBootROM starts the machine where the boot ROM of its model would
+// leave it, at the cartridge entry point. Games looking at A, or at the
+// other registers, to tell the models apart see the expected values.
+func (c *CPU) SkipBootROM() {
+	m := c.Model()
+	values := postBootRegisters[m]
+	if m == model.CGB && !c.mmu.CGB() {
+		values = postBootCGBForDMG
+	}
+	cart := c.mmu.Cartridge
+	if (m == model.DMG || m == model.MGB) && cart != nil && cart.ROM[0x14D] == 0 {
+		// H and C come out of the header checksum computation
+		values[1] = 0x80
+	}
+
+	r := &c.registers
+	for i, reg := range []*types.ByteRegister{&r.A, &r.F, &r.B, &r.C, &r.D, &r.E, &r.H, &r.L} {
+		reg.Set(values[i])
+	}
+	r.SP.Set(0xFFFE)
+	r.PC.Set(0x0100)
+	c.ime = false
+	c.halted = false
+	c.mmu.PostBoot(m)
+	c.FlushBlocks()
+}
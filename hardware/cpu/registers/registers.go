@@ -1,7 +1,6 @@
 package registers
 
 import (
-	"log"
 	"github.com/cgimenes/gomenes-boy/hardware/types"
 )
 
@@ -41,61 +40,46 @@ type Flags struct {
 	R *types.ByteRegister
 }
 
+// Flag is one of the 4 flags kept in the upper nibble of F
+type Flag int
+
 //flags
 const (
-	_ = iota
+	_ Flag = iota
 	C
 	H
 	N
 	Z
 )
 
-func (f *Flags) Reset(flag int) {
-	switch flag {
-	case Z:
-		f.R.Set(types.ResetBit(7, f.R.Get()))
-	case N:
-		f.R.Set(types.ResetBit(6, f.R.Get()))
-	case H:
-		f.R.Set(types.ResetBit(5, f.R.Get()))
-	case C:
-		f.R.Set(types.ResetBit(4, f.R.Get()))
-	default:
-		log.Fatalf("Unknown flag %c", flag)
+// bit returns the bit of F holding the flag. Values other than the
+// constants above hold no flag.
+func (flag Flag) bit() (byte, bool) {
+	if flag < C || flag > Z {
+		return 0, false
+	}
+	return byte(flag) + 3, true
+}
+
+func (f *Flags) Reset(flag Flag) {
+	if bit, ok := flag.bit(); ok {
+		f.R.Set(types.ResetBit(bit, f.R.Get()))
 	}
 	f.R.Set(0xF0 & f.R.Get())
 }
 
-func (f *Flags) Set(flag int) {
-	switch flag {
-	case Z:
-		f.R.Set(types.SetBit(7, f.R.Get()))
-	case N:
-		f.R.Set(types.SetBit(6, f.R.Get()))
-	case H:
-		f.R.Set(types.SetBit(5, f.R.Get()))
-	case C:
-		f.R.Set(types.SetBit(4, f.R.Get()))
-	default:
-		log.Fatalf("Unknown flag %c", flag)
+func (f *Flags) Set(flag Flag) {
+	if bit, ok := flag.bit(); ok {
+		f.R.Set(types.SetBit(bit, f.R.Get()))
 	}
 	f.R.Set(0xF0 & f.R.Get())
 }
 
-func (f Flags) Get(flag int) types.Byte {
-	switch flag {
-	case Z:
-		return types.GetBit(7, f.R.Get())
-	case N:
-		return types.GetBit(6, f.R.Get())
-	case H:
-		return types.GetBit(5, f.R.Get())
-	case C:
-		return types.GetBit(4, f.R.Get())
-	default:
-		log.Fatalf("Unknown flag %c", flag)
-		return 0
+func (f Flags) Get(flag Flag) types.Byte {
+	if bit, ok := flag.bit(); ok {
+		return types.GetBit(bit, f.R.Get())
 	}
+	return 0
 }
 
 type Registers struct {
@@ -0,0 +1,31 @@
+package registers
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/state"
+)
+
+func (r *Registers) SaveState(e *state.Encoder) {
+	e.Byte(r.A.Get())
+	e.Byte(r.F.Get())
+	e.Byte(r.B.Get())
+	e.Byte(r.C.Get())
+	e.Byte(r.D.Get())
+	e.Byte(r.E.Get())
+	e.Byte(r.H.Get())
+	e.Byte(r.L.Get())
+	e.Word(r.SP.Get())
+	e.Word(r.PC.Get())
+}
+
+func (r *Registers) LoadState(d *state.Decoder) {
+	r.A.Set(d.Byte())
+	r.F.Set(d.Byte())
+	r.B.Set(d.Byte())
+	r.C.Set(d.Byte())
+	r.D.Set(d.Byte())
+	r.E.Set(d.Byte())
+	r.H.Set(d.Byte())
+	r.L.Set(d.Byte())
+	r.SP.Set(d.Word())
+	r.PC.Set(d.Word())
+}
@@ -0,0 +1,220 @@
+package cpu
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// flatBus is 64KB of RAM with nothing mapped on it
+type flatBus struct {
+	memory  [0x10000]types.Byte
+	touched []types.Word
+}
+
+func (b *flatBus) Get(address types.Word) types.Byte {
+	return b.memory[address]
+}
+
+func (b *flatBus) Set(address types.Word, value types.Byte) {
+	b.memory[address] = value
+	b.touched = append(b.touched, address)
+}
+
+func (b *flatBus) Peek(address types.Word) types.Byte {
+	return b.memory[address]
+}
+
+func (b *flatBus) Tick(cycles int) {
+}
+
+// reset zeroes whatever the last case wrote, which is much cheaper than
+// clearing the whole address space
+func (b *flatBus) reset() {
+	for _, address := range b.touched {
+		b.memory[address] = 0
+	}
+	b.touched = b.touched[:0]
+}
+
+// singleStepState is a machine state in the format of the SM83 single step
+// tests
+type singleStepState struct {
+	PC, SP                 types.Word
+	A, B, C, D, E, F, H, L types.Byte
+	IME                    types.Byte
+	IE                     *types.Byte
+	RAM                    [][2]uint16
+}
+
+type singleStepCase struct {
+	Name    string
+	Initial singleStepState
+	Final   singleStepState
+	// one entry per machine cycle
+	Cycles []json.RawMessage
+}
+
+// singleStepFiles lists the fixture of every opcode: all but the illegal
+// ones, STOP, HALT and the CB prefix itself
+func singleStepFiles() []string {
+	var files []string
+	for i, op := range Opcodes {
+		if op.Illegal() || i == 0x10 || i == 0x76 || i == 0xCB {
+			continue
+		}
+		files = append(files, fmt.Sprintf("%02x.json", i))
+	}
+	for i := range CBOpcodes {
+		files = append(files, fmt.Sprintf("cb %02x.json", i))
+	}
+	return files
+}
+
+// TestSingleStep runs the SM83 single step tests in testdata/sm83, one JSON
+// file per opcode. A missing file fails its opcode.
+func TestSingleStep(t *testing.T) {
+	files := singleStepFiles()
+	for _, file := range files {
+		t.Run(strings.TrimSuffix(file, ".json"), func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "sm83", file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var cases []singleStepCase
+			if err := json.Unmarshal(data, &cases); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, blockCache := range []bool{false, true} {
+				name := "interpreter"
+				if blockCache {
+					name = "blocks"
+				}
+				t.Run(name, func(t *testing.T) {
+					bus := &flatBus{}
+					c := &CPU{}
+					c.Init()
+					c.ConnectBus(bus)
+					c.EnableBlockCache(blockCache)
+
+					failed := 0
+					for _, tc := range cases {
+						diffs := runSingleStep(c, bus, tc)
+						if len(diffs) == 0 {
+							continue
+						}
+						failed++
+						if failed <= 3 {
+							t.Errorf("%s:\n\t%s", tc.Name, strings.Join(diffs, "\n\t"))
+						}
+					}
+					if failed > 0 {
+						t.Errorf("%d/%d cases failed", failed, len(cases))
+					}
+				})
+			}
+		})
+	}
+}
+
+// runSingleStep executes the instruction of tc and lists how the outcome
+// differs from the expected one
+func runSingleStep(c *CPU, bus *flatBus, tc singleStepCase) (diffs []string) {
+	bus.reset()
+	resetCPU(c)
+	setSingleStepState(c, bus, tc.Initial)
+	// the RAM was filled behind the CPU's back
+	c.FlushBlocks()
+
+	cycles, err := c.Step()
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	want := tc.Final
+	got := getSingleStepState(c)
+	bytes := []struct {
+		name      string
+		got, want types.Byte
+	}{
+		{"A", got.A, want.A}, {"F", got.F, want.F}, {"B", got.B, want.B}, {"C", got.C, want.C},
+		{"D", got.D, want.D}, {"E", got.E, want.E}, {"H", got.H, want.H}, {"L", got.L, want.L},
+		{"IME", got.IME, want.IME},
+	}
+	for _, r := range bytes {
+		if r.got != r.want {
+			diffs = append(diffs, fmt.Sprintf("%s = %02X, want %02X", r.name, r.got, r.want))
+		}
+	}
+	if got.SP != want.SP {
+		diffs = append(diffs, fmt.Sprintf("SP = %04X, want %04X", got.SP, want.SP))
+	}
+	if got.PC != want.PC {
+		diffs = append(diffs, fmt.Sprintf("PC = %04X, want %04X", got.PC, want.PC))
+	}
+	for _, cell := range want.RAM {
+		if value := bus.memory[cell[0]]; value != types.Byte(cell[1]) {
+			diffs = append(diffs, fmt.Sprintf("[%04X] = %02X, want %02X", cell[0], value, cell[1]))
+		}
+	}
+	if cycles != len(tc.Cycles)*4 {
+		diffs = append(diffs, fmt.Sprintf("took %d cycles, want %d", cycles, len(tc.Cycles)*4))
+	}
+	return diffs
+}
+
+// resetCPU clears whatever the last case left behind besides the registers,
+// such as an EI still pending
+func resetCPU(c *CPU) {
+	c.ime = false
+	c.eiDelay = false
+	c.halted = false
+	c.haltBug = false
+	c.locked = false
+	c.branched = false
+	c.cycles = 0
+	c.ticked = 0
+}
+
+func setSingleStepState(c *CPU, bus *flatBus, s singleStepState) {
+	r := c.Registers()
+	r.A.Set(s.A)
+	r.F.Set(s.F)
+	r.B.Set(s.B)
+	r.C.Set(s.C)
+	r.D.Set(s.D)
+	r.E.Set(s.E)
+	r.H.Set(s.H)
+	r.L.Set(s.L)
+	r.SP.Set(s.SP)
+	r.PC.Set(s.PC)
+	c.ime = s.IME == 1
+
+	if s.IE != nil {
+		bus.Set(0xFFFF, *s.IE)
+	}
+	for _, cell := range s.RAM {
+		bus.Set(cell[0], types.Byte(cell[1]))
+	}
+}
+
+func getSingleStepState(c *CPU) singleStepState {
+	r := c.Registers()
+	s := singleStepState{
+		A: r.A.Get(), F: r.F.Get(), B: r.B.Get(), C: r.C.Get(),
+		D: r.D.Get(), E: r.E.Get(), H: r.H.Get(), L: r.L.Get(),
+		SP: r.SP.Get(), PC: r.PC.Get(),
+	}
+	// the fixtures count an EI as done once it has run, while here it
+	// still waits for the start of the next instruction
+	if c.ime || c.eiDelay {
+		s.IME = 1
+	}
+	return s
+}
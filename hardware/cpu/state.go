@@ -0,0 +1,89 @@
+package cpu
+
+import (
+	"io"
+
+	"github.com/cgimenes/gomenes-boy/hardware/state"
+)
+
+// chunk ids
+const (
+	chunkCPU    = "CPU "
+	chunkMMU    = "MMU "
+	chunkSerial = "SIO "
+	chunkPPU    = "PPU "
+	chunkCart   = "CART"
+	chunkJoypad = "JOYP"
+	chunkTimer  = "TIMR"
+	chunkSGB    = "SGB "
+)
+
+// SaveState writes a snapshot of the whole machine to w
+func (c *CPU) SaveState(w io.Writer) error {
+	sw := state.NewWriter(w)
+	sw.Chunk(chunkCPU, func(e *state.Encoder) {
+		c.registers.SaveState(e)
+		e.Bool(c.ime)
+		e.Bool(c.halted)
+		e.Uint64(c.cycles)
+		e.Bool(c.locked)
+		e.Bool(c.eiDelay)
+		e.Bool(c.haltBug)
+	})
+	sw.Chunk(chunkMMU, c.mmu.SaveState)
+	sw.Chunk(chunkSerial, c.mmu.Serial.SaveState)
+	sw.Chunk(chunkPPU, c.mmu.PPU.SaveState)
+	sw.Chunk(chunkJoypad, c.mmu.Joypad.SaveState)
+	sw.Chunk(chunkTimer, c.mmu.Timer.SaveState)
+	if c.mmu.Cartridge != nil {
+		sw.Chunk(chunkCart, c.mmu.Cartridge.SaveState)
+	}
+	if c.mmu.SGB != nil {
+		sw.Chunk(chunkSGB, c.mmu.SGB.SaveState)
+	}
+	return sw.Err()
+}
+
+// LoadState restores a snapshot written by SaveState. The cartridge must be
+// inserted beforehand. Devices plugged into the machine, such as the serial
+// peripheral, are kept as they are.
+func (c *CPU) LoadState(r io.Reader) error {
+	sr, err := state.NewReader(r)
+	if err != nil {
+		return err
+	}
+	// memory is about to change behind the bus
+	defer c.FlushBlocks()
+
+	if d, ok := sr.Chunk(chunkCPU); ok {
+		c.registers.LoadState(d)
+		c.ime = d.Bool()
+		c.halted = d.Bool()
+		c.cycles = d.Uint64()
+		c.locked = d.Bool()
+		c.eiDelay = d.Bool()
+		c.haltBug = d.Bool()
+	}
+	if d, ok := sr.Chunk(chunkMMU); ok {
+		c.mmu.LoadState(d)
+	}
+	if d, ok := sr.Chunk(chunkSerial); ok {
+		c.mmu.Serial.LoadState(d)
+	}
+	if d, ok := sr.Chunk(chunkPPU); ok {
+		c.mmu.PPU.LoadState(d)
+	}
+	if d, ok := sr.Chunk(chunkJoypad); ok {
+		c.mmu.Joypad.LoadState(d)
+	}
+	if d, ok := sr.Chunk(chunkTimer); ok {
+		c.mmu.Timer.LoadState(d)
+	}
+	if d, ok := sr.Chunk(chunkCart); ok && c.mmu.Cartridge != nil {
+		c.mmu.Cartridge.LoadState(d)
+	}
+	if d, ok := sr.Chunk(chunkSGB); ok && c.mmu.SGB != nil {
+		c.mmu.SGB.LoadState(d)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// stepProgram is a loop mixing loads, ALU, memory and prefixed opcodes
+var stepProgram = []byte{
+	0x06, 0x12, // LD B,$12
+	0x04,             // INC B
+	0x0D,             // DEC C
+	0x80,             // ADD A,B
+	0x21, 0x00, 0xC0, // LD HL,$C000
+	0x77,       // LD (HL),A
+	0x7E,       // LD A,(HL)
+	0xCB, 0x37, // SWAP A
+	0xCB, 0x7F, // BIT 7,A
+	0x20, 0x00, // JR NZ,+0
+	0xC3, 0x00, 0x01, // JP $0100
+}
+
+// ramBus is a flatBus that does not keep track of writes
+type ramBus [0x10000]types.Byte
+
+func (b *ramBus) Get(address types.Word) types.Byte        { return b[address] }
+func (b *ramBus) Set(address types.Word, value types.Byte) { b[address] = value }
+func (b *ramBus) Peek(address types.Word) types.Byte       { return b[address] }
+func (b *ramBus) Tick(cycles int)                          {}
+
+func newStepCPU() *CPU {
+	bus := &ramBus{}
+	copy(bus[0x100:], stepProgram)
+	c := &CPU{}
+	c.Init()
+	c.ConnectBus(bus)
+	c.registers.PC.Set(0x100)
+	c.registers.SP.Set(0xFFFE)
+	return c
+}
+
+func TestStepDoesNotAllocate(t *testing.T) {
+	c := newStepCPU()
+	allocs := testing.AllocsPerRun(1000, func() {
+		if _, err := c.Step(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("Step allocates %v times per instruction", allocs)
+	}
+}
+
+// BenchmarkStep measures raw instruction throughput, with nothing on the bus
+func BenchmarkStep(b *testing.B) {
+	c := newStepCPU()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := c.Step(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(c.Cycles())/b.Elapsed().Seconds(), "cycles/s")
+}
@@ -0,0 +1,98 @@
+package joypad
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// Address of the P1 register
+const Address types.Word = 0xFF00
+
+// Interrupt is the bit of IF raised when a button goes down
+const Interrupt = 4
+
+type Button int
+
+// buttons, in the order of their bits in P1
+const (
+	Right Button = iota
+	Left
+	Up
+	Down
+	A
+	B
+	Select
+	Start
+)
+
+type Joypad struct {
+	// P1 only keeps the line select bits 4 and 5
+	P1 types.ByteRegister
+
+	pressed [8]bool
+}
+
+func (j *Joypad) Read() types.Byte {
+	p1 := j.P1.Get()
+	lines := types.Byte(0x0F)
+	if types.GetBit(4, p1) == 0 {
+		lines &= j.lines(Right)
+	}
+	if types.GetBit(5, p1) == 0 {
+		lines &= j.lines(A)
+	}
+	return 0xC0 | p1&0x30 | lines
+}
+
+// lines returns the low nibble for the group of 4 buttons starting at first,
+// with pressed buttons pulled low
+func (j *Joypad) lines(first Button) types.Byte {
+	lines := types.Byte(0x0F)
+	for i := 0; i < 4; i++ {
+		if j.pressed[int(first)+i] {
+			lines = types.ResetBit(byte(i), lines)
+		}
+	}
+	return lines
+}
+
+func (j *Joypad) Write(value types.Byte) {
+	j.P1.Set(value & 0x30)
+}
+
+// Press holds a button down and reports whether the joypad interrupt must
+// be raised
+func (j *Joypad) Press(b Button) bool {
+	before := j.Read()
+	j.pressed[b] = true
+	// the interrupt fires on a selected line going from high to low
+	return before&^j.Read()&0x0F != 0
+}
+
+func (j *Joypad) Release(b Button) {
+	j.pressed[b] = false
+}
+
+func (j *Joypad) Pressed(b Button) bool {
+	return j.pressed[b]
+}
+
+// Buttons returns the buttons held down, a bit per Button
+func (j *Joypad) Buttons() types.Byte {
+	var buttons types.Byte
+	for b, pressed := range j.pressed {
+		if pressed {
+			buttons = types.SetBit(byte(b), buttons)
+		}
+	}
+	return buttons
+}
+
+// SetButtons holds down exactly the buttons set in buttons and reports
+// whether the joypad interrupt must be raised
+func (j *Joypad) SetButtons(buttons types.Byte) bool {
+	before := j.Read()
+	for b := range j.pressed {
+		j.pressed[b] = types.GetBit(byte(b), buttons) == 1
+	}
+	return before&^j.Read()&0x0F != 0
+}
@@ -0,0 +1,19 @@
+package joypad
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/state"
+)
+
+func (j *Joypad) SaveState(e *state.Encoder) {
+	e.Byte(j.P1.Get())
+	for _, pressed := range j.pressed {
+		e.Bool(pressed)
+	}
+}
+
+func (j *Joypad) LoadState(d *state.Decoder) {
+	j.P1.Set(d.Byte())
+	for i := range j.pressed {
+		j.pressed[i] = d.Bool()
+	}
+}
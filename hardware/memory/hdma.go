@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// I/O addresses of the CGB VRAM DMA
+const (
+	HDMA1Address types.Word = 0xFF51
+	HDMA2Address types.Word = 0xFF52
+	HDMA3Address types.Word = 0xFF53
+	HDMA4Address types.Word = 0xFF54
+	HDMA5Address types.Word = 0xFF55
+)
+
+// CPU cycles the CPU is held for every 16 bytes copied, twice as many in
+// double speed
+const hdmaBlockCycles = 32
+
+// hdma copies blocks of 16 bytes into VRAM, either all at once (general
+// purpose) or one block per HBlank
+type hdma struct {
+	source      types.Word
+	destination types.Word
+	// blocks left to copy minus one, as HDMA5 reads it
+	length types.Byte
+	// an HBlank transfer is in progress
+	active bool
+}
+
+func isHDMARegister(address types.Word) bool {
+	return address >= HDMA1Address && address <= HDMA5Address
+}
+
+func (r *MMU) readHDMA(address types.Word) types.Byte {
+	if address != HDMA5Address {
+		// the source and destination are write only
+		return 0xFF
+	}
+	if r.hdma.active {
+		return r.hdma.length
+	}
+	return 0x80 | r.hdma.length
+}
+
+func (r *MMU) writeHDMA(address types.Word, value types.Byte) {
+	h := &r.hdma
+	switch address {
+	case HDMA1Address:
+		h.source = types.Word(value)<<8 | h.source&0x00FF
+	case HDMA2Address:
+		h.source = h.source&0xFF00 | types.Word(value&0xF0)
+	case HDMA3Address:
+		h.destination = types.Word(value&0x1F)<<8 | h.destination&0x00FF
+	case HDMA4Address:
+		h.destination = h.destination&0xFF00 | types.Word(value&0xF0)
+	default:
+		if h.active && types.GetBit(7, value) == 0 {
+			// cancels the HBlank transfer, the length left can be read back
+			h.active = false
+			return
+		}
+		h.length = value & 0x7F
+		if types.GetBit(7, value) == 0 {
+			for r.hdmaBlock() {
+			}
+			return
+		}
+		h.active = true
+		if !r.PPU.Enabled() || r.PPU.Mode() == ppu.ModeHBlank {
+			// no HBlank is coming, or one is already going on
+			r.hdmaBlock()
+		}
+	}
+}
+
+// hdmaBlock copies the next 16 bytes into the VRAM bank selected with VBK
+// and reports whether there are blocks left
+func (r *MMU) hdmaBlock() bool {
+	h := &r.hdma
+	for i := 0; i < 0x10; i++ {
+		r.PPU.WriteVRAM(0x8000|h.destination, r.Peek(h.source))
+		h.source++
+		h.destination = (h.destination + 1) & 0x1FFF
+	}
+
+	stall := hdmaBlockCycles
+	if r.doubleSpeed {
+		stall *= 2
+	}
+	r.stall += stall
+
+	h.length--
+	if h.length == 0xFF {
+		// done, HDMA5 reads 0xFF from now on
+		h.length = 0x7F
+		h.active = false
+		return false
+	}
+	return true
+}
+
+// TakeStall returns the cycles the CPU has to wait for DMA transfers started
+// since the last call
+func (r *MMU) TakeStall() int {
+	stall := r.stall
+	r.stall = 0
+	return stall
+}
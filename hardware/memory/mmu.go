@@ -1,25 +1,386 @@
 package memory
 
 import (
+	"fmt"
+
+	"github.com/cgimenes/gomenes-boy/hardware/cartridge"
+	"github.com/cgimenes/gomenes-boy/hardware/joypad"
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+	"github.com/cgimenes/gomenes-boy/hardware/serial"
+	"github.com/cgimenes/gomenes-boy/hardware/sgb"
+	"github.com/cgimenes/gomenes-boy/hardware/timer"
 	"github.com/cgimenes/gomenes-boy/hardware/types"
 )
 
+// interrupt registers
+const (
+	IF types.Word = 0xFF0F
+	IE types.Word = 0xFFFF
+)
+
+// writing anything but zero here unmaps the boot ROM
+const BootROMDisableAddress types.Word = 0xFF50
+
+// CGB only registers
+const (
+	// KEY1 arms a speed switch, carried out by STOP
+	KEY1Address types.Word = 0xFF4D
+	// SVBK selects the WRAM bank at D000-DFFF
+	SVBKAddress types.Word = 0xFF70
+)
+
+// Watcher is told about every access made through Get and Set while it is
+// installed. Leaving it nil costs nothing.
+type Watcher interface {
+	Read(address types.Word, value types.Byte)
+	Write(address types.Word, value types.Byte)
+}
+
+// Patcher can change what cartridge ROM reads return, for cheat devices
+// sitting between the cartridge and the console
+type Patcher interface {
+	Patch(address types.Word, value types.Byte) types.Byte
+}
+
 type MMU struct {
 	addresses [100000]types.Byte
+
+	Joypad    joypad.Joypad
+	Serial    serial.Port
+	Timer     timer.Timer
+	PPU       ppu.PPU
+	Cartridge *cartridge.Cartridge
+	Watcher   Watcher
+	Patcher   Patcher
+	// SGB listens to the joypad register when the game runs on a Super
+	// Game Boy
+	SGB *sgb.SGB
+
+	bootROMDisabled bool
+	// boot ROM image loaded from a file, the embedded DMG one when nil
+	bootROM []types.Byte
+
+	cgb bool
+	// WRAM banks 2 to 7 of the CGB, bank 1 lives in addresses
+	wramBanks   [6][0x1000]types.Byte
+	svbk        types.Byte
+	speedArmed  bool
+	doubleSpeed bool
+	hdma        hdma
+	oamDMA      oamDMA
+	// cycles the CPU owes to DMA transfers
+	stall int
+}
+
+// SetCGB switches the Game Boy Color hardware on or off
+func (r *MMU) SetCGB(on bool) {
+	r.cgb = on
+	r.PPU.SetCGB(on)
+	// no transfer done, HDMA5 reads 0xFF
+	r.hdma.length = 0x7F
+}
+
+// CGB reports whether the machine runs as a Game Boy Color
+func (r *MMU) CGB() bool {
+	return r.cgb
+}
+
+// DoubleSpeed reports whether the CGB runs the CPU at twice the clock
+func (r *MMU) DoubleSpeed() bool {
+	return r.doubleSpeed
+}
+
+// SwitchSpeed carries out a speed switch armed through KEY1 and reports
+// whether there was one. STOP calls it.
+// @todo the switch takes 2050 M-cycles, during which the CPU is stopped
+func (r *MMU) SwitchSpeed() bool {
+	if !r.cgb || !r.speedArmed {
+		return false
+	}
+	r.speedArmed = false
+	r.doubleSpeed = !r.doubleSpeed
+	return true
+}
+
+// wram returns where a WRAM address, or its echo, is stored
+func (r *MMU) wram(address types.Word) *types.Byte {
+	if address >= 0xE000 {
+		address -= 0x2000
+	}
+	if bank := r.WRAMBank(); address >= 0xD000 && bank > 1 {
+		return &r.wramBanks[bank-2][address-0xD000]
+	}
+	return &r.addresses[address]
+}
+
+func (r *MMU) readCGB(address types.Word) types.Byte {
+	switch {
+	case address == KEY1Address:
+		value := types.Byte(0x7E)
+		if r.doubleSpeed {
+			value |= 0x80
+		}
+		if r.speedArmed {
+			value |= 0x01
+		}
+		return value
+	case address == SVBKAddress:
+		return 0xF8 | r.svbk
+	case isHDMARegister(address):
+		return r.readHDMA(address)
+	default:
+		return r.PPU.Read(address)
+	}
+}
+
+func (r *MMU) writeCGB(address types.Word, value types.Byte) {
+	switch {
+	case address == KEY1Address:
+		r.speedArmed = types.GetBit(0, value) == 1
+	case address == SVBKAddress:
+		r.svbk = value & 0x07
+	case isHDMARegister(address):
+		r.writeHDMA(address, value)
+	default:
+		r.PPU.Write(address, value)
+	}
+}
+
+// isCGBRegister reports whether address is a register only the CGB has. On
+// DMG those addresses are plain memory.
+func (r *MMU) isCGBRegister(address types.Word) bool {
+	return r.cgb && (address == KEY1Address || address == SVBKAddress || isHDMARegister(address) || ppu.IsCGBRegister(address))
 }
 
 func (r *MMU) Get(address types.Word) types.Byte {
-	if address <= 0x100 {
+	if r.oamDMABlocks(address) {
+		return 0xFF
+	}
+	value := r.Peek(address)
+	if r.Watcher != nil {
+		r.Watcher.Read(address, value)
+	}
+	return value
+}
+
+// Peek reads a byte without notifying the watcher
+func (r *MMU) Peek(address types.Word) types.Byte {
+	if r.bootROMMapped(address) {
+		if r.bootROM != nil {
+			return r.bootROM[address]
+		}
 		return BootROM[address]
+	} else if address < 0x8000 || (address >= 0xA000 && address < 0xC000) {
+		if r.Cartridge == nil {
+			return 0xFF
+		}
+		value := r.Cartridge.Read(address)
+		if r.Patcher != nil && address < 0x8000 {
+			value = r.Patcher.Patch(address, value)
+		}
+		return value
+	} else if address < 0xA000 {
+		return r.PPU.ReadVRAM(address)
+	} else if address >= 0xC000 && address < 0xFE00 {
+		// WRAM and its echo
+		return *r.wram(address)
+	} else if address >= 0xFE00 && address < 0xFEA0 {
+		return r.PPU.OAM[address-0xFE00]
+	} else if address == joypad.Address {
+		if r.SGB != nil {
+			return r.SGB.ReadP1(r.Joypad.Read())
+		}
+		return r.Joypad.Read()
+	} else if address == serial.SBAddress || address == serial.SCAddress {
+		return r.Serial.Read(address)
+	} else if address >= timer.DIVAddress && address <= timer.TACAddress {
+		return r.Timer.Read(address)
+	} else if address >= ppu.LCDCAddress && address <= ppu.WXAddress {
+		return r.PPU.Read(address)
+	} else if r.isCGBRegister(address) {
+		return r.readCGB(address)
+	} else if address == IF {
+		// the top 3 bits are not wired and read back as 1
+		return r.addresses[IF] | 0xE0
 	} else {
 		return r.addresses[address]
 	}
 }
 
 func (r *MMU) Set(address types.Word, value types.Byte) {
-	if address <= 0x100 {
-		BootROM[address] = value
+	if r.oamDMABlocks(address) {
+		return
+	}
+	if r.Watcher != nil {
+		r.Watcher.Write(address, value)
+	}
+
+	if address < 0x8000 || (address >= 0xA000 && address < 0xC000) {
+		if r.Cartridge != nil {
+			r.Cartridge.Write(address, value)
+		}
+	} else if address < 0xA000 {
+		r.PPU.WriteVRAM(address, value)
+	} else if address >= 0xC000 && address < 0xFE00 {
+		*r.wram(address) = value
+	} else if address >= 0xFE00 && address < 0xFEA0 {
+		r.PPU.OAM[address-0xFE00] = value
+	} else if address == joypad.Address {
+		r.Joypad.Write(value)
+		if r.SGB != nil {
+			r.SGB.WriteP1(value)
+		}
+	} else if address == serial.SBAddress || address == serial.SCAddress {
+		if r.Serial.Write(address, value) {
+			r.RequestInterrupt(serial.Interrupt)
+		}
+	} else if address >= timer.DIVAddress && address <= timer.TACAddress {
+		if r.Timer.Write(address, value) {
+			r.RequestInterrupt(timer.Interrupt)
+		}
+	} else if address == ppu.DMAAddress {
+		r.PPU.Write(address, value)
+		r.startOAMDMA(value)
+	} else if address >= ppu.LCDCAddress && address <= ppu.WXAddress {
+		r.PPU.Write(address, value)
+	} else if r.isCGBRegister(address) {
+		r.writeCGB(address, value)
+	} else if address == BootROMDisableAddress {
+		r.bootROMDisabled = r.bootROMDisabled || value != 0
 	} else {
 		r.addresses[address] = value
 	}
-}
\ No newline at end of file
+}
+
+
+// LoadBootROM replaces the embedded DMG boot ROM with rom, either 256 bytes
+// or the 2304 of the CGB, whose 0100-01FF gap leaves the cartridge header
+// visible
+func (r *MMU) LoadBootROM(rom []types.Byte) error {
+	if len(rom) != 0x100 && len(rom) != 0x900 {
+		return fmt.Errorf("memory: a boot ROM is 256 or 2304 bytes, not %d", len(rom))
+	}
+	r.bootROM = append([]types.Byte(nil), rom...)
+	return nil
+}
+
+func (r *MMU) bootROMMapped(address types.Word) bool {
+	if r.bootROMDisabled {
+		return false
+	}
+	return address < 0x100 || (len(r.bootROM) == 0x900 && address >= 0x200 && address < 0x900)
+}
+
+// BootROMDisabled reports whether the boot ROM has handed over to the
+// cartridge
+func (r *MMU) BootROMDisabled() bool {
+	return r.bootROMDisabled
+}
+
+// WRAMBank returns the WRAM bank mapped at D000-DFFF, always 1 on the DMG
+func (r *MMU) WRAMBank() int {
+	if !r.cgb || r.svbk == 0 {
+		return 1
+	}
+	return int(r.svbk)
+}
+
+// Banks lists the banks that can be mapped at address: those of the
+// cartridge RAM at A000-BFFF, none without any, and those of WRAM at
+// D000-DFFF. Anywhere else it is the single bank 0.
+func (r *MMU) Banks(address types.Word) []int {
+	var n, first int
+	switch {
+	case address >= 0xA000 && address < 0xC000:
+		if r.Cartridge != nil {
+			n = r.Cartridge.RAMBanks()
+		}
+	case address >= 0xD000 && address < 0xE000:
+		n, first = 1, 1
+		if r.cgb {
+			n = 7
+		}
+	default:
+		n = 1
+	}
+	banks := make([]int, n)
+	for i := range banks {
+		banks[i] = first + i
+	}
+	return banks
+}
+
+// PeekBank reads address as Peek does, but from the given bank of the
+// cartridge RAM or WRAM whatever is mapped. Cartridge RAM is read even while
+// it is disabled.
+func (r *MMU) PeekBank(bank int, address types.Word) types.Byte {
+	switch {
+	case address >= 0xA000 && address < 0xC000:
+		if r.Cartridge == nil {
+			return 0xFF
+		}
+		return r.Cartridge.PeekRAM(bank, address)
+	case address >= 0xD000 && address < 0xE000 && bank > 1:
+		return r.wramBanks[bank-2][address-0xD000]
+	case address >= 0xD000 && address < 0xE000:
+		return r.addresses[address]
+	}
+	return r.Peek(address)
+}
+
+// Bank tells apart what can be mapped at the same address, for caches of
+// decoded code. It returns false for cartridge RAM, echo RAM and I/O, which
+// are not worth caching.
+func (r *MMU) Bank(address types.Word) (int, bool) {
+	switch {
+	case r.oamDMABlocks(address):
+		// the CPU does not see the code there until the transfer ends
+		return 0, false
+	case r.bootROMMapped(address):
+		return -1, true
+	case address < 0x8000:
+		if r.Cartridge == nil {
+			return 0, true
+		}
+		return r.Cartridge.ROMBank(address), true
+	case address < 0xA000:
+		// HDMA writes VRAM without going through the bus
+		return r.PPU.VRAMBank(), false
+	case address < 0xC000:
+		return 0, false
+	case address < 0xD000:
+		return 0, true
+	case address < 0xE000:
+		return r.WRAMBank(), true
+	case address < 0xFF80:
+		return 0, false
+	default:
+		return 0, true
+	}
+}
+
+func (r *MMU) RequestInterrupt(bit byte) {
+	r.addresses[IF] = types.SetBit(bit, r.addresses[IF])
+}
+
+// Tick advances the devices on the bus by the given number of cycles
+func (r *MMU) Tick(cycles int) {
+	// in double speed the PPU keeps its pace while the CPU and timer run
+	// twice as fast
+	ppuCycles := cycles
+	if r.doubleSpeed {
+		ppuCycles /= 2
+	}
+	interrupts := r.PPU.Tick(ppuCycles)
+	r.addresses[IF] |= interrupts
+	if r.SGB != nil && types.GetBit(ppu.VBlankInterrupt, interrupts) == 1 {
+		r.SGB.VBlank(&r.PPU)
+	}
+	if r.hdma.active && r.PPU.EnteredHBlank() {
+		r.hdmaBlock()
+	}
+	if r.Timer.Tick(cycles) {
+		r.RequestInterrupt(timer.Interrupt)
+	}
+	r.tickOAMDMA(cycles)
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// M-cycles between the write to DMA and the first byte copied
+const oamDMADelay = 2
+
+// oamDMA copies 160 bytes into OAM, one per M-cycle. While it runs the CPU
+// cannot reach OAM nor the bus the bytes come from.
+type oamDMA struct {
+	// source of the transfer waiting to start, and M-cycles until it does
+	pending types.Word
+	delay   int
+	source  types.Word
+	// bytes copied so far
+	index  int
+	active bool
+	// cycles towards the next M-cycle
+	cycles int
+}
+
+// startOAMDMA schedules a transfer from value*0x100. A transfer already
+// running goes on until the new one starts.
+func (r *MMU) startOAMDMA(value types.Byte) {
+	source := types.Word(value) << 8
+	if source >= 0xE000 {
+		// the top pages are not reachable, their echo of WRAM is read
+		source -= 0x2000
+	}
+	r.oamDMA.pending = source
+	r.oamDMA.delay = oamDMADelay
+}
+
+// tickOAMDMA advances the transfer by the given number of cycles
+func (r *MMU) tickOAMDMA(cycles int) {
+	d := &r.oamDMA
+	if !d.active && d.delay == 0 {
+		return
+	}
+	for d.cycles += cycles; d.cycles >= 4; d.cycles -= 4 {
+		if d.delay > 0 {
+			d.delay--
+			if d.delay == 0 {
+				d.source, d.index, d.active = d.pending, 0, true
+			}
+		}
+		if !d.active {
+			continue
+		}
+		if d.index == len(r.PPU.OAM) {
+			d.active = false
+			continue
+		}
+		r.PPU.OAM[d.index] = r.Peek(d.source + types.Word(d.index))
+		d.index++
+	}
+	if !d.active && d.delay == 0 {
+		d.cycles = 0
+	}
+}
+
+// oamDMABlocks reports whether a running transfer keeps the CPU from
+// address: OAM, and whatever shares the bus the transfer reads from, VRAM
+// or the external one
+// @todo on a conflict the CPU actually sees the byte being copied
+func (r *MMU) oamDMABlocks(address types.Word) bool {
+	if !r.oamDMA.active || address >= 0xFF00 {
+		return false
+	}
+	if address >= 0xFE00 {
+		return true
+	}
+	return vramBus(address) == vramBus(r.oamDMA.source)
+}
+
+func vramBus(address types.Word) bool {
+	return address >= 0x8000 && address < 0xA000
+}
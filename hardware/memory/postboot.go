@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/joypad"
+	"github.com/cgimenes/gomenes-boy/hardware/model"
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+type ioValue struct {
+	address types.Word
+	value   types.Byte
+}
+
+// I/O registers as the DMG boot ROM leaves them, the sound ones included
+var dmgIO = []ioValue{
+	{0xFF00, 0xCF}, {0xFF01, 0x00}, {0xFF02, 0x7E},
+	{0xFF05, 0x00}, {0xFF06, 0x00}, {0xFF07, 0xF8}, {0xFF0F, 0xE1},
+	{0xFF10, 0x80}, {0xFF11, 0xBF}, {0xFF12, 0xF3}, {0xFF13, 0xFF}, {0xFF14, 0xBF},
+	{0xFF16, 0x3F}, {0xFF17, 0x00}, {0xFF18, 0xFF}, {0xFF19, 0xBF},
+	{0xFF1A, 0x7F}, {0xFF1B, 0xFF}, {0xFF1C, 0x9F}, {0xFF1D, 0xFF}, {0xFF1E, 0xBF},
+	{0xFF20, 0xFF}, {0xFF21, 0x00}, {0xFF22, 0x00}, {0xFF23, 0xBF},
+	{0xFF24, 0x77}, {0xFF25, 0xF3}, {0xFF26, 0xF1},
+	{0xFF40, 0x91}, {0xFF42, 0x00}, {0xFF43, 0x00}, {0xFF45, 0x00},
+	{0xFF46, 0xFF}, {0xFF47, 0xFC}, {0xFF48, 0xFF}, {0xFF49, 0xFF},
+	{0xFF4A, 0x00}, {0xFF4B, 0x00}, {0xFFFF, 0x00},
+}
+
+// I/O registers as the boot ROM of each model leaves them
+var postBootIO = map[model.Model][]ioValue{
+	model.DMG0: dmgIO,
+	model.DMG:  dmgIO,
+	model.MGB:  dmgIO,
+	// sound register NR52
+	model.SGB: overrideIO(dmgIO, ioValue{0xFF26, 0xF0}),
+	// SC has the clock speed bit and the last OAM DMA was never started
+	model.CGB: overrideIO(dmgIO, ioValue{0xFF02, 0x7F}, ioValue{ppu.DMAAddress, 0x00}),
+}
+
+// registers only the CGB has, set when it runs a CGB game: the single
+// speed, VRAM bank 0, WRAM bank 1 and no HDMA transfer. The palettes are
+// filled with auto-increment, which wraps the indexes back to 0.
+var cgbIO = []ioValue{
+	{KEY1Address, 0x00}, {ppu.VBKAddress, 0x00}, {SVBKAddress, 0x00},
+	{HDMA5Address, 0xFF}, {ppu.BCPSAddress, 0x80}, {ppu.OCPSAddress, 0x80},
+}
+
+// overrideIO copies io with the values in changes put in place
+func overrideIO(io []ioValue, changes ...ioValue) []ioValue {
+	out := append([]ioValue(nil), io...)
+	for _, change := range changes {
+		for i := range out {
+			if out[i].address == change.address {
+				out[i].value = change.value
+			}
+		}
+	}
+	return out
+}
+
+// counter behind DIV when the boot ROM hands over. Only DIV itself, the
+// upper byte, is documented.
+// @todo the SGB and CGB values are not documented, the DMG one is used
+var postBootCounter = map[model.Model]uint16{
+	model.DMG0: 0x1800,
+	model.DMG:  0xABCC,
+	model.MGB:  0xABCC,
+	model.SGB:  0xABCC,
+	model.CGB:  0xABCC,
+}
+
+// PostBoot sets the I/O registers and the timer to where the boot ROM of m
+// leaves them and unmaps the boot ROM
+// @todo VRAM is left empty, without the logo the boot ROM draws
+func (r *MMU) PostBoot(m model.Model) {
+	io := postBootIO[m]
+	if r.cgb {
+		io = append(io[:len(io):len(io)], cgbIO...)
+	}
+	for _, reg := range io {
+		// straight to the registers where a write would have side effects:
+		// an OAM DMA, an SGB packet, an HDMA transfer
+		switch reg.address {
+		case IF, IE:
+			r.addresses[reg.address] = reg.value
+		case joypad.Address:
+			r.Joypad.Write(reg.value)
+		case ppu.DMAAddress:
+			r.PPU.Write(reg.address, reg.value)
+		case HDMA5Address:
+			r.hdma.active = false
+			r.hdma.length = reg.value & 0x7F
+		default:
+			r.Set(reg.address, reg.value)
+		}
+	}
+	r.Timer.SetCounter(postBootCounter[m])
+	r.bootROMDisabled = true
+}
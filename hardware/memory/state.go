@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/state"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// SaveState stores the whole address space. Until the APU registers
+// move into their own device they live here, in the I/O area.
+func (r *MMU) SaveState(e *state.Encoder) {
+	e.Bytes(r.addresses[:0x10000])
+	// kept so states from when the boot ROM was writable still line up
+	e.Bytes(BootROM[:])
+	e.Bool(r.bootROMDisabled)
+	e.Bool(r.cgb)
+	for i := range r.wramBanks {
+		e.Bytes(r.wramBanks[i][:])
+	}
+	e.Byte(r.svbk)
+	e.Bool(r.speedArmed)
+	e.Bool(r.doubleSpeed)
+	e.Word(r.hdma.source)
+	e.Word(r.hdma.destination)
+	e.Byte(r.hdma.length)
+	e.Bool(r.hdma.active)
+	e.Uint32(uint32(r.stall))
+	e.Word(r.oamDMA.pending)
+	e.Uint32(uint32(r.oamDMA.delay))
+	e.Word(r.oamDMA.source)
+	e.Uint32(uint32(r.oamDMA.index))
+	e.Bool(r.oamDMA.active)
+	e.Uint32(uint32(r.oamDMA.cycles))
+}
+
+func (r *MMU) LoadState(d *state.Decoder) {
+	d.Bytes(r.addresses[:0x10000])
+	// the boot ROM is fixed, its old copy is read and dropped
+	var bootROM [len(BootROM)]types.Byte
+	d.Bytes(bootROM[:])
+	r.bootROMDisabled = d.Bool()
+	r.cgb = d.Bool()
+	for i := range r.wramBanks {
+		d.Bytes(r.wramBanks[i][:])
+	}
+	r.svbk = d.Byte()
+	r.speedArmed = d.Bool()
+	r.doubleSpeed = d.Bool()
+	r.hdma.source = d.Word()
+	r.hdma.destination = d.Word()
+	r.hdma.length = d.Byte()
+	r.hdma.active = d.Bool()
+	r.stall = int(d.Uint32())
+	r.oamDMA.pending = d.Word()
+	r.oamDMA.delay = int(d.Uint32())
+	r.oamDMA.source = d.Word()
+	r.oamDMA.index = int(d.Uint32())
+	r.oamDMA.active = d.Bool()
+	r.oamDMA.cycles = int(d.Uint32())
+}
@@ -0,0 +1,78 @@
+// Package model names the Game Boy hardware revisions the emulator can be
+// told to behave as.
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cgimenes/gomenes-boy/hardware/cartridge"
+)
+
+type Model int
+
+const (
+	// Auto picks CGB for Game Boy Color games, SGB for Super Game Boy games
+	// and DMG for the rest
+	Auto Model = iota
+	// DMG0 is the early Japanese DMG, with its own boot ROM
+	DMG0
+	DMG
+	// MGB is the Game Boy Pocket
+	MGB
+	SGB
+	CGB
+)
+
+var names = map[Model]string{
+	Auto: "auto",
+	DMG0: "dmg0",
+	DMG:  "dmg",
+	MGB:  "mgb",
+	SGB:  "sgb",
+	CGB:  "cgb",
+}
+
+func (m Model) String() string {
+	if name, ok := names[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Model(%d)", int(m))
+}
+
+// Parse accepts the names printed by String, in any case
+func Parse(name string) (Model, error) {
+	for m, n := range names {
+		if strings.EqualFold(name, n) {
+			return m, nil
+		}
+	}
+	return Auto, fmt.Errorf("model: unknown model %q, want auto, dmg0, dmg, mgb, sgb or cgb", name)
+}
+
+// Resolve turns Auto into the model the cartridge is best played on. cart
+// may be nil.
+func (m Model) Resolve(cart *cartridge.Cartridge) Model {
+	if m != Auto {
+		return m
+	}
+	switch {
+	case cart == nil:
+		return DMG
+	case cart.Header.CGB():
+		return CGB
+	case cart.Header.SGB():
+		return SGB
+	default:
+		return DMG
+	}
+}
+
+// BootROMSize is the size of the model's boot ROM image. The CGB one is
+// mapped at 0000-00FF and 0200-08FF, around the cartridge header.
+func (m Model) BootROMSize() int {
+	if m == CGB {
+		return 0x900
+	}
+	return 0x100
+}
@@ -0,0 +1,108 @@
+package ppu
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// I/O addresses of the CGB only registers
+const (
+	VBKAddress  types.Word = 0xFF4F
+	BCPSAddress types.Word = 0xFF68
+	BCPDAddress types.Word = 0xFF69
+	OCPSAddress types.Word = 0xFF6A
+	OCPDAddress types.Word = 0xFF6B
+)
+
+// bits of the BG map attributes, kept in VRAM bank 1, and of the CGB sprite
+// attributes
+const (
+	attributeBank     = 3
+	attributeXFlip    = 5
+	attributeYFlip    = 6
+	attributePriority = 7
+)
+
+// IsCGBRegister reports whether the PPU answers at address in CGB mode
+func IsCGBRegister(address types.Word) bool {
+	return address == VBKAddress || (address >= BCPSAddress && address <= OCPDAddress)
+}
+
+// SetCGB switches the Game Boy Color registers and rendering on or off
+func (p *PPU) SetCGB(on bool) {
+	p.CGB = on
+	if on {
+		// stands in for the CGB boot ROM, which leaves the background white
+		for i := 0; i < len(p.BGPalettes); i += 2 {
+			p.BGPalettes[i], p.BGPalettes[i+1] = 0xFF, 0x7F
+		}
+	}
+}
+
+func (p *PPU) readCGB(address types.Word) types.Byte {
+	switch address {
+	case VBKAddress:
+		return 0xFE | p.vbk
+	case BCPSAddress:
+		return p.bcps | 0x40
+	case BCPDAddress:
+		return p.BGPalettes[p.bcps&0x3F]
+	case OCPSAddress:
+		return p.ocps | 0x40
+	default:
+		return p.OBJPalettes[p.ocps&0x3F]
+	}
+}
+
+func (p *PPU) writeCGB(address types.Word, value types.Byte) {
+	switch address {
+	case VBKAddress:
+		p.vbk = value & 0x01
+	case BCPSAddress:
+		p.bcps = value & 0xBF
+	case BCPDAddress:
+		p.BGPalettes[p.bcps&0x3F] = value
+		p.bcps = nextPaletteIndex(p.bcps)
+	case OCPSAddress:
+		p.ocps = value & 0xBF
+	default:
+		p.OBJPalettes[p.ocps&0x3F] = value
+		p.ocps = nextPaletteIndex(p.ocps)
+	}
+}
+
+// nextPaletteIndex moves BCPS or OCPS on after a data write when bit 7 asks
+// for it
+func nextPaletteIndex(spec types.Byte) types.Byte {
+	if types.GetBit(7, spec) == 0 {
+		return spec
+	}
+	return spec&0x80 | (spec+1)&0x3F
+}
+
+// VRAMBank returns the VRAM bank the CPU sees at 8000-9FFF
+func (p *PPU) VRAMBank() int {
+	return int(p.vbk)
+}
+
+// ReadVRAM reads VRAM through the bank selected with VBK
+func (p *PPU) ReadVRAM(address types.Word) types.Byte {
+	if p.vbk == 1 {
+		return p.VRAM1[address-0x8000]
+	}
+	return p.VRAM[address-0x8000]
+}
+
+func (p *PPU) WriteVRAM(address types.Word, value types.Byte) {
+	if p.vbk == 1 {
+		p.VRAM1[address-0x8000] = value
+	} else {
+		p.VRAM[address-0x8000] = value
+	}
+}
+
+// paletteColor reads colour index of palette out of BG or OBJ palette RAM,
+// where colours are stored little endian
+func paletteColor(ram *[64]types.Byte, palette, index types.Byte) Color {
+	i := int(palette&0x07)*8 + int(index)*2
+	return (Color(ram[i]) | Color(ram[i+1])<<8) & 0x7FFF
+}
@@ -0,0 +1,53 @@
+package ppu
+
+import (
+	"image"
+	"image/color"
+)
+
+// Color is a pixel in RGB555, the format of the CGB palette RAM: 5 bits
+// each of red, green and blue, starting from the lowest bit
+type Color uint16
+
+// NewColor builds a colour from 5 bit components
+func NewColor(r, g, b uint8) Color {
+	return Color(r&0x1F) | Color(g&0x1F)<<5 | Color(b&0x1F)<<10
+}
+
+// RGB expands the components to 8 bits
+func (c Color) RGB() (r, g, b uint8) {
+	expand := func(v Color) uint8 {
+		v &= 0x1F
+		return uint8(v<<3 | v>>2)
+	}
+	return expand(c), expand(c >> 5), expand(c >> 10)
+}
+
+// RGBA makes Color a color.Color
+func (c Color) RGBA() (r, g, b, a uint32) {
+	r8, g8, b8 := c.RGB()
+	return color.RGBA{r8, g8, b8, 0xFF}.RGBA()
+}
+
+// Shades maps the 4 DMG shades, from white to black, to colours
+var Shades = [4]Color{
+	NewColor(31, 31, 31),
+	NewColor(21, 21, 21),
+	NewColor(10, 10, 10),
+	NewColor(0, 0, 0),
+}
+
+// Image returns a copy of the framebuffer
+func (p *PPU) Image() *image.RGBA {
+	return ToImage(p.Framebuffer[:], Width)
+}
+
+// ToImage converts rows of width pixels to an image
+func ToImage(pixels []Color, width int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, len(pixels)/width))
+	for i, c := range pixels {
+		r, g, b := c.RGB()
+		copy(img.Pix[i*4:], []uint8{r, g, b, 0xFF})
+	}
+	return img
+}
@@ -1,11 +1,16 @@
 package ppu
 
 import (
+	"sort"
+
 	"github.com/cgimenes/gomenes-boy/hardware/types"
 )
 
 const defaultBGP types.Byte = 0x1B
 
+// CyclesPerFrame is how long the LCD takes to draw a frame, VBlank included
+const CyclesPerFrame = 70224
+
 type Tile struct {
 	Tile [16]types.Byte
 }
@@ -27,4 +32,448 @@ type Registers struct {
 	OBP1 types.ByteRegister
 	WY types.ByteRegister
 	WX types.ByteRegister
-}
\ No newline at end of file
+}
+
+const (
+	Width  = 160
+	Height = 144
+
+	dotsPerLine = 456
+	lines       = 154
+	// dots spent in OAM scan and pixel transfer
+	oamScanDots  = 80
+	transferDots = 172
+)
+
+// interrupt bits raised by the PPU
+const (
+	VBlankInterrupt = 0
+	STATInterrupt   = 1
+)
+
+// modes reported in STAT
+const (
+	ModeHBlank   types.Byte = 0
+	ModeVBlank   types.Byte = 1
+	ModeOAMScan  types.Byte = 2
+	ModeTransfer types.Byte = 3
+)
+
+// I/O addresses of the PPU registers
+const (
+	LCDCAddress types.Word = 0xFF40
+	STATAddress types.Word = 0xFF41
+	SCYAddress  types.Word = 0xFF42
+	SCXAddress  types.Word = 0xFF43
+	LYAddress   types.Word = 0xFF44
+	LYCAddress  types.Word = 0xFF45
+	DMAAddress  types.Word = 0xFF46
+	BGPAddress  types.Word = 0xFF47
+	OBP0Address types.Word = 0xFF48
+	OBP1Address types.Word = 0xFF49
+	WYAddress   types.Word = 0xFF4A
+	WXAddress   types.Word = 0xFF4B
+)
+
+type PPU struct {
+	Registers Registers
+	VRAM      [0x2000]types.Byte
+	OAM       [0xA0]types.Byte
+
+	// CGB enables the Game Boy Color registers and rendering
+	CGB bool
+	// VRAM1 is the second VRAM bank of the CGB, holding more tiles and the
+	// BG map attributes
+	VRAM1       [0x2000]types.Byte
+	BGPalettes  [64]types.Byte
+	OBJPalettes [64]types.Byte
+
+	// Framebuffer holds the colour of every pixel. DMG shades are drawn with
+	// the Shades palette.
+	Framebuffer [Width * Height]Color
+	// Frame counts the frames completed so far
+	Frame uint64
+
+	dots       int
+	windowLine int
+	statLine   bool
+	// background colour indexes and CGB attributes of the current line, for
+	// sprite priority
+	bgIndexes    [Width]types.Byte
+	bgAttributes [Width]types.Byte
+
+	// CGB registers
+	vbk  types.Byte
+	bcps types.Byte
+	ocps types.Byte
+
+	// set by the last Tick when a visible line entered HBlank
+	enteredHBlank bool
+}
+
+func (p *PPU) register(address types.Word) *types.ByteRegister {
+	switch address {
+	case LCDCAddress:
+		return &p.Registers.LCDC
+	case STATAddress:
+		return &p.Registers.STAT
+	case SCYAddress:
+		return &p.Registers.SCY
+	case SCXAddress:
+		return &p.Registers.SCX
+	case LYAddress:
+		return &p.Registers.LY
+	case LYCAddress:
+		return &p.Registers.LYC
+	case DMAAddress:
+		return &p.Registers.DMA
+	case BGPAddress:
+		return &p.Registers.BGP
+	case OBP0Address:
+		return &p.Registers.OBP0
+	case OBP1Address:
+		return &p.Registers.OBP1
+	case WYAddress:
+		return &p.Registers.WY
+	default:
+		return &p.Registers.WX
+	}
+}
+
+func (p *PPU) Read(address types.Word) types.Byte {
+	if IsCGBRegister(address) {
+		return p.readCGB(address)
+	}
+	if address == STATAddress {
+		return p.Registers.STAT.Get() | 0x80
+	}
+	return p.register(address).Get()
+}
+
+func (p *PPU) Write(address types.Word, value types.Byte) {
+	if IsCGBRegister(address) {
+		p.writeCGB(address, value)
+		return
+	}
+	switch address {
+	case STATAddress:
+		// only the interrupt selects are writable
+		p.Registers.STAT.Set(p.Registers.STAT.Get()&0x07 | value&0x78)
+	case LYAddress:
+		// read only
+	case LCDCAddress:
+		wasOn := p.enabled()
+		p.Registers.LCDC.Set(value)
+		if wasOn && !p.enabled() {
+			p.dots = 0
+			p.Registers.LY.Set(0)
+			p.setMode(ModeHBlank)
+		} else if !wasOn && p.enabled() {
+			// the frame counter kept running while off, start on a fresh line
+			p.dots = 0
+			p.setMode(ModeOAMScan)
+		}
+	default:
+		p.register(address).Set(value)
+	}
+}
+
+func (p *PPU) enabled() bool {
+	return types.GetBit(7, p.Registers.LCDC.Get()) == 1
+}
+
+// Enabled reports whether the LCD is on
+func (p *PPU) Enabled() bool {
+	return p.enabled()
+}
+
+// EnteredHBlank reports whether the last Tick started an HBlank, for the
+// CGB HBlank DMA
+func (p *PPU) EnteredHBlank() bool {
+	return p.enteredHBlank
+}
+
+func (p *PPU) Mode() types.Byte {
+	return p.Registers.STAT.Get() & 0x03
+}
+
+func (p *PPU) setMode(mode types.Byte) {
+	p.Registers.STAT.Set(p.Registers.STAT.Get()&0xFC | mode)
+}
+
+// Tick advances the PPU and returns the interrupts it requests, as IF bits
+func (p *PPU) Tick(cycles int) types.Byte {
+	var interrupts types.Byte
+	p.enteredHBlank = false
+
+	if !p.enabled() {
+		// keep frames coming so whoever waits on them is not stuck
+		p.dots += cycles
+		for p.dots >= CyclesPerFrame {
+			p.dots -= CyclesPerFrame
+			p.Frame++
+		}
+		return interrupts
+	}
+
+	for ; cycles > 0; cycles-- {
+		p.dots++
+		ly := int(p.Registers.LY.Get())
+
+		if ly < Height {
+			switch p.dots {
+			case oamScanDots:
+				p.setMode(ModeTransfer)
+				p.renderLine(ly)
+			case oamScanDots + transferDots:
+				p.setMode(ModeHBlank)
+				p.enteredHBlank = true
+			}
+		}
+
+		if p.dots == dotsPerLine {
+			p.dots = 0
+			ly = (ly + 1) % lines
+			p.Registers.LY.Set(types.Byte(ly))
+
+			switch {
+			case ly == Height:
+				p.setMode(ModeVBlank)
+				p.Frame++
+				interrupts = types.SetBit(VBlankInterrupt, interrupts)
+			case ly == 0:
+				p.windowLine = 0
+				p.setMode(ModeOAMScan)
+			case ly < Height:
+				p.setMode(ModeOAMScan)
+			}
+		}
+
+		if p.updateSTAT() {
+			interrupts = types.SetBit(STATInterrupt, interrupts)
+		}
+	}
+	return interrupts
+}
+
+// updateSTAT refreshes the LY=LYC flag and reports a rising edge of the
+// STAT interrupt line
+func (p *PPU) updateSTAT() bool {
+	stat := p.Registers.STAT.Get()
+	if p.Registers.LY.Get() == p.Registers.LYC.Get() {
+		stat = types.SetBit(2, stat)
+	} else {
+		stat = types.ResetBit(2, stat)
+	}
+	p.Registers.STAT.Set(stat)
+
+	line := (types.GetBit(6, stat) == 1 && types.GetBit(2, stat) == 1) ||
+		(types.GetBit(5, stat) == 1 && p.Mode() == ModeOAMScan) ||
+		(types.GetBit(4, stat) == 1 && p.Mode() == ModeVBlank) ||
+		(types.GetBit(3, stat) == 1 && p.Mode() == ModeHBlank)
+
+	rising := line && !p.statLine
+	p.statLine = line
+	return rising
+}
+
+func (p *PPU) vram(address types.Word) types.Byte {
+	return p.VRAM[address-0x8000]
+}
+
+// tileRow returns the two bitplanes of a row of a tile in a VRAM bank
+func (p *PPU) tileRow(bank types.Byte, tileAddress types.Word, row int) (types.Byte, types.Byte) {
+	address := tileAddress + types.Word(row*2) - 0x8000
+	if bank == 1 {
+		return p.VRAM1[address], p.VRAM1[address+1]
+	}
+	return p.VRAM[address], p.VRAM[address+1]
+}
+
+func colorIndex(lo, hi types.Byte, bit byte) types.Byte {
+	return types.GetBit(bit, hi)<<1 | types.GetBit(bit, lo)
+}
+
+func shade(palette, index types.Byte) types.Byte {
+	return (palette >> (index * 2)) & 0x03
+}
+
+func (p *PPU) renderLine(ly int) {
+	lcdc := p.Registers.LCDC.Get()
+	line := p.Framebuffer[ly*Width : (ly+1)*Width]
+
+	for x := range p.bgIndexes {
+		p.bgIndexes[x] = 0
+		p.bgAttributes[x] = 0
+	}
+
+	// on CGB LCDC bit 0 only takes the priority away from the background
+	if p.CGB || types.GetBit(0, lcdc) == 1 {
+		p.renderBackground(ly, line)
+	} else {
+		for x := range line {
+			line[x] = Shades[0]
+		}
+	}
+	if types.GetBit(1, lcdc) == 1 {
+		p.renderSprites(ly, line)
+	}
+}
+
+// BGTileAddress returns where the data of a background tile starts, with the
+// addressing mode selected by LCDC bit 4
+func (p *PPU) BGTileAddress(tile types.Byte) types.Word {
+	if types.GetBit(4, p.Registers.LCDC.Get()) == 1 {
+		return 0x8000 + types.Word(tile)*16
+	}
+	return types.Word(0x9000 + int(int8(tile))*16)
+}
+
+func (p *PPU) renderBackground(ly int, line []Color) {
+	lcdc := p.Registers.LCDC.Get()
+	bgp := p.Registers.BGP.Get()
+
+	bgMap := types.Word(0x9800)
+	if types.GetBit(3, lcdc) == 1 {
+		bgMap = 0x9C00
+	}
+	windowMap := types.Word(0x9800)
+	if types.GetBit(6, lcdc) == 1 {
+		windowMap = 0x9C00
+	}
+
+	wy := int(p.Registers.WY.Get())
+	wx := int(p.Registers.WX.Get()) - 7
+	window := types.GetBit(5, lcdc) == 1 && ly >= wy && wx < Width
+
+	for x := 0; x < Width; x++ {
+		var mapAddress types.Word
+		var px, py int
+		if window && x >= wx {
+			mapAddress = windowMap
+			px, py = x-wx, p.windowLine
+		} else {
+			mapAddress = bgMap
+			px = (x + int(p.Registers.SCX.Get())) & 0xFF
+			py = (ly + int(p.Registers.SCY.Get())) & 0xFF
+		}
+
+		tileMapAddress := mapAddress + types.Word(py/8*32+px/8)
+		tile := p.vram(tileMapAddress)
+		row, col := py%8, px%8
+		var attributes types.Byte
+		if p.CGB {
+			attributes = p.VRAM1[tileMapAddress-0x8000]
+			if types.GetBit(attributeYFlip, attributes) == 1 {
+				row = 7 - row
+			}
+			if types.GetBit(attributeXFlip, attributes) == 1 {
+				col = 7 - col
+			}
+		}
+		lo, hi := p.tileRow(types.GetBit(attributeBank, attributes), p.BGTileAddress(tile), row)
+		index := colorIndex(lo, hi, byte(7-col))
+
+		p.bgIndexes[x] = index
+		p.bgAttributes[x] = attributes
+		if p.CGB {
+			line[x] = paletteColor(&p.BGPalettes, attributes, index)
+		} else {
+			line[x] = Shades[shade(bgp, index)]
+		}
+	}
+
+	if window {
+		p.windowLine++
+	}
+}
+
+func (p *PPU) renderSprites(ly int, line []Color) {
+	height := 8
+	if types.GetBit(2, p.Registers.LCDC.Get()) == 1 {
+		height = 16
+	}
+
+	// the first 10 sprites on the line in OAM order are drawn
+	var visible []int
+	for i := 0; i < 40 && len(visible) < 10; i++ {
+		y := int(p.OAM[i*4]) - 16
+		if ly >= y && ly < y+height {
+			visible = append(visible, i)
+		}
+	}
+
+	// on DMG the sprite with the smallest X wins, then the first in OAM. On
+	// CGB only the OAM order counts.
+	if !p.CGB {
+		sort.SliceStable(visible, func(a, b int) bool {
+			return p.OAM[visible[a]*4+1] < p.OAM[visible[b]*4+1]
+		})
+	}
+
+	for x := 0; x < Width; x++ {
+		for _, i := range visible {
+			y := int(p.OAM[i*4]) - 16
+			sx := int(p.OAM[i*4+1]) - 8
+			tile := p.OAM[i*4+2]
+			attributes := p.OAM[i*4+3]
+			if x < sx || x >= sx+8 {
+				continue
+			}
+
+			row := ly - y
+			if types.GetBit(6, attributes) == 1 {
+				row = height - 1 - row
+			}
+			if height == 16 {
+				tile &= 0xFE
+			}
+			col := x - sx
+			if types.GetBit(5, attributes) == 1 {
+				col = 7 - col
+			}
+
+			var bank types.Byte
+			if p.CGB {
+				bank = types.GetBit(attributeBank, attributes)
+			}
+			lo, hi := p.tileRow(bank, 0x8000+types.Word(tile)*16, row)
+			index := colorIndex(lo, hi, byte(7-col))
+			if index == 0 {
+				// transparent, a sprite behind it may still show
+				continue
+			}
+
+			if p.spriteVisible(x, attributes) {
+				if p.CGB {
+					line[x] = paletteColor(&p.OBJPalettes, attributes, index)
+				} else {
+					palette := p.Registers.OBP0.Get()
+					if types.GetBit(4, attributes) == 1 {
+						palette = p.Registers.OBP1.Get()
+					}
+					line[x] = Shades[shade(palette, index)]
+				}
+			}
+			break
+		}
+	}
+}
+
+// spriteVisible tells whether a sprite pixel shows over the background at x.
+// Background colour 0 never hides sprites. Otherwise the sprite's priority
+// bit and, on CGB, the tile's own priority bit put the background on top,
+// unless LCDC bit 0 is clear on CGB.
+func (p *PPU) spriteVisible(x int, attributes types.Byte) bool {
+	if p.bgIndexes[x] == 0 {
+		return true
+	}
+	behind := types.GetBit(attributePriority, attributes) == 1
+	if p.CGB {
+		if types.GetBit(0, p.Registers.LCDC.Get()) == 0 {
+			return true
+		}
+		behind = behind || types.GetBit(attributePriority, p.bgAttributes[x]) == 1
+	}
+	return !behind
+}
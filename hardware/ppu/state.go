@@ -0,0 +1,58 @@
+package ppu
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/state"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+func (p *PPU) SaveState(e *state.Encoder) {
+	for address := LCDCAddress; address <= WXAddress; address++ {
+		e.Byte(p.register(address).Get())
+	}
+	e.Bytes(p.VRAM[:])
+	e.Bytes(p.OAM[:])
+	for _, c := range p.Framebuffer {
+		e.Word(types.Word(c))
+	}
+	e.Uint64(p.Frame)
+	e.Uint32(uint32(p.dots))
+	e.Uint32(uint32(p.windowLine))
+	e.Bool(p.statLine)
+	e.Bool(p.CGB)
+	e.Bytes(p.VRAM1[:])
+	e.Bytes(p.BGPalettes[:])
+	e.Bytes(p.OBJPalettes[:])
+	e.Byte(p.vbk)
+	e.Byte(p.bcps)
+	e.Byte(p.ocps)
+}
+
+func (p *PPU) LoadState(d *state.Decoder) {
+	for address := LCDCAddress; address <= WXAddress; address++ {
+		p.register(address).Set(d.Byte())
+	}
+	d.Bytes(p.VRAM[:])
+	d.Bytes(p.OAM[:])
+	if d.Version < 2 {
+		var shades [Width * Height]types.Byte
+		d.Bytes(shades[:])
+		for i, s := range shades {
+			p.Framebuffer[i] = Shades[s&0x03]
+		}
+	} else {
+		for i := range p.Framebuffer {
+			p.Framebuffer[i] = Color(d.Word())
+		}
+	}
+	p.Frame = d.Uint64()
+	p.dots = int(d.Uint32())
+	p.windowLine = int(d.Uint32())
+	p.statLine = d.Bool()
+	p.CGB = d.Bool()
+	d.Bytes(p.VRAM1[:])
+	d.Bytes(p.BGPalettes[:])
+	d.Bytes(p.OBJPalettes[:])
+	p.vbk = d.Byte()
+	p.bcps = d.Byte()
+	p.ocps = d.Byte()
+}
@@ -0,0 +1,108 @@
+package printer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+const (
+	width = 160
+	// blank rows fed for each unit of margin
+	marginRows = 8
+
+	// NeutralExposure prints the shades as they are. Exposure goes from 0x00,
+	// 25% lighter, to 0x7F, 25% darker.
+	NeutralExposure types.Byte = 0x40
+)
+
+// shades of the thermal paper, from white to black
+var shades = [4]color.Gray{{0xFF}, {0xAA}, {0x55}, {0x00}}
+
+// Job is a single print command with the image data received before it
+type Job struct {
+	Sheets       int
+	MarginBefore int
+	MarginAfter  int
+	Palette      types.Byte
+	// Exposure is how hard the head burns the paper, NeutralExposure being
+	// the nominal darkness
+	Exposure types.Byte
+	Data     []types.Byte
+}
+
+// Output receives every printed strip
+type Output interface {
+	Print(job Job) error
+}
+
+// PNGOutput writes every strip to its own numbered PNG file in Dir
+type PNGOutput struct {
+	Dir string
+
+	count int
+}
+
+func (o *PNGOutput) Print(job Job) error {
+	o.count++
+	path := filepath.Join(o.Dir, fmt.Sprintf("print-%04d.png", o.count))
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, job.Image()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Image renders the job as it comes out of the printer, margins included
+func (j Job) Image() *image.Gray {
+	rows := (len(j.Data)/16 + 19) / 20 * 8
+	top := j.MarginBefore * marginRows
+	height := top + rows + j.MarginAfter*marginRows
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = shades[0].Y
+	}
+	var inks [4]color.Gray
+	for i := range inks {
+		inks[i] = j.ink(shades[i])
+	}
+
+	for tile := 0; tile < len(j.Data)/16; tile++ {
+		// tiles come in bands of 2 rows by 20 columns
+		tx := tile % 20
+		ty := tile / 20
+		for y := 0; y < 8; y++ {
+			lo := j.Data[tile*16+y*2]
+			hi := j.Data[tile*16+y*2+1]
+			for x := 0; x < 8; x++ {
+				bit := byte(7 - x)
+				index := types.GetBit(bit, hi)<<1 | types.GetBit(bit, lo)
+				shade := (j.Palette >> (index * 2)) & 0x03
+				img.SetGray(tx*8+x, top+ty*8+y, inks[shade])
+			}
+		}
+	}
+	return img
+}
+
+// ink darkens or lightens a shade by the exposure of the job. White paper
+// stays white.
+func (j Job) ink(shade color.Gray) color.Gray {
+	darkness := 0xFF - int(shade.Y)
+	darkness = darkness * (0x100 + int(j.Exposure&0x7F) - int(NeutralExposure)) / 0x100
+	if darkness > 0xFF {
+		darkness = 0xFF
+	}
+	return color.Gray{Y: uint8(0xFF - darkness)}
+}
@@ -0,0 +1,259 @@
+package printer
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// commands
+const (
+	CommandInit   types.Byte = 0x01
+	CommandPrint  types.Byte = 0x02
+	CommandData   types.Byte = 0x04
+	CommandBreak  types.Byte = 0x08
+	CommandStatus types.Byte = 0x0F
+)
+
+// status bits
+const (
+	StatusChecksumError = 0
+	StatusPrinting      = 1
+	StatusImageFull     = 2
+	StatusUnprocessed   = 3
+	StatusPacketError   = 4
+	StatusPaperJam      = 5
+	StatusOtherError    = 6
+	StatusLowBattery    = 7
+)
+
+const (
+	magic1 types.Byte = 0x88
+	magic2 types.Byte = 0x33
+
+	// answered in place of the first trailing zero byte of a packet
+	alive types.Byte = 0x81
+
+	// a band is two rows of 20 tiles
+	bandSize = 640
+	// the printer RAM holds a whole screen
+	bufferSize = 9 * bandSize
+
+	// status polls answered as busy after a print command
+	printPolls = 4
+
+	// a print command with a zero palette prints with this one, which maps
+	// every colour to its own shade
+	defaultPalette types.Byte = 0xE4
+)
+
+type state int
+
+const (
+	stateMagic1 state = iota
+	stateMagic2
+	stateCommand
+	stateCompression
+	stateLengthL
+	stateLengthH
+	stateData
+	stateChecksumL
+	stateChecksumH
+	stateAlive
+	stateStatus
+)
+
+// Printer is a Game Boy Printer plugged into the serial port. Every printed
+// strip is handed to Output.
+type Printer struct {
+	Output Output
+
+	state      state
+	command    types.Byte
+	compressed bool
+	length     types.Word
+	data       []types.Byte
+	checksum   types.Word
+	received   types.Word
+	status     types.Byte
+	buffer     []types.Byte
+	busyPolls  int
+	pendingErr error
+}
+
+func New(output Output) *Printer {
+	return &Printer{Output: output}
+}
+
+// Err returns the last error reported by Output, if any
+func (p *Printer) Err() error {
+	return p.pendingErr
+}
+
+func (p *Printer) Transfer(b types.Byte) types.Byte {
+	switch p.state {
+	case stateMagic1:
+		if b == magic1 {
+			p.state = stateMagic2
+		}
+	case stateMagic2:
+		if b == magic2 {
+			p.state = stateCommand
+		} else {
+			p.state = stateMagic1
+		}
+	case stateCommand:
+		p.command = b
+		p.checksum = types.Word(b)
+		p.state = stateCompression
+	case stateCompression:
+		p.compressed = b&0x01 == 0x01
+		p.checksum += types.Word(b)
+		p.state = stateLengthL
+	case stateLengthL:
+		p.length = types.Word(b)
+		p.checksum += types.Word(b)
+		p.state = stateLengthH
+	case stateLengthH:
+		p.length |= types.Word(b) << 8
+		p.checksum += types.Word(b)
+		p.data = p.data[:0]
+		if p.length == 0 {
+			p.state = stateChecksumL
+		} else {
+			p.state = stateData
+		}
+	case stateData:
+		p.data = append(p.data, b)
+		p.checksum += types.Word(b)
+		if types.Word(len(p.data)) == p.length {
+			p.state = stateChecksumL
+		}
+	case stateChecksumL:
+		p.received = types.Word(b)
+		p.state = stateChecksumH
+	case stateChecksumH:
+		p.received |= types.Word(b) << 8
+		p.state = stateAlive
+	case stateAlive:
+		p.state = stateStatus
+		return alive
+	case stateStatus:
+		p.state = stateMagic1
+		p.handlePacket()
+		return p.status
+	}
+	return 0x00
+}
+
+func (p *Printer) handlePacket() {
+	if p.received != p.checksum {
+		p.status = types.SetBit(StatusChecksumError, p.status)
+		return
+	}
+	p.status = types.ResetBit(StatusChecksumError, p.status)
+
+	switch p.command {
+	case CommandInit:
+		p.buffer = p.buffer[:0]
+		p.busyPolls = 0
+		p.status = 0x00
+	case CommandData:
+		p.receiveData()
+	case CommandPrint:
+		p.print()
+	case CommandBreak:
+		p.busyPolls = 0
+		p.status = types.ResetBit(StatusPrinting, p.status)
+	case CommandStatus:
+		p.updateStatus()
+	default:
+		p.status = types.SetBit(StatusPacketError, p.status)
+	}
+}
+
+func (p *Printer) receiveData() {
+	data := p.data
+	if p.compressed {
+		data = decompress(data)
+	}
+
+	room := bufferSize - len(p.buffer)
+	if len(data) > room {
+		data = data[:room]
+	}
+	p.buffer = append(p.buffer, data...)
+
+	if len(p.buffer) > 0 {
+		p.status = types.SetBit(StatusUnprocessed, p.status)
+	}
+	if len(p.buffer) == bufferSize {
+		p.status = types.SetBit(StatusImageFull, p.status)
+	}
+}
+
+func (p *Printer) print() {
+	if len(p.data) < 4 {
+		p.status = types.SetBit(StatusPacketError, p.status)
+		return
+	}
+
+	job := Job{
+		Sheets:       int(p.data[0]),
+		MarginBefore: int(p.data[1] >> 4),
+		MarginAfter:  int(p.data[1] & 0x0F),
+		Palette:      p.data[2],
+		Exposure:     p.data[3] & 0x7F,
+		Data:         append([]types.Byte(nil), p.buffer...),
+	}
+	if job.Palette == 0x00 {
+		job.Palette = defaultPalette
+	}
+	if job.Sheets > 0 && len(job.Data) > 0 && p.Output != nil {
+		if err := p.Output.Print(job); err != nil {
+			p.pendingErr = err
+			p.status = types.SetBit(StatusOtherError, p.status)
+		}
+	}
+
+	p.buffer = p.buffer[:0]
+	p.busyPolls = printPolls
+	p.status = types.ResetBit(StatusUnprocessed, p.status)
+	p.status = types.ResetBit(StatusImageFull, p.status)
+	p.status = types.SetBit(StatusPrinting, p.status)
+}
+
+// updateStatus pretends the print head is moving for a few polls so games
+// waiting for the printer to finish see it complete
+func (p *Printer) updateStatus() {
+	if p.busyPolls > 0 {
+		p.busyPolls--
+		return
+	}
+	p.status = types.ResetBit(StatusPrinting, p.status)
+}
+
+// decompress expands the printer RLE: a control byte with bit 7 set repeats
+// the next byte (n&0x7F)+2 times, otherwise n+1 literal bytes follow
+func decompress(data []types.Byte) []types.Byte {
+	var out []types.Byte
+	for i := 0; i < len(data); {
+		control := data[i]
+		i++
+		if control&0x80 == 0x80 {
+			if i >= len(data) {
+				break
+			}
+			for n := 0; n < int(control&0x7F)+2; n++ {
+				out = append(out, data[i])
+			}
+			i++
+		} else {
+			end := i + int(control) + 1
+			if end > len(data) {
+				end = len(data)
+			}
+			out = append(out, data[i:end]...)
+			i = end
+		}
+	}
+	return out
+}
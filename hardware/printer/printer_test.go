@@ -0,0 +1,257 @@
+package printer
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// packet builds what a game sends for a command: magic bytes, header, data,
+// checksum and the two bytes clocked in for the alive and status answers
+func packet(command types.Byte, compressed bool, data []types.Byte) []types.Byte {
+	var compression types.Byte
+	if compressed {
+		compression = 0x01
+	}
+	header := []types.Byte{command, compression, types.Byte(len(data)), types.Byte(len(data) >> 8)}
+	var checksum types.Word
+	for _, b := range append(header, data...) {
+		checksum += types.Word(b)
+	}
+
+	p := append([]types.Byte{magic1, magic2}, header...)
+	p = append(p, data...)
+	return append(p, types.Byte(checksum), types.Byte(checksum>>8), 0x00, 0x00)
+}
+
+// send shifts a packet into the printer and returns its last two answers,
+// the alive byte and the status
+func send(p *Printer, packet []types.Byte) (types.Byte, types.Byte) {
+	var answers []types.Byte
+	for _, b := range packet {
+		answers = append(answers, p.Transfer(b))
+	}
+	return answers[len(answers)-2], answers[len(answers)-1]
+}
+
+type jobs []Job
+
+func (j *jobs) Print(job Job) error {
+	*j = append(*j, job)
+	return nil
+}
+
+type failingOutput struct{}
+
+func (failingOutput) Print(Job) error {
+	return errors.New("out of paper")
+}
+
+func TestPackets(t *testing.T) {
+	band := bytes.Repeat([]types.Byte{0xFF}, bandSize)
+
+	tests := []struct {
+		name    string
+		packets [][]types.Byte
+		status  types.Byte
+	}{
+		{"init", [][]types.Byte{packet(CommandInit, false, nil)}, 0x00},
+		{"status", [][]types.Byte{packet(CommandStatus, false, nil)}, 0x00},
+		{"data", [][]types.Byte{packet(CommandData, false, band)}, 1 << StatusUnprocessed},
+		{"full", [][]types.Byte{
+			packet(CommandData, false, band), packet(CommandData, false, band), packet(CommandData, false, band),
+			packet(CommandData, false, band), packet(CommandData, false, band), packet(CommandData, false, band),
+			packet(CommandData, false, band), packet(CommandData, false, band), packet(CommandData, false, band),
+		}, 1<<StatusUnprocessed | 1<<StatusImageFull},
+		{"print", [][]types.Byte{
+			packet(CommandData, false, band),
+			packet(CommandPrint, false, []types.Byte{0x01, 0x13, 0xE4, 0x40}),
+		}, 1 << StatusPrinting},
+		{"print done", [][]types.Byte{
+			packet(CommandData, false, band),
+			packet(CommandPrint, false, []types.Byte{0x01, 0x13, 0xE4, 0x40}),
+			packet(CommandStatus, false, nil), packet(CommandStatus, false, nil),
+			packet(CommandStatus, false, nil), packet(CommandStatus, false, nil),
+			packet(CommandStatus, false, nil),
+		}, 0x00},
+		{"break", [][]types.Byte{
+			packet(CommandData, false, band),
+			packet(CommandPrint, false, []types.Byte{0x01, 0x13, 0xE4, 0x40}),
+			packet(CommandBreak, false, nil),
+		}, 0x00},
+		{"short print", [][]types.Byte{packet(CommandPrint, false, []types.Byte{0x01})}, 1 << StatusPacketError},
+		{"unknown command", [][]types.Byte{packet(0x03, false, nil)}, 1 << StatusPacketError},
+		{"bad checksum", [][]types.Byte{func() []types.Byte {
+			p := packet(CommandData, false, band)
+			p[len(p)-4]++
+			return p
+		}()}, 1 << StatusChecksumError},
+		{"noise before magic", [][]types.Byte{append([]types.Byte{0x00, 0x88, 0x00}, packet(CommandInit, false, nil)...)}, 0x00},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(&jobs{})
+			var alive, status types.Byte
+			for _, pkt := range tt.packets {
+				alive, status = send(p, pkt)
+			}
+			if alive != 0x81 {
+				t.Errorf("alive byte 0x%02X, want 0x81", alive)
+			}
+			if status != tt.status {
+				t.Errorf("status 0x%02X, want 0x%02X", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestPrintJob(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   []types.Byte
+		palette  types.Byte
+		exposure types.Byte
+		margins  [2]int
+	}{
+		{"params", []types.Byte{0x01, 0x31, 0x1B, 0x7F}, 0x1B, 0x7F, [2]int{3, 1}},
+		{"zero palette", []types.Byte{0x01, 0x00, 0x00, 0x40}, 0xE4, 0x40, [2]int{0, 0}},
+		{"exposure high bit", []types.Byte{0x01, 0x00, 0xE4, 0xC0}, 0xE4, 0x40, [2]int{0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &jobs{}
+			p := New(out)
+			send(p, packet(CommandData, false, make([]types.Byte, bandSize)))
+			send(p, packet(CommandPrint, false, tt.params))
+
+			if len(*out) != 1 {
+				t.Fatalf("%d jobs printed, want 1", len(*out))
+			}
+			job := (*out)[0]
+			if job.Palette != tt.palette || job.Exposure != tt.exposure {
+				t.Errorf("palette 0x%02X exposure 0x%02X, want 0x%02X and 0x%02X", job.Palette, job.Exposure, tt.palette, tt.exposure)
+			}
+			if got := [2]int{job.MarginBefore, job.MarginAfter}; got != tt.margins {
+				t.Errorf("margins %v, want %v", got, tt.margins)
+			}
+			if len(job.Data) != bandSize {
+				t.Errorf("%d bytes of data, want %d", len(job.Data), bandSize)
+			}
+		})
+	}
+}
+
+func TestPrintWithoutSheetsOrData(t *testing.T) {
+	out := &jobs{}
+	p := New(out)
+	send(p, packet(CommandPrint, false, []types.Byte{0x01, 0x00, 0xE4, 0x40}))
+	send(p, packet(CommandData, false, make([]types.Byte, bandSize)))
+	send(p, packet(CommandPrint, false, []types.Byte{0x00, 0x00, 0xE4, 0x40}))
+	if len(*out) != 0 {
+		t.Fatalf("%d jobs printed, want none", len(*out))
+	}
+}
+
+func TestOutputError(t *testing.T) {
+	p := New(failingOutput{})
+	send(p, packet(CommandData, false, make([]types.Byte, bandSize)))
+	_, status := send(p, packet(CommandPrint, false, []types.Byte{0x01, 0x00, 0xE4, 0x40}))
+	if types.GetBit(StatusOtherError, status) != 1 {
+		t.Errorf("status 0x%02X, want the other error bit", status)
+	}
+	if p.Err() == nil {
+		t.Error("the output error was not kept")
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []types.Byte
+		want []types.Byte
+	}{
+		{"empty", nil, nil},
+		{"literal", []types.Byte{0x02, 0xA, 0xB, 0xC}, []types.Byte{0xA, 0xB, 0xC}},
+		{"run", []types.Byte{0x81, 0x55}, []types.Byte{0x55, 0x55, 0x55}},
+		{"shortest run", []types.Byte{0x80, 0x11}, []types.Byte{0x11, 0x11}},
+		{"mixed", []types.Byte{0x00, 0x01, 0x82, 0x02, 0x01, 0x03, 0x04}, []types.Byte{0x01, 0x02, 0x02, 0x02, 0x02, 0x03, 0x04}},
+		{"truncated literal", []types.Byte{0x03, 0x01}, []types.Byte{0x01}},
+		{"truncated run", []types.Byte{0x01, 0x07, 0x08, 0x85}, []types.Byte{0x07, 0x08}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decompress(tt.in); !bytes.Equal(got, tt.want) {
+				t.Errorf("decompress(% X) = % X, want % X", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompressedData(t *testing.T) {
+	out := &jobs{}
+	p := New(out)
+	// a band of 0xFF in runs of 128
+	var data []types.Byte
+	for i := 0; i < bandSize/128; i++ {
+		data = append(data, 0xFE, 0xFF)
+	}
+	send(p, packet(CommandData, true, data))
+	send(p, packet(CommandPrint, false, []types.Byte{0x01, 0x00, 0xE4, 0x40}))
+
+	if len(*out) != 1 {
+		t.Fatalf("%d jobs printed, want 1", len(*out))
+	}
+	if want := bytes.Repeat([]types.Byte{0xFF}, bandSize); !bytes.Equal((*out)[0].Data, want) {
+		t.Error("the data was not decompressed")
+	}
+}
+
+func TestJobImage(t *testing.T) {
+	// tile 0 row 0 is colour 3 on the left half and 1 on the right, tile 21
+	// is the second tile of the second row of the band
+	data := make([]types.Byte, bandSize)
+	data[0], data[1] = 0xFF, 0xF0
+	data[21*16] = 0x80
+
+	tests := []struct {
+		name     string
+		palette  types.Byte
+		exposure types.Byte
+		pixels   map[[2]int]uint8
+	}{
+		{"identity", 0xE4, NeutralExposure, map[[2]int]uint8{
+			{0, 16}: 0x00, {4, 16}: 0xAA, {8, 16}: 0xFF, {8, 24}: 0xAA, {9, 24}: 0xFF,
+		}},
+		{"inverted", 0x1B, NeutralExposure, map[[2]int]uint8{
+			{0, 16}: 0xFF, {4, 16}: 0x55, {8, 16}: 0x00,
+		}},
+		{"light", 0xE4, 0x00, map[[2]int]uint8{
+			{0, 16}: 0x40, {4, 16}: 0xC0, {8, 16}: 0xFF,
+		}},
+		{"dark", 0xE4, 0x7F, map[[2]int]uint8{
+			{0, 16}: 0x00, {4, 16}: 0x96, {8, 16}: 0xFF,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := Job{Sheets: 1, MarginBefore: 2, MarginAfter: 1, Palette: tt.palette, Exposure: tt.exposure, Data: data}
+			img := job.Image()
+			if got, want := img.Bounds().Dy(), 2*marginRows+16+marginRows; got != want {
+				t.Fatalf("%d rows, want %d", got, want)
+			}
+			if got := img.Bounds().Dx(); got != width {
+				t.Fatalf("%d columns, want %d", got, width)
+			}
+			if got := img.GrayAt(0, 0).Y; got != 0xFF {
+				t.Errorf("margin pixel 0x%02X, want white", got)
+			}
+			for xy, want := range tt.pixels {
+				if got := img.GrayAt(xy[0], xy[1]).Y; got != want {
+					t.Errorf("pixel %v = 0x%02X, want 0x%02X", xy, got, want)
+				}
+			}
+		})
+	}
+}
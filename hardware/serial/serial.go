@@ -0,0 +1,58 @@
+package serial
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// I/O addresses of the serial port
+const (
+	SBAddress types.Word = 0xFF01
+	SCAddress types.Word = 0xFF02
+)
+
+// Interrupt is the bit of IF raised when a transfer completes
+const Interrupt = 3
+
+// Device is whatever is plugged into the other end of the link cable
+type Device interface {
+	// Transfer shifts b out to the device and returns the byte shifted back in
+	Transfer(b types.Byte) types.Byte
+}
+
+type Port struct {
+	SB types.ByteRegister
+	SC types.ByteRegister
+
+	Device Device
+}
+
+func (p *Port) Read(address types.Word) types.Byte {
+	if address == SBAddress {
+		return p.SB.Get()
+	}
+	// unused bits read back as 1
+	return p.SC.Get() | 0x7E
+}
+
+// Write updates SB or SC and reports whether a serial interrupt must be raised.
+// Transfers driven by the internal clock complete immediately.
+func (p *Port) Write(address types.Word, value types.Byte) bool {
+	if address == SBAddress {
+		p.SB.Set(value)
+		return false
+	}
+
+	p.SC.Set(value)
+	if types.GetBit(7, value) == 0 || types.GetBit(0, value) == 0 {
+		// nothing to do, or waiting for an external clock that never comes
+		return false
+	}
+
+	received := types.Byte(0xFF)
+	if p.Device != nil {
+		received = p.Device.Transfer(p.SB.Get())
+	}
+	p.SB.Set(received)
+	p.SC.Set(types.ResetBit(7, value))
+	return true
+}
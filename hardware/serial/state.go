@@ -0,0 +1,15 @@
+package serial
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/state"
+)
+
+func (p *Port) SaveState(e *state.Encoder) {
+	e.Byte(p.SB.Get())
+	e.Byte(p.SC.Get())
+}
+
+func (p *Port) LoadState(d *state.Decoder) {
+	p.SB.Set(d.Byte())
+	p.SC.Set(d.Byte())
+}
@@ -0,0 +1,255 @@
+package sgb
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// command codes, in the upper 5 bits of the first byte of a packet
+const (
+	cmdPAL01   = 0x00
+	cmdPAL23   = 0x01
+	cmdPAL03   = 0x02
+	cmdPAL12   = 0x03
+	cmdATTRBLK = 0x04
+	cmdATTRLIN = 0x05
+	cmdATTRDIV = 0x06
+	cmdATTRCHR = 0x07
+	cmdPALSET  = 0x0A
+	cmdPALTRN  = 0x0B
+	cmdMLTREQ  = 0x11
+	cmdCHRTRN  = 0x13
+	cmdPCTTRN  = 0x14
+	cmdATTRTRN = 0x15
+	cmdATTRSET = 0x16
+	cmdMASKEN  = 0x17
+)
+
+// pairs of palettes set by PAL01 to PAL12
+var palettePairs = map[types.Byte][2]int{
+	cmdPAL01: {0, 1},
+	cmdPAL23: {2, 3},
+	cmdPAL03: {0, 3},
+	cmdPAL12: {1, 2},
+}
+
+// execute runs a complete command, data holding all its packets
+// @todo the sound commands and the SNES program transfers are ignored
+func (s *SGB) execute(data []types.Byte) {
+	switch code := data[0] >> 3; code {
+	case cmdPAL01, cmdPAL23, cmdPAL03, cmdPAL12:
+		s.setPalettes(palettePairs[code], data[1:])
+	case cmdATTRBLK:
+		s.attrBlock(data)
+	case cmdATTRLIN:
+		s.attrLine(data)
+	case cmdATTRDIV:
+		s.attrDivide(data)
+	case cmdATTRCHR:
+		s.attrCharacters(data)
+	case cmdPALSET:
+		s.palSet(data)
+	case cmdMLTREQ:
+		switch data[1] & 0x03 {
+		case 1:
+			s.players = 2
+		case 3:
+			s.players = 4
+		default:
+			s.players = 1
+		}
+		s.player = 0
+	case cmdATTRSET:
+		s.attrSet(data[1])
+	case cmdMASKEN:
+		s.setMask(data[1] & 0x03)
+	case cmdPALTRN, cmdCHRTRN, cmdPCTTRN, cmdATTRTRN:
+		s.transfer = data
+	}
+}
+
+// finishTransfer stores the VRAM copied for a *_TRN command
+func (s *SGB) finishTransfer(command, data []types.Byte) {
+	switch command[0] >> 3 {
+	case cmdPALTRN:
+		for i := range s.systemPalettes {
+			for c := range s.systemPalettes[i] {
+				s.systemPalettes[i][c] = color(data[i*8+c*2:])
+			}
+		}
+	case cmdCHRTRN:
+		half := int(command[1]&0x01) * transferSize
+		copy(s.borderTiles[half:], data)
+	case cmdPCTTRN:
+		for i := range s.borderMap {
+			s.borderMap[i] = types.Word(data[i*2]) | types.Word(data[i*2+1])<<8
+		}
+		for p := range s.borderPalettes {
+			for c := range s.borderPalettes[p] {
+				s.borderPalettes[p][c] = color(data[0x800+p*32+c*2:])
+			}
+		}
+	case cmdATTRTRN:
+		for i := range s.attributeFiles {
+			copy(s.attributeFiles[i][:], data[i*len(s.attributeFiles[i]):])
+		}
+	}
+}
+
+// color reads a little endian RGB555 colour
+func color(b []types.Byte) ppu.Color {
+	return (ppu.Color(b[0]) | ppu.Color(b[1])<<8) & 0x7FFF
+}
+
+// setPalettes loads colour 0, shared by every palette, then colours 1 to 3
+// of two palettes
+func (s *SGB) setPalettes(pair [2]int, data []types.Byte) {
+	s.setColor0(color(data))
+	for i, p := range pair {
+		for c := 1; c < 4; c++ {
+			s.palettes[p][c] = color(data[2+i*6+(c-1)*2:])
+		}
+	}
+}
+
+func (s *SGB) setColor0(c ppu.Color) {
+	for p := range s.palettes {
+		s.palettes[p][0] = c
+	}
+}
+
+// attrBlock colours the inside, the frame and the outside of rectangles of
+// cells
+func (s *SGB) attrBlock(data []types.Byte) {
+	sets := int(data[1] & 0x1F)
+	for i := 0; i < sets && 2+i*6+6 <= len(data); i++ {
+		set := data[2+i*6:]
+		control := set[0]
+		inside, frame, outside := set[1]&0x03, set[1]>>2&0x03, set[1]>>4&0x03
+		changeInside := types.GetBit(0, control) == 1
+		changeFrame := types.GetBit(1, control) == 1
+		changeOutside := types.GetBit(2, control) == 1
+		// changing only one side also changes the frame to match
+		if changeInside && !changeFrame && !changeOutside {
+			changeFrame, frame = true, inside
+		} else if changeOutside && !changeFrame && !changeInside {
+			changeFrame, frame = true, outside
+		}
+
+		x1, y1 := int(set[2]&0x1F), int(set[3]&0x1F)
+		x2, y2 := int(set[4]&0x1F), int(set[5]&0x1F)
+		for y := 0; y < cellRows; y++ {
+			for x := 0; x < cellColumns; x++ {
+				in := x >= x1 && x <= x2 && y >= y1 && y <= y2
+				onFrame := in && (x == x1 || x == x2 || y == y1 || y == y2)
+				switch {
+				case onFrame && changeFrame:
+					s.attributes[y][x] = frame
+				case in && !onFrame && changeInside:
+					s.attributes[y][x] = inside
+				case !in && changeOutside:
+					s.attributes[y][x] = outside
+				}
+			}
+		}
+	}
+}
+
+// attrLine colours whole rows or columns of cells
+func (s *SGB) attrLine(data []types.Byte) {
+	sets := int(data[1])
+	for i := 0; i < sets && 2+i < len(data); i++ {
+		set := data[2+i]
+		line, palette := int(set&0x1F), set>>5&0x03
+		if types.GetBit(7, set) == 1 {
+			if line < cellRows {
+				for x := 0; x < cellColumns; x++ {
+					s.attributes[line][x] = palette
+				}
+			}
+		} else if line < cellColumns {
+			for y := 0; y < cellRows; y++ {
+				s.attributes[y][line] = palette
+			}
+		}
+	}
+}
+
+// attrDivide splits the screen in two along a row or column of cells
+func (s *SGB) attrDivide(data []types.Byte) {
+	after, before, on := data[1]&0x03, data[1]>>2&0x03, data[1]>>4&0x03
+	horizontal := types.GetBit(6, data[1]) == 1
+	divider := int(data[2] & 0x1F)
+	for y := 0; y < cellRows; y++ {
+		for x := 0; x < cellColumns; x++ {
+			position := x
+			if horizontal {
+				position = y
+			}
+			switch {
+			case position < divider:
+				s.attributes[y][x] = before
+			case position == divider:
+				s.attributes[y][x] = on
+			default:
+				s.attributes[y][x] = after
+			}
+		}
+	}
+}
+
+// attrCharacters sets the palette of cells one by one, 4 per byte, from a
+// starting cell left to right or top to bottom
+func (s *SGB) attrCharacters(data []types.Byte) {
+	x, y := int(data[1]), int(data[2])
+	count := int(data[3]) | int(data[4])<<8
+	vertical := data[5]&0x01 == 1
+	for i := 0; i < count && 6+i/4 < len(data); i++ {
+		if x >= cellColumns || y >= cellRows {
+			return
+		}
+		s.attributes[y][x] = data[6+i/4] >> (6 - 2*(i%4)) & 0x03
+		if vertical {
+			if y++; y == cellRows {
+				y, x = 0, x+1
+			}
+		} else if x++; x == cellColumns {
+			x, y = 0, y+1
+		}
+	}
+}
+
+// palSet copies system palettes into the 4 screen palettes, colour 0 coming
+// from the first one
+func (s *SGB) palSet(data []types.Byte) {
+	for p := range s.palettes {
+		n := (int(data[1+p*2]) | int(data[2+p*2])<<8) & 0x1FF
+		s.palettes[p] = s.systemPalettes[n]
+	}
+	s.setColor0(s.palettes[0][0])
+
+	if types.GetBit(7, data[9]) == 1 {
+		s.attrSet(data[9])
+	}
+	if types.GetBit(6, data[9]) == 1 {
+		s.setMask(maskOff)
+	}
+}
+
+// attrSet applies an attribute file loaded with ATTR_TRN
+func (s *SGB) attrSet(value types.Byte) {
+	n := int(value & 0x3F)
+	if n < len(s.attributeFiles) {
+		for i := 0; i < cellRows*cellColumns; i++ {
+			s.attributes[i/cellColumns][i%cellColumns] = s.attributeFiles[n][i/4] >> (6 - 2*(i%4)) & 0x03
+		}
+	}
+	if types.GetBit(6, value) == 1 {
+		s.setMask(maskOff)
+	}
+}
+
+func (s *SGB) setMask(mask types.Byte) {
+	s.mask = mask
+	s.hasFrozen = false
+}
@@ -0,0 +1,91 @@
+package sgb
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// Colorize paints the DMG screen, drawn with ppu.Shades, with the palette of
+// every cell. out may be screen itself.
+func (s *SGB) Colorize(screen, out []ppu.Color) {
+	switch s.mask {
+	case maskBlack:
+		fill(out[:ppu.Width*ppu.Height], 0)
+		return
+	case maskColor0:
+		fill(out[:ppu.Width*ppu.Height], s.palettes[0][0])
+		return
+	case maskFreeze:
+		if s.hasFrozen {
+			copy(out, s.frozen[:])
+			return
+		}
+	}
+
+	for y := 0; y < ppu.Height; y++ {
+		for x := 0; x < ppu.Width; x++ {
+			i := y*ppu.Width + x
+			palette := s.attributes[y/8][x/8]
+			out[i] = s.palettes[palette][shadeOf(screen[i])]
+		}
+	}
+	if s.mask == maskFreeze {
+		copy(s.frozen[:], out)
+		s.hasFrozen = true
+	}
+}
+
+func fill(pixels []ppu.Color, c ppu.Color) {
+	for i := range pixels {
+		pixels[i] = c
+	}
+}
+
+// shadeOf turns a DMG pixel back into its shade
+func shadeOf(c ppu.Color) int {
+	for shade, s := range ppu.Shades {
+		if c == s {
+			return shade
+		}
+	}
+	return 0
+}
+
+// Compose lays the coloured screen over the border into a Width x Height
+// picture. Transparent border pixels show colour 0.
+func (s *SGB) Compose(screen, out []ppu.Color) {
+	backdrop := s.palettes[0][0]
+	for y := 0; y < Height; y++ {
+		for x := 0; x < Width; x++ {
+			out[y*Width+x] = s.borderPixel(x, y, backdrop)
+		}
+	}
+	for y := 0; y < ppu.Height; y++ {
+		copy(out[(y+screenY)*Width+screenX:], screen[y*ppu.Width:(y+1)*ppu.Width])
+	}
+}
+
+// borderPixel reads a pixel of the border: each map entry holds a tile number
+// in bits 0-7, a palette from 4 to 7 in bits 10-12 and flips in bits 14-15
+func (s *SGB) borderPixel(x, y int, backdrop ppu.Color) ppu.Color {
+	entry := s.borderMap[y/8*32+x/8]
+	row, col := y%8, x%8
+	if entry&0x4000 != 0 {
+		col = 7 - col
+	}
+	if entry&0x8000 != 0 {
+		row = 7 - row
+	}
+
+	// the 4 bitplanes are stored as two pairs of 2, 16 bytes apart
+	tile := s.borderTiles[int(entry&0xFF)*32:]
+	bit := byte(7 - col)
+	index := types.GetBit(bit, tile[row*2]) |
+		types.GetBit(bit, tile[row*2+1])<<1 |
+		types.GetBit(bit, tile[16+row*2])<<2 |
+		types.GetBit(bit, tile[16+row*2+1])<<3
+	if index == 0 {
+		return backdrop
+	}
+	return s.borderPalettes[entry>>10&0x03][index]
+}
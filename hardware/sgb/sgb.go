@@ -0,0 +1,168 @@
+// Package sgb implements the Super Game Boy side of a DMG game: the commands
+// sent as packets through the joypad register, the colourisation of the
+// screen and the border around it.
+package sgb
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// size of the SNES picture, border included
+const (
+	Width  = 256
+	Height = 224
+
+	// where the Game Boy screen sits in the picture
+	screenX = (Width - ppu.Width) / 2
+	screenY = (Height - ppu.Height) / 2
+
+	// the screen is coloured in cells of 8x8 pixels
+	cellColumns = ppu.Width / 8
+	cellRows    = ppu.Height / 8
+
+	packetSize = 16
+	// bytes copied from VRAM by the *_TRN commands
+	transferSize = 0x1000
+)
+
+// mask modes set with MASK_EN
+const (
+	maskOff types.Byte = iota
+	maskFreeze
+	maskBlack
+	maskColor0
+)
+
+// SGB is what the SNES side of a Super Game Boy keeps track of
+type SGB struct {
+	// palettes currently used on the screen, colour 0 is shared
+	palettes [4][4]ppu.Color
+	// system palettes loaded with PAL_TRN, picked with PAL_SET
+	systemPalettes [512][4]ppu.Color
+	// palette of every 8x8 cell of the screen
+	attributes [cellRows][cellColumns]types.Byte
+	// attribute files loaded with ATTR_TRN, picked with ATTR_SET
+	attributeFiles [45][cellRows * cellColumns / 4]types.Byte
+
+	// border tiles in the SNES 4 bits per pixel format, its tile map and
+	// palettes 4 to 7
+	borderTiles    [256 * 32]types.Byte
+	borderMap      [32 * 32]types.Word
+	borderPalettes [4][16]ppu.Color
+
+	mask types.Byte
+	// the screen as it was when MASK_EN froze it
+	frozen    [ppu.Width * ppu.Height]ppu.Color
+	hasFrozen bool
+
+	// joypads selected with MLT_REQ
+	players int
+	player  int
+
+	// packet being received through P1
+	receiving bool
+	p1        types.Byte
+	bit       int
+	packet    [packetSize]types.Byte
+	// packets of the current command
+	command  []types.Byte
+	expected int
+
+	// command waiting for the next VBlank to copy VRAM
+	transfer []types.Byte
+}
+
+// defaultPalette is what the SGB shows before a game picks its colours
+var defaultPalette = [4]ppu.Color{0x67BF, 0x265B, 0x10B5, 0x2866}
+
+func New() *SGB {
+	s := &SGB{players: 1, p1: 0x30}
+	for i := range s.palettes {
+		s.palettes[i] = defaultPalette
+	}
+	return s
+}
+
+// WriteP1 follows the pulses games send through the joypad register. A reset
+// pulse, both lines low, starts a packet. Then every bit is a pulse on P14
+// for 0 or P15 for 1, separated by both lines high, and a 0 bit ends the
+// 128 bits of the packet.
+func (s *SGB) WriteP1(value types.Byte) {
+	value &= 0x30
+	previous := s.p1
+	s.p1 = value
+
+	// the next joypad is selected when P15 goes back high
+	if types.GetBit(5, previous) == 0 && types.GetBit(5, value) == 1 && s.players > 1 && !s.receiving {
+		s.player = (s.player + 1) % s.players
+	}
+
+	switch {
+	case value == 0x00:
+		s.receiving = true
+		s.bit = 0
+		s.packet = [packetSize]types.Byte{}
+	case !s.receiving || previous != 0x30 || value == 0x30:
+	case s.bit == packetSize*8:
+		// stop bit
+		s.receiving = false
+		s.receivePacket()
+	default:
+		if value == 0x10 {
+			s.packet[s.bit/8] = types.SetBit(byte(s.bit%8), s.packet[s.bit/8])
+		}
+		s.bit++
+	}
+}
+
+// ReadP1 adjusts what the joypad returns: with both lines deselected the low
+// nibble identifies the current joypad, 0xF for the first one
+func (s *SGB) ReadP1(value types.Byte) types.Byte {
+	if value&0x30 != 0x30 || s.players == 1 {
+		return value
+	}
+	return value&0xF0 | types.Byte(0x0F-s.player)
+}
+
+func (s *SGB) receivePacket() {
+	if s.command == nil {
+		// the first byte holds the command and how many packets it takes
+		s.expected = int(s.packet[0] & 0x07)
+		if s.expected == 0 {
+			return
+		}
+	}
+	s.command = append(s.command, s.packet[:]...)
+	if len(s.command) < s.expected*packetSize {
+		return
+	}
+	command := s.command
+	s.command = nil
+	s.execute(command)
+}
+
+// VBlank carries out a pending VRAM transfer. The SGB copies what the
+// screen shows: the first 256 tiles of the background map, 20 per row, in
+// the order they are displayed.
+func (s *SGB) VBlank(p *ppu.PPU) {
+	if s.transfer == nil {
+		return
+	}
+	lcdc := p.Registers.LCDC.Get()
+	bgMap := types.Word(0x9800)
+	if types.GetBit(3, lcdc) == 1 {
+		bgMap = 0x9C00
+	}
+
+	var data [transferSize]types.Byte
+	for i := 0; i < transferSize/16; i++ {
+		tile := p.VRAM[bgMap+types.Word(i/cellColumns*32+i%cellColumns)-0x8000]
+		address := p.BGTileAddress(tile) - 0x8000
+		copy(data[i*16:], p.VRAM[address:address+16])
+	}
+
+	command := s.transfer
+	s.transfer = nil
+	s.finishTransfer(command, data[:])
+}
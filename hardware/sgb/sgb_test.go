@@ -0,0 +1,212 @@
+package sgb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+	"github.com/cgimenes/gomenes-boy/hardware/state"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// send pulses the packets of a command through P1 as a game would
+func send(s *SGB, command types.Byte, data ...types.Byte) {
+	packets := (len(data) + 1 + packetSize - 1) / packetSize
+	payload := make([]types.Byte, packets*packetSize)
+	payload[0] = command<<3 | types.Byte(packets)
+	copy(payload[1:], data)
+
+	for p := 0; p < packets; p++ {
+		s.WriteP1(0x00)
+		s.WriteP1(0x30)
+		for _, b := range payload[p*packetSize : (p+1)*packetSize] {
+			for bit := byte(0); bit < 8; bit++ {
+				if types.GetBit(bit, b) == 1 {
+					s.WriteP1(0x10)
+				} else {
+					s.WriteP1(0x20)
+				}
+				s.WriteP1(0x30)
+			}
+		}
+		// stop bit
+		s.WriteP1(0x20)
+		s.WriteP1(0x30)
+	}
+}
+
+func colors(cs ...ppu.Color) []types.Byte {
+	var b []types.Byte
+	for _, c := range cs {
+		b = append(b, types.Byte(c), types.Byte(c>>8))
+	}
+	return b
+}
+
+func TestPalettes(t *testing.T) {
+	s := New()
+	send(s, cmdPAL01, colors(0x001F, 1, 2, 3, 4, 5, 6)...)
+	send(s, cmdPAL23, colors(0x7C00, 7, 8, 9, 10, 11, 12)...)
+
+	want := [4][4]ppu.Color{
+		{0x7C00, 1, 2, 3},
+		{0x7C00, 4, 5, 6},
+		{0x7C00, 7, 8, 9},
+		{0x7C00, 10, 11, 12},
+	}
+	if s.palettes != want {
+		t.Errorf("palettes = %v, want %v", s.palettes, want)
+	}
+
+	// the right half of the screen uses palette 2
+	send(s, cmdATTRDIV, 0x02, 10)
+	var screen [ppu.Width * ppu.Height]ppu.Color
+	for i := range screen {
+		screen[i] = ppu.Shades[3]
+	}
+	s.Colorize(screen[:], screen[:])
+	if screen[0] != 3 || screen[ppu.Width-1] != 9 {
+		t.Errorf("coloured pixels 0x%04X and 0x%04X, want 0x0003 and 0x0009", screen[0], screen[ppu.Width-1])
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	s := New()
+
+	// only the inside changes, so the frame follows it
+	send(s, cmdATTRBLK, 1, 0x01, 0x02, 2, 2, 5, 4)
+	if s.attributes[2][2] != 2 || s.attributes[3][3] != 2 || s.attributes[4][5] != 2 {
+		t.Error("ATTR_BLK did not colour the block and its frame")
+	}
+	if s.attributes[1][1] != 0 || s.attributes[3][6] != 0 {
+		t.Error("ATTR_BLK coloured outside the block")
+	}
+
+	// row 17 with palette 1, column 0 with palette 3
+	send(s, cmdATTRLIN, 2, 0x80|1<<5|17, 3<<5|0)
+	if s.attributes[17][5] != 1 || s.attributes[9][0] != 3 {
+		t.Error("ATTR_LIN did not colour the lines")
+	}
+
+	// 6 cells from the end of row 0 wrap onto row 1, spanning two packets
+	data := []types.Byte{18, 0, 6, 0, 0}
+	data = append(data, 0x1B, 0xE4)
+	send(s, cmdATTRCHR, append(data, make([]types.Byte, 12)...)...)
+	got := []types.Byte{s.attributes[0][18], s.attributes[0][19], s.attributes[1][0], s.attributes[1][1], s.attributes[1][2], s.attributes[1][3]}
+	want := []types.Byte{0, 1, 2, 3, 3, 2}
+	if !bytes.Equal(got, want) {
+		t.Errorf("ATTR_CHR set %v, want %v", got, want)
+	}
+}
+
+func TestMultiplayer(t *testing.T) {
+	s := New()
+	if got := s.ReadP1(0xFF); got != 0xFF {
+		t.Fatalf("single player ID read 0x%02X, want 0xFF", got)
+	}
+	send(s, cmdMLTREQ, 0x01)
+	s.WriteP1(0x30)
+	if got := s.ReadP1(0xFF); got != 0xFF {
+		t.Errorf("first joypad ID 0x%02X, want 0xFF", got)
+	}
+	s.WriteP1(0x10)
+	s.WriteP1(0x30)
+	if got := s.ReadP1(0xFF); got != 0xFE {
+		t.Errorf("second joypad ID 0x%02X, want 0xFE", got)
+	}
+	s.WriteP1(0x10)
+	s.WriteP1(0x30)
+	if got := s.ReadP1(0xFF); got != 0xFF {
+		t.Errorf("back to the first joypad, ID 0x%02X", got)
+	}
+}
+
+// screenPPU displays 256 tiles whose data counts up from 0, as games lay
+// out VRAM for the transfer commands
+func screenPPU(data []types.Byte) *ppu.PPU {
+	p := &ppu.PPU{}
+	p.Registers.LCDC.Set(0x91)
+	for i := 0; i < 256; i++ {
+		p.VRAM[0x1800+i/20*32+i%20] = types.Byte(i)
+	}
+	copy(p.VRAM[:0x1000], data)
+	return p
+}
+
+func TestBorder(t *testing.T) {
+	s := New()
+
+	// tile 1 is solid colour 5
+	var tiles [transferSize]types.Byte
+	for row := 0; row < 8; row++ {
+		tiles[32+row*2] = 0xFF
+		tiles[32+16+row*2] = 0xFF
+	}
+	send(s, cmdCHRTRN, 0)
+	if s.borderTiles[32] != 0 {
+		t.Fatal("CHR_TRN copied before VBlank")
+	}
+	s.VBlank(screenPPU(tiles[:]))
+
+	// the top left corner uses tile 1 with palette 5, the rest is transparent
+	var picture [transferSize]types.Byte
+	picture[0], picture[1] = 0x01, 5<<2
+	copy(picture[0x800+32+5*2:], colors(0x03E0))
+	send(s, cmdPCTTRN)
+	s.VBlank(screenPPU(picture[:]))
+
+	var screen [ppu.Width * ppu.Height]ppu.Color
+	for i := range screen {
+		screen[i] = 0x1234
+	}
+	var out [Width * Height]ppu.Color
+	s.Compose(screen[:], out[:])
+	if out[0] != 0x03E0 || out[7*Width+7] != 0x03E0 {
+		t.Errorf("border tile drew 0x%04X, want 0x03E0", out[0])
+	}
+	if out[8] != defaultPalette[0] {
+		t.Errorf("transparent border drew 0x%04X, want the backdrop", out[8])
+	}
+	if out[screenY*Width+screenX] != 0x1234 || out[(screenY+ppu.Height-1)*Width+screenX+ppu.Width-1] != 0x1234 {
+		t.Error("the screen is not in the middle of the border")
+	}
+}
+
+func TestPalSet(t *testing.T) {
+	s := New()
+	var palettes [transferSize]types.Byte
+	copy(palettes[300*8:], colors(0x0001, 0x0002, 0x0003, 0x0004))
+	send(s, cmdPALTRN)
+	s.VBlank(screenPPU(palettes[:]))
+
+	send(s, cmdMASKEN, maskBlack)
+	send(s, cmdPALSET, 0x2C, 0x01, 0, 0, 0, 0, 0, 0, 0x40)
+	if s.palettes[0] != [4]ppu.Color{1, 2, 3, 4} || s.palettes[1][0] != 1 {
+		t.Errorf("palettes = %v", s.palettes)
+	}
+	if s.mask != maskOff {
+		t.Error("PAL_SET did not cancel the mask")
+	}
+}
+
+func TestState(t *testing.T) {
+	s := New()
+	send(s, cmdPAL01, colors(1, 2, 3, 4, 5, 6, 7)...)
+	send(s, cmdMLTREQ, 0x03)
+	send(s, cmdCHRTRN, 1)
+
+	var buf bytes.Buffer
+	w := state.NewWriter(&buf)
+	w.Chunk("SGB ", s.SaveState)
+	r, err := state.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _ := r.Chunk("SGB ")
+	loaded := New()
+	loaded.LoadState(d)
+
+	if loaded.palettes != s.palettes || loaded.players != 4 || len(loaded.transfer) != packetSize {
+		t.Error("state did not round trip")
+	}
+}
@@ -0,0 +1,99 @@
+package sgb
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+	"github.com/cgimenes/gomenes-boy/hardware/state"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+func (s *SGB) SaveState(e *state.Encoder) {
+	colors := func(cs []ppu.Color) {
+		for _, c := range cs {
+			e.Word(types.Word(c))
+		}
+	}
+	for i := range s.palettes {
+		colors(s.palettes[i][:])
+	}
+	for i := range s.systemPalettes {
+		colors(s.systemPalettes[i][:])
+	}
+	for i := range s.attributes {
+		e.Bytes(s.attributes[i][:])
+	}
+	for i := range s.attributeFiles {
+		e.Bytes(s.attributeFiles[i][:])
+	}
+	e.Bytes(s.borderTiles[:])
+	for _, entry := range s.borderMap {
+		e.Word(entry)
+	}
+	for i := range s.borderPalettes {
+		colors(s.borderPalettes[i][:])
+	}
+	e.Byte(s.mask)
+	colors(s.frozen[:])
+	e.Bool(s.hasFrozen)
+	e.Uint32(uint32(s.players))
+	e.Uint32(uint32(s.player))
+
+	e.Bool(s.receiving)
+	e.Byte(s.p1)
+	e.Uint32(uint32(s.bit))
+	e.Bytes(s.packet[:])
+	e.Uint32(uint32(s.expected))
+	e.Uint32(uint32(len(s.command)))
+	e.Bytes(s.command)
+	e.Uint32(uint32(len(s.transfer)))
+	e.Bytes(s.transfer)
+}
+
+func (s *SGB) LoadState(d *state.Decoder) {
+	colors := func(cs []ppu.Color) {
+		for i := range cs {
+			cs[i] = ppu.Color(d.Word())
+		}
+	}
+	// a nil slice round trips as nil, an empty one would not
+	slice := func() []types.Byte {
+		n := d.Uint32()
+		if n == 0 {
+			return nil
+		}
+		b := make([]types.Byte, n)
+		d.Bytes(b)
+		return b
+	}
+	for i := range s.palettes {
+		colors(s.palettes[i][:])
+	}
+	for i := range s.systemPalettes {
+		colors(s.systemPalettes[i][:])
+	}
+	for i := range s.attributes {
+		d.Bytes(s.attributes[i][:])
+	}
+	for i := range s.attributeFiles {
+		d.Bytes(s.attributeFiles[i][:])
+	}
+	d.Bytes(s.borderTiles[:])
+	for i := range s.borderMap {
+		s.borderMap[i] = d.Word()
+	}
+	for i := range s.borderPalettes {
+		colors(s.borderPalettes[i][:])
+	}
+	s.mask = d.Byte()
+	colors(s.frozen[:])
+	s.hasFrozen = d.Bool()
+	s.players = int(d.Uint32())
+	s.player = int(d.Uint32())
+
+	s.receiving = d.Bool()
+	s.p1 = d.Byte()
+	s.bit = int(d.Uint32())
+	d.Bytes(s.packet[:])
+	s.expected = int(d.Uint32())
+	s.command = slice()
+	s.transfer = slice()
+}
@@ -0,0 +1,184 @@
+package state
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// Magic opens every save state
+const Magic = "GBST"
+
+// Version is bumped whenever a chunk layout changes in a way that older
+// readers cannot ignore. Appending fields to a chunk does not need a bump.
+//
+// Version 2 stores the PPU framebuffer as RGB555 colours instead of shades.
+const Version uint16 = 2
+
+var ErrBadMagic = errors.New("state: not a save state")
+
+// Writer lays out a save state as a header followed by tagged chunks.
+// Each chunk is a 4 byte id, a 32 bit length and the payload.
+type Writer struct {
+	w   io.Writer
+	err error
+}
+
+func NewWriter(w io.Writer) *Writer {
+	sw := &Writer{w: w}
+	sw.write([]byte(Magic))
+	sw.write(binary.LittleEndian.AppendUint16(nil, Version))
+	return sw
+}
+
+// Chunk encodes a component with fn and appends it to the state
+func (w *Writer) Chunk(id string, fn func(e *Encoder)) {
+	if len(id) != 4 {
+		panic(fmt.Sprintf("state: chunk id %q must be 4 bytes", id))
+	}
+	e := &Encoder{}
+	fn(e)
+	w.write([]byte(id))
+	w.write(binary.LittleEndian.AppendUint32(nil, uint32(e.buf.Len())))
+	w.write(e.buf.Bytes())
+}
+
+func (w *Writer) Err() error {
+	return w.err
+}
+
+func (w *Writer) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.w.Write(b)
+}
+
+// Reader holds every chunk of a save state in memory
+type Reader struct {
+	Version uint16
+
+	chunks map[string][]byte
+}
+
+func NewReader(r io.Reader) (*Reader, error) {
+	header := make([]byte, len(Magic)+2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if string(header[:len(Magic)]) != Magic {
+		return nil, ErrBadMagic
+	}
+	sr := &Reader{
+		Version: binary.LittleEndian.Uint16(header[len(Magic):]),
+		chunks:  map[string][]byte{},
+	}
+	if sr.Version > Version {
+		return nil, fmt.Errorf("state: version %d is newer than supported version %d", sr.Version, Version)
+	}
+
+	for {
+		head := make([]byte, 8)
+		if _, err := io.ReadFull(r, head); err == io.EOF {
+			return sr, nil
+		} else if err != nil {
+			return nil, err
+		}
+		payload := make([]byte, binary.LittleEndian.Uint32(head[4:]))
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return nil, err
+		}
+		sr.chunks[string(head[:4])] = payload
+	}
+}
+
+// Chunk returns a decoder for the chunk with the given id. Components missing
+// from the state are reported with ok false and should keep their reset state.
+func (r *Reader) Chunk(id string) (d *Decoder, ok bool) {
+	payload, ok := r.chunks[id]
+	if !ok {
+		return nil, false
+	}
+	return &Decoder{Version: r.Version, buf: bytes.NewReader(payload)}, true
+}
+
+type Encoder struct {
+	buf bytes.Buffer
+}
+
+func (e *Encoder) Byte(b types.Byte) {
+	e.buf.WriteByte(b)
+}
+
+func (e *Encoder) Word(w types.Word) {
+	e.buf.Write(binary.LittleEndian.AppendUint16(nil, w))
+}
+
+func (e *Encoder) Bool(b bool) {
+	if b {
+		e.Byte(1)
+	} else {
+		e.Byte(0)
+	}
+}
+
+func (e *Encoder) Uint32(v uint32) {
+	e.buf.Write(binary.LittleEndian.AppendUint32(nil, v))
+}
+
+func (e *Encoder) Uint64(v uint64) {
+	e.buf.Write(binary.LittleEndian.AppendUint64(nil, v))
+}
+
+func (e *Encoder) Bytes(b []types.Byte) {
+	e.buf.Write(b)
+}
+
+// Decoder reads fields back in the order they were encoded. Reading past the
+// end of a chunk yields zero values, so fields appended in later versions
+// load from older states as zero.
+type Decoder struct {
+	// Version of the state being read
+	Version uint16
+
+	buf *bytes.Reader
+}
+
+func (d *Decoder) Byte() types.Byte {
+	b, err := d.buf.ReadByte()
+	if err != nil {
+		return 0
+	}
+	return b
+}
+
+func (d *Decoder) Word() types.Word {
+	l := d.Byte()
+	h := d.Byte()
+	return types.WordFromBytes(h, l)
+}
+
+func (d *Decoder) Bool() bool {
+	return d.Byte() != 0
+}
+
+func (d *Decoder) Uint32() uint32 {
+	b := make([]byte, 4)
+	d.buf.Read(b)
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (d *Decoder) Uint64() uint64 {
+	b := make([]byte, 8)
+	d.buf.Read(b)
+	return binary.LittleEndian.Uint64(b)
+}
+
+// Bytes fills dst, leaving whatever the chunk does not cover untouched
+func (d *Decoder) Bytes(dst []types.Byte) {
+	d.buf.Read(dst)
+}
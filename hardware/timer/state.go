@@ -0,0 +1,23 @@
+package timer
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/state"
+)
+
+func (t *Timer) SaveState(e *state.Encoder) {
+	e.Word(t.counter)
+	e.Byte(t.TIMA.Get())
+	e.Byte(t.TMA.Get())
+	e.Byte(t.TAC.Get())
+	e.Uint32(uint32(t.overflow))
+	e.Uint32(uint32(t.reloaded))
+}
+
+func (t *Timer) LoadState(d *state.Decoder) {
+	t.counter = d.Word()
+	t.TIMA.Set(d.Byte())
+	t.TMA.Set(d.Byte())
+	t.TAC.Set(d.Byte())
+	t.overflow = int(d.Uint32())
+	t.reloaded = int(d.Uint32())
+}
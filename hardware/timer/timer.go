@@ -0,0 +1,137 @@
+package timer
+
+import (
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// I/O addresses of the timer
+const (
+	DIVAddress  types.Word = 0xFF04
+	TIMAAddress types.Word = 0xFF05
+	TMAAddress  types.Word = 0xFF06
+	TACAddress  types.Word = 0xFF07
+)
+
+// Interrupt is the bit of IF raised when TIMA overflows
+const Interrupt = 2
+
+// bit of the internal counter that clocks TIMA for each TAC frequency
+var tacBits = [4]uint{9, 3, 5, 7}
+
+// Timer is built around a 16 bit counter bumped every cycle, DIV being its
+// upper byte. TIMA counts the falling edges of one of its bits.
+type Timer struct {
+	TIMA types.ByteRegister
+	TMA  types.ByteRegister
+	TAC  types.ByteRegister
+
+	counter uint16
+	// cycles left before TIMA, which overflowed to 0, is reloaded from TMA
+	overflow int
+	// cycles left of the reload, during which TIMA follows TMA
+	reloaded int
+}
+
+func (t *Timer) Read(address types.Word) types.Byte {
+	switch address {
+	case DIVAddress:
+		return types.Byte(t.counter >> 8)
+	case TIMAAddress:
+		return t.TIMA.Get()
+	case TMAAddress:
+		return t.TMA.Get()
+	default:
+		// unused bits read back as 1
+		return t.TAC.Get() | 0xF8
+	}
+}
+
+// Write updates a register and reports whether a timer interrupt must be
+// raised. Resetting DIV or changing TAC can clock TIMA on the way.
+func (t *Timer) Write(address types.Word, value types.Byte) bool {
+	switch address {
+	case DIVAddress:
+		before := t.input()
+		t.counter = 0
+		return before && t.clock()
+	case TIMAAddress:
+		// ignored on the cycle TMA is loaded, and cancels a pending reload
+		if t.reloaded == 0 {
+			t.TIMA.Set(value)
+			t.overflow = 0
+		}
+	case TMAAddress:
+		t.TMA.Set(value)
+		if t.reloaded > 0 {
+			t.TIMA.Set(value)
+		}
+	default:
+		before := t.input()
+		t.TAC.Set(value & 0x07)
+		return before && !t.input() && t.clock()
+	}
+	return false
+}
+
+// clock bumps TIMA from a write. The CPU only looks at the interrupts
+// between instructions and the write lands at the end of one, so an overflow
+// reloads TIMA straight away rather than 4 cycles later.
+func (t *Timer) clock() bool {
+	if !t.increment() {
+		return false
+	}
+	t.overflow = 0
+	t.TIMA.Set(t.TMA.Get())
+	return true
+}
+
+// SetCounter loads the internal counter, DIV being its upper byte, as the
+// boot ROM leaves it
+func (t *Timer) SetCounter(counter uint16) {
+	t.counter = counter
+}
+
+// Tick advances the timer and reports whether TIMA was reloaded after an
+// overflow, raising the interrupt
+func (t *Timer) Tick(cycles int) bool {
+	interrupt := false
+	for i := 0; i < cycles; i++ {
+		if t.reloaded > 0 {
+			t.reloaded--
+		}
+		if t.overflow > 0 {
+			t.overflow--
+			if t.overflow == 0 {
+				t.TIMA.Set(t.TMA.Get())
+				t.reloaded = 4
+				interrupt = true
+			}
+		}
+
+		before := t.input()
+		t.counter++
+		if before && !t.input() {
+			t.increment()
+		}
+	}
+	return interrupt
+}
+
+// input is the signal TIMA counts: the selected counter bit gated by the
+// enable bit of TAC
+func (t *Timer) input() bool {
+	tac := t.TAC.Get()
+	return types.GetBit(2, tac) == 1 && t.counter>>tacBits[tac&0x03]&1 == 1
+}
+
+// increment bumps TIMA and reports whether it overflowed. It then stays 0
+// for 4 cycles before being reloaded from TMA.
+func (t *Timer) increment() bool {
+	tima := t.TIMA.Get() + 1
+	t.TIMA.Set(tima)
+	if tima == 0 {
+		t.overflow = 4
+		return true
+	}
+	return false
+}
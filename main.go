@@ -1,11 +1,102 @@
 package main
 
 import (
-	"github.com/cgimenes/gomenes-boy/hardware/cpu"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/cgimenes/gomenes-boy/gameboy"
+	"github.com/cgimenes/gomenes-boy/hardware/printer"
 )
 
 func main() {
-	thecpu := cpu.CPU{}
-	thecpu.Init()
-	thecpu.Run()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "run":
+			os.Exit(runCommand(os.Args[2:]))
+		case "term":
+			os.Exit(termCommand(os.Args[2:]))
+		case "debug":
+			os.Exit(debugCommand(os.Args[2:]))
+		case "disasm":
+			os.Exit(disasmCommand(os.Args[2:]))
+		case "tracediff":
+			os.Exit(tracediffCommand(os.Args[2:]))
+		}
+	}
+	play(os.Args[1:])
+}
+
+// play runs the emulator until it is interrupted
+func play(args []string) {
+	flags := flag.NewFlagSet("gomenes-boy", flag.ExitOnError)
+	romPath := flags.String("rom", "", "cartridge ROM to insert")
+	printerDir := flags.String("printer", "", "attach a Game Boy Printer that saves PNGs to this directory")
+	stateDir := flags.String("state-dir", ".", "directory holding the save state slots")
+	loadSlot := flags.Int("load-slot", -1, "load the save state in this slot before running")
+	saveSlot := flags.Int("save-slot", -1, "save the machine to this slot when interrupted")
+	flags.Parse(args)
+
+	if *romPath == "" {
+		log.Fatal("--rom is required")
+	}
+	rom, err := os.ReadFile(*romPath)
+	if err != nil {
+		log.Fatalf("Loading ROM: %v", err)
+	}
+	var opts gameboy.Options
+	if *printerDir != "" {
+		opts.Serial = printer.New(&printer.PNGOutput{Dir: *printerDir})
+	}
+	gb, err := gameboy.New(rom, opts)
+	if err != nil {
+		log.Fatalf("Loading ROM: %v", err)
+	}
+	defer gb.Close()
+	if *loadSlot >= 0 {
+		if err := loadState(gb, slotPath(*stateDir, *loadSlot)); err != nil {
+			log.Fatalf("Loading slot %d: %v", *loadSlot, err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := gb.Run(ctx); !errors.Is(err, context.Canceled) {
+		log.Fatalf("Running: %v", err)
+	}
+	if *saveSlot >= 0 {
+		if err := saveState(gb, slotPath(*stateDir, *saveSlot)); err != nil {
+			log.Fatalf("Saving slot %d: %v", *saveSlot, err)
+		}
+	}
+}
+
+func slotPath(dir string, slot int) string {
+	return filepath.Join(dir, fmt.Sprintf("slot%d.state", slot))
+}
+
+func loadState(gb *gameboy.GameBoy, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gb.LoadState(f)
+}
+
+func saveState(gb *gameboy.GameBoy, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gb.SaveState(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
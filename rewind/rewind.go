@@ -0,0 +1,229 @@
+package rewind
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// Machine is anything that can snapshot and restore its whole state, and
+// play a frame back with recorded input
+type Machine interface {
+	SaveState(w io.Writer) error
+	LoadState(r io.Reader) error
+	// Buttons returns the buttons held down during the last frame
+	Buttons() types.Byte
+	// ReplayFrame runs a frame with exactly buttons held down
+	ReplayFrame(buttons types.Byte) error
+}
+
+var ErrCorruptDelta = errors.New("rewind: corrupt delta")
+
+// delta turns the snapshot after it back into the snapshot before it.
+// inputs are the buttons of the frames played from the snapshot before it
+// up to the one after it.
+type delta struct {
+	size   int
+	data   []byte
+	inputs []types.Byte
+}
+
+// Buffer keeps a snapshot every Interval frames as a ring of compressed
+// deltas, along with the input of every frame. Only the newest snapshot is
+// kept whole; older ones are rebuilt by walking the deltas backwards. Frames
+// between two snapshots are rebuilt by replaying their input from the
+// snapshot before them. The oldest deltas are dropped once the buffer grows
+// past Budget bytes.
+type Buffer struct {
+	Interval int
+	Budget   int
+
+	ring    []delta
+	head    int
+	count   int
+	used    int
+	current []byte
+	// inputs of the frames played since the current snapshot
+	inputs []types.Byte
+}
+
+func New(interval, budget int) *Buffer {
+	if interval < 1 {
+		interval = 1
+	}
+	return &Buffer{Interval: interval, Budget: budget}
+}
+
+// Len returns how many frames the buffer can step back through
+func (b *Buffer) Len() int {
+	n := len(b.inputs)
+	for i := 0; i < b.count; i++ {
+		n += len(b.ring[(b.head+i)%len(b.ring)].inputs)
+	}
+	return n
+}
+
+// Frame must be called once per emulated frame, after it has run. It
+// records the input of the frame and snapshots m every Interval frames.
+func (b *Buffer) Frame(m Machine) error {
+	if b.current == nil {
+		// nothing to replay from yet
+		return b.Push(m)
+	}
+	b.inputs = append(b.inputs, m.Buttons())
+	b.used++
+	if len(b.inputs) < b.Interval {
+		return nil
+	}
+	return b.Push(m)
+}
+
+// Push snapshots m right away
+func (b *Buffer) Push(m Machine) error {
+	var buf bytes.Buffer
+	if err := m.SaveState(&buf); err != nil {
+		return err
+	}
+	snapshot := buf.Bytes()
+
+	if b.current != nil {
+		d := delta{size: len(b.current), data: compress(xor(snapshot, b.current)), inputs: b.inputs}
+		b.used -= len(b.inputs)
+		b.push(d)
+	}
+	b.used += len(snapshot) - len(b.current)
+	b.current = snapshot
+	b.inputs = nil
+
+	for b.count > 0 && b.used > b.Budget {
+		b.dropOldest()
+	}
+	return nil
+}
+
+// Rewind brings m back one frame: it restores the nearest snapshot and
+// replays the recorded input up to the frame before the last one. It
+// reports false when there is nothing left to rewind to.
+func (b *Buffer) Rewind(m Machine) (bool, error) {
+	if len(b.inputs) == 0 {
+		if b.count == 0 {
+			return false, nil
+		}
+		d := b.pop()
+		diff, err := decompress(d.data)
+		if err != nil {
+			return false, err
+		}
+		previous := xor(b.current, diff)[:d.size]
+		b.used += len(previous) - len(b.current) + len(d.inputs)
+		b.current = previous
+		b.inputs = d.inputs
+	}
+
+	b.inputs = b.inputs[:len(b.inputs)-1]
+	b.used--
+	if err := m.LoadState(bytes.NewReader(b.current)); err != nil {
+		return false, err
+	}
+	for _, buttons := range b.inputs {
+		if err := m.ReplayFrame(buttons); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Reset forgets every snapshot
+func (b *Buffer) Reset() {
+	b.ring = nil
+	b.head = 0
+	b.count = 0
+	b.used = 0
+	b.current = nil
+	b.inputs = nil
+}
+
+func (b *Buffer) push(d delta) {
+	if b.count == len(b.ring) {
+		grown := make([]delta, max(16, len(b.ring)*2))
+		for i := 0; i < b.count; i++ {
+			grown[i] = b.ring[(b.head+i)%len(b.ring)]
+		}
+		b.ring = grown
+		b.head = 0
+	}
+	b.ring[(b.head+b.count)%len(b.ring)] = d
+	b.count++
+	b.used += len(d.data) + len(d.inputs)
+}
+
+func (b *Buffer) pop() delta {
+	i := (b.head + b.count - 1) % len(b.ring)
+	d := b.ring[i]
+	b.ring[i] = delta{}
+	b.count--
+	b.used -= len(d.data) + len(d.inputs)
+	return d
+}
+
+func (b *Buffer) dropOldest() {
+	b.used -= len(b.ring[b.head].data) + len(b.ring[b.head].inputs)
+	b.ring[b.head] = delta{}
+	b.head = (b.head + 1) % len(b.ring)
+	b.count--
+}
+
+// xor returns a^b, as long as the longest of both
+func xor(a, b []byte) []byte {
+	out := make([]byte, max(len(a), len(b)))
+	copy(out, a)
+	for i := range b {
+		out[i] ^= b[i]
+	}
+	return out
+}
+
+// compress run-length encodes the zeroes of an xor delta. The output is a
+// sequence of (zero run, literal length, literal bytes) with both lengths as
+// uvarints.
+func compress(data []byte) []byte {
+	var out []byte
+	for i := 0; i < len(data); {
+		zeroes := 0
+		for i < len(data) && data[i] == 0 {
+			zeroes++
+			i++
+		}
+		start := i
+		for i < len(data) && data[i] != 0 {
+			i++
+		}
+		out = binary.AppendUvarint(out, uint64(zeroes))
+		out = binary.AppendUvarint(out, uint64(i-start))
+		out = append(out, data[start:i]...)
+	}
+	return out
+}
+
+func decompress(data []byte) ([]byte, error) {
+	var out []byte
+	for len(data) > 0 {
+		zeroes, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, ErrCorruptDelta
+		}
+		data = data[n:]
+		literal, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < literal {
+			return nil, ErrCorruptDelta
+		}
+		data = data[n:]
+		out = append(out, make([]byte, zeroes)...)
+		out = append(out, data[:literal]...)
+		data = data[literal:]
+	}
+	return out, nil
+}
@@ -0,0 +1,173 @@
+package rewind
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/gameboy"
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// inputProgram keeps writing the d-pad lines, mixed with a counter, to BGP,
+// so every frame shows the input it was played with
+var inputProgram = []types.Byte{
+	0x3E, 0x20, // 0150: LD A,$20
+	0xE0, 0x00, // 0152: LDH ($00),A
+	0xF0, 0x00, // 0154: LDH A,($00)
+	0xA8,       // 0156: XOR B
+	0xE0, 0x47, // 0157: LDH ($47),A
+	0x04,             // 0159: INC B
+	0xC3, 0x54, 0x01, // 015A: JP $0154
+}
+
+func newGameBoy(t *testing.T) *gameboy.GameBoy {
+	t.Helper()
+	rom := make([]types.Byte, 0x8000)
+	copy(rom[0x100:], []types.Byte{0xC3, 0x50, 0x01})
+	copy(rom[0x150:], inputProgram)
+	g, err := gameboy.New(rom, gameboy.Options{SkipBoot: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// recording are the buttons held on each frame: right and left, which
+// change the BGP shade of colour 0, go up and down every few frames
+func recording(frames int) []types.Byte {
+	inputs := make([]types.Byte, frames)
+	for i := range inputs {
+		inputs[i] = types.Byte(i/3) & 0x03
+	}
+	return inputs
+}
+
+type playthrough struct {
+	frames []*[ppu.Width * ppu.Height]ppu.Color
+	states [][]byte
+}
+
+// play runs the recorded input, feeding the buffer, and keeps every frame
+// and state along the way
+func play(t *testing.T, g *gameboy.GameBoy, b *Buffer, inputs []types.Byte) playthrough {
+	t.Helper()
+	var p playthrough
+	for _, buttons := range inputs {
+		if err := g.ReplayFrame(buttons); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Frame(g); err != nil {
+			t.Fatal(err)
+		}
+		frame := g.Frame().Pixels
+		p.frames = append(p.frames, &frame)
+		p.states = append(p.states, snapshot(t, g))
+	}
+	return p
+}
+
+func snapshot(t *testing.T, m Machine) []byte {
+	var buf bytes.Buffer
+	if err := m.SaveState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRewindStepsBackFrameByFrame(t *testing.T) {
+	for _, interval := range []int{1, 4, 7} {
+		g := newGameBoy(t)
+		b := New(interval, 16<<20)
+
+		const frames, back = 60, 30
+		inputs := recording(frames)
+		original := play(t, g, b, inputs)
+		idle := play(t, newGameBoy(t), New(interval, 0), make([]types.Byte, frames))
+		if *original.frames[frames-1] == *idle.frames[frames-1] {
+			t.Fatal("the input does not show on the screen")
+		}
+
+		for i := frames - 2; i >= frames-1-back; i-- {
+			ok, err := b.Rewind(g)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				t.Fatalf("interval %d: buffer ran out before frame %d", interval, i)
+			}
+			if g.Frame().Pixels != *original.frames[i] {
+				t.Fatalf("interval %d: rewinding did not show frame %d", interval, i)
+			}
+			if !bytes.Equal(snapshot(t, g), original.states[i]) {
+				t.Fatalf("interval %d: rewinding did not restore frame %d", interval, i)
+			}
+		}
+
+		// playing the same input again gives the same frames
+		replayed := play(t, g, b, inputs[frames-back:])
+		for i, frame := range replayed.frames {
+			if *frame != *original.frames[frames-back+i] {
+				t.Fatalf("interval %d: replayed frame %d differs from the original run", interval, frames-back+i)
+			}
+		}
+	}
+}
+
+func TestRewindToFirstFrame(t *testing.T) {
+	g := newGameBoy(t)
+	b := New(5, 16<<20)
+	original := play(t, g, b, recording(12))
+
+	if b.Len() != 11 {
+		t.Fatalf("%d frames to rewind, want 11", b.Len())
+	}
+	for i := 0; i < 11; i++ {
+		if ok, err := b.Rewind(g); err != nil || !ok {
+			t.Fatalf("rewind %d: %v %v", i, ok, err)
+		}
+	}
+	if g.Frame().Pixels != *original.frames[0] {
+		t.Error("did not rewind to the first frame")
+	}
+	if ok, err := b.Rewind(g); err != nil || ok {
+		t.Errorf("rewound past the first frame: %v %v", ok, err)
+	}
+}
+
+func TestRewindRespectsBudget(t *testing.T) {
+	g := newGameBoy(t)
+	size := len(snapshot(t, g))
+	b := New(1, size+1<<20)
+
+	play(t, g, b, recording(120))
+	if b.used > b.Budget {
+		t.Fatalf("buffer uses %d bytes, budget is %d", b.used, b.Budget)
+	}
+
+	rewound := 0
+	for {
+		ok, err := b.Rewind(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			break
+		}
+		rewound++
+	}
+	if rewound == 0 || rewound >= 119 {
+		t.Fatalf("rewound %d frames, expected the budget to keep only some", rewound)
+	}
+}
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := []byte{0, 0, 0, 1, 2, 0, 3, 0, 0, 0, 0, 0, 4}
+	out, err := decompress(compress(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Fatalf("got %v, want %v", out, data)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image/png"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cgimenes/gomenes-boy/cheats"
+	"github.com/cgimenes/gomenes-boy/gameboy"
+	"github.com/cgimenes/gomenes-boy/hardware/cpu"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+	"github.com/cgimenes/gomenes-boy/trace"
+)
+
+// exit codes of the run command
+const (
+	exitCompleted           = 0
+	exitError               = 1
+	exitUnimplementedOpcode = 2
+	exitBreakpoint          = 3
+	exitLocked              = 4
+)
+
+var errBreakpoint = errors.New("breakpoint")
+
+// runCommand runs a ROM headlessly for a number of frames
+func runCommand(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	romPath := flags.String("rom", "", "cartridge ROM to run")
+	frames := flags.Uint64("frames", 600, "number of frames to run")
+	screenshot := flags.String("screenshot", "", "write the last frame to this PNG file")
+	breakList := flags.String("break", "", "comma separated PCs to stop at, in hex")
+	tracePath := flags.String("trace", "", "write a Gameboy Doctor trace to this file")
+	traceFromPC := flags.String("trace-from-pc", "", "start tracing when this PC is reached, in hex")
+	traceFromCycle := flags.Uint64("trace-from-cycle", 0, "start tracing after this many cycles")
+	blockCache := flags.Bool("block-cache", false, "run decoded blocks instead of interpreting every opcode")
+	cheatPath := flags.String("cheats", "", "cheat file to load, defaults to the ROM path with a .cht extension")
+	boot := addBootFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if *romPath == "" {
+		fmt.Fprintln(os.Stderr, "run: --rom is required")
+		return exitError
+	}
+
+	breakpoints, err := parseBreakpoints(*breakList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run: %v\n", err)
+		return exitError
+	}
+
+	rom, err := os.ReadFile(*romPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run: %v\n", err)
+		return exitError
+	}
+
+	list, err := loadCheats(*romPath, *cheatPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run: %v\n", err)
+		return exitError
+	}
+
+	opts := gameboy.Options{BlockCache: *blockCache, Cheats: list}
+	if err := boot.apply(&opts); err != nil {
+		fmt.Fprintf(os.Stderr, "run: %v\n", err)
+		return exitError
+	}
+	if *tracePath != "" {
+		f, err := os.Create(*tracePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "run: %v\n", err)
+			return exitError
+		}
+		defer f.Close()
+
+		logger := trace.New(f)
+		logger.StartCycle = *traceFromCycle
+		if *traceFromPC != "" {
+			pcs, err := parseBreakpoints(*traceFromPC)
+			if err != nil || len(pcs) != 1 {
+				fmt.Fprintf(os.Stderr, "run: bad PC %q\n", *traceFromPC)
+				return exitError
+			}
+			for pc := range pcs {
+				logger.StartPC = &pc
+			}
+		}
+		opts.Tracer = logger
+		defer logger.Flush()
+	}
+
+	gb, err := gameboy.New(rom, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run: %v\n", err)
+		return exitError
+	}
+	defer gb.Close()
+
+	code := exitCompleted
+	err = runFrames(gb, *frames, breakpoints)
+	var unimplemented cpu.ErrUnimplementedOpcode
+	switch {
+	case errors.As(err, &unimplemented):
+		fmt.Fprintf(os.Stderr, "run: %v\n", err)
+		code = exitUnimplementedOpcode
+	case errors.Is(err, errBreakpoint):
+		fmt.Fprintf(os.Stderr, "run: breakpoint at 0x%04X\n", gb.PC())
+		code = exitBreakpoint
+	case gb.Locked():
+		fmt.Fprintln(os.Stderr, "run: the CPU locked up on an illegal opcode")
+		code = exitLocked
+	}
+
+	if *screenshot != "" {
+		if err := writeScreenshot(gb, *screenshot); err != nil {
+			fmt.Fprintf(os.Stderr, "run: %v\n", err)
+			return exitError
+		}
+	}
+	return code
+}
+
+// runFrames runs the given number of frames, stopping early when a
+// breakpoint is hit or the CPU stops on an opcode it does not know
+func runFrames(gb *gameboy.GameBoy, frames uint64, breakpoints map[types.Word]bool) error {
+	if len(breakpoints) == 0 {
+		for i := uint64(0); i < frames; i++ {
+			gb.RunFrame()
+			if err := gb.Err(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	target := gb.FrameNumber() + frames
+	for gb.FrameNumber() < target {
+		if breakpoints[gb.PC()] {
+			return errBreakpoint
+		}
+		if _, err := gb.StepInstruction(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseBreakpoints(list string) (map[types.Word]bool, error) {
+	breakpoints := map[types.Word]bool{}
+	if list == "" {
+		return breakpoints, nil
+	}
+	for _, s := range strings.Split(list, ",") {
+		pc, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(s), "0x"), 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("bad breakpoint %q", s)
+		}
+		breakpoints[types.Word(pc)] = true
+	}
+	return breakpoints, nil
+}
+
+func writeScreenshot(gb *gameboy.GameBoy, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, gb.Frame().Image()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadCheats reads the cheat file given on the command line, or the one kept
+// next to the ROM, which is allowed to be missing
+func loadCheats(romPath, path string) (*cheats.List, error) {
+	if path == "" {
+		return cheats.Load(cheats.PathFor(romPath))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return cheats.Read(f)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cgimenes/gomenes-boy/gameboy"
+	"github.com/cgimenes/gomenes-boy/hardware/cpu"
+	"github.com/cgimenes/gomenes-boy/hardware/joypad"
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+	"github.com/cgimenes/gomenes-boy/rewind"
+	"github.com/cgimenes/gomenes-boy/terminal"
+)
+
+const (
+	frameDuration = time.Second * ppu.CyclesPerFrame / 4194304
+
+	// terminals only report key presses, so a button is released once its
+	// key has not been seen, through auto-repeat, for this long
+	holdDuration = 200 * time.Millisecond
+)
+
+var termKeys = map[terminal.Event]joypad.Button{
+	{Key: terminal.KeyUp}:              joypad.Up,
+	{Key: terminal.KeyDown}:            joypad.Down,
+	{Key: terminal.KeyLeft}:            joypad.Left,
+	{Key: terminal.KeyRight}:           joypad.Right,
+	{Key: terminal.KeyRune, Rune: 'x'}: joypad.A,
+	{Key: terminal.KeyRune, Rune: 'z'}: joypad.B,
+	{Key: terminal.KeyEnter}:           joypad.Start,
+	{Key: terminal.KeyBackspace}:       joypad.Select,
+}
+
+// termCommand plays a ROM in the terminal
+func termCommand(args []string) int {
+	flags := flag.NewFlagSet("term", flag.ContinueOnError)
+	romPath := flags.String("rom", "", "cartridge ROM to play")
+	colors256 := flags.Bool("256", false, "use the 256 colour palette instead of 24-bit colour")
+	rewindBudget := flags.Int("rewind-budget", 32<<20, "memory for the rewind buffer, in bytes")
+	rewindInterval := flags.Int("rewind-interval", 1, "frames between rewind snapshots")
+	cheatPath := flags.String("cheats", "", "cheat file to load, defaults to the ROM path with a .cht extension")
+	boot := addBootFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if *romPath == "" {
+		fmt.Fprintln(os.Stderr, "term: --rom is required")
+		return exitError
+	}
+
+	rom, err := os.ReadFile(*romPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "term: %v\n", err)
+		return exitError
+	}
+	list, err := loadCheats(*romPath, *cheatPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "term: %v\n", err)
+		return exitError
+	}
+	opts := gameboy.Options{Cheats: list}
+	if err := boot.apply(&opts); err != nil {
+		fmt.Fprintf(os.Stderr, "term: %v\n", err)
+		return exitError
+	}
+	gb, err := gameboy.New(rom, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "term: %v\n", err)
+		return exitError
+	}
+	defer gb.Close()
+
+	state, err := terminal.MakeRaw(os.Stdin.Fd())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "term: %v\n", err)
+		return exitError
+	}
+	renderer := terminal.NewRenderer(os.Stdout, !*colors256)
+	renderer.Start()
+
+	events := make(chan terminal.Event, 16)
+	go terminal.ReadEvents(os.Stdin, events)
+
+	code, err := termLoop(gb, renderer, events, rewind.New(*rewindInterval, *rewindBudget))
+
+	renderer.Stop()
+	terminal.Restore(os.Stdin.Fd(), state)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "term: %v\n", err)
+	}
+	return code
+}
+
+// termLoop runs a frame per tick until the player quits
+func termLoop(gb *gameboy.GameBoy, renderer *terminal.Renderer, events <-chan terminal.Event, buffer *rewind.Buffer) (int, error) {
+	held := map[joypad.Button]time.Time{}
+	var rewindUntil time.Time
+
+	ticker := time.NewTicker(frameDuration)
+	defer ticker.Stop()
+	for now := range ticker.C {
+	drain:
+		for {
+			select {
+			case e, ok := <-events:
+				if !ok || e.Key == terminal.KeyInterrupt || (e.Key == terminal.KeyRune && e.Rune == 'q') {
+					return exitCompleted, nil
+				}
+				if e.Key == terminal.KeyRune && e.Rune == 'r' {
+					rewindUntil = now.Add(holdDuration)
+				}
+				// 1 to 9 toggle the cheats in the order of the cheat file
+				if list := gb.Cheats(); e.Key == terminal.KeyRune && e.Rune >= '1' && e.Rune <= '9' {
+					if i := int(e.Rune - '1'); list != nil && i < len(list.Cheats) {
+						list.Toggle(i)
+					}
+				}
+				if b, ok := termKeys[e]; ok {
+					if _, down := held[b]; !down {
+						gb.Press(b)
+					}
+					held[b] = now.Add(holdDuration)
+				}
+			default:
+				break drain
+			}
+		}
+		for b, until := range held {
+			if now.After(until) {
+				gb.Release(b)
+				delete(held, b)
+			}
+		}
+
+		if now.Before(rewindUntil) {
+			if _, err := buffer.Rewind(gb); err != nil {
+				return exitError, err
+			}
+		} else {
+			gb.RunFrame()
+			if err := gb.Err(); err != nil {
+				return termExitCode(err)
+			}
+			if err := buffer.Frame(gb); err != nil {
+				return exitError, err
+			}
+		}
+
+		frame := gb.Frame()
+		if err := renderer.Draw(frame.Pixels[:]); err != nil {
+			return exitError, err
+		}
+	}
+	return exitCompleted, nil
+}
+
+// termExitCode maps the error that stopped a frame to the exit codes of the
+// run command. A machine closed under the loop is a normal quit.
+func termExitCode(err error) (int, error) {
+	var unimplemented cpu.ErrUnimplementedOpcode
+	switch {
+	case errors.Is(err, gameboy.ErrClosed):
+		return exitCompleted, nil
+	case errors.As(err, &unimplemented):
+		return exitUnimplementedOpcode, err
+	default:
+		return exitError, err
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/gameboy"
+	"github.com/cgimenes/gomenes-boy/hardware/cpu"
+	"github.com/cgimenes/gomenes-boy/hardware/joypad"
+	"github.com/cgimenes/gomenes-boy/terminal"
+)
+
+func TestTermKeys(t *testing.T) {
+	input := "\x1b[A\x1b[B\x1b[D\x1b[Cxz\r\x7f"
+	want := []joypad.Button{
+		joypad.Up, joypad.Down, joypad.Left, joypad.Right,
+		joypad.A, joypad.B, joypad.Start, joypad.Select,
+	}
+
+	events := make(chan terminal.Event, len(want))
+	terminal.ReadEvents(strings.NewReader(input), events)
+	i := 0
+	for e := range events {
+		b, ok := termKeys[e]
+		if !ok {
+			t.Errorf("event %+v is not mapped", e)
+		} else if i < len(want) && b != want[i] {
+			t.Errorf("event %+v maps to %v, want %v", e, b, want[i])
+		}
+		i++
+	}
+	if i != len(want) {
+		t.Errorf("got %d events, want %d", i, len(want))
+	}
+}
+
+func TestTermExitCode(t *testing.T) {
+	unimplemented := cpu.ErrUnimplementedOpcode{Opcode: 0xD3, PC: 0x150}
+	tests := []struct {
+		name    string
+		err     error
+		code    int
+		wantErr bool
+	}{
+		{"unimplemented opcode", unimplemented, exitUnimplementedOpcode, true},
+		{"wrapped unimplemented opcode", fmt.Errorf("frame: %w", unimplemented), exitUnimplementedOpcode, true},
+		{"closed", gameboy.ErrClosed, exitCompleted, false},
+		{"other", context.DeadlineExceeded, exitError, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := termExitCode(tt.err)
+			if code != tt.code {
+				t.Errorf("code %d, want %d", code, tt.code)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err %v, want an error: %v", err, tt.wantErr)
+			} else if err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("err %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
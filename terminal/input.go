@@ -0,0 +1,78 @@
+package terminal
+
+import (
+	"bufio"
+	"io"
+)
+
+type Key int
+
+// keys understood by the frontend
+const (
+	KeyNone Key = iota
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyEnter
+	KeyBackspace
+	KeySpace
+	KeyInterrupt
+	KeyEscape
+	KeyRune
+)
+
+type Event struct {
+	Key  Key
+	Rune rune
+}
+
+// ReadEvents decodes key presses from a terminal in raw mode and sends them
+// to events until r fails
+func ReadEvents(r io.Reader, events chan<- Event) {
+	in := bufio.NewReader(r)
+	for {
+		b, err := in.ReadByte()
+		if err != nil {
+			close(events)
+			return
+		}
+
+		switch b {
+		case 0x1B:
+			events <- readEscape(in)
+		case '\r', '\n':
+			events <- Event{Key: KeyEnter}
+		case 0x7F, 0x08:
+			events <- Event{Key: KeyBackspace}
+		case ' ':
+			events <- Event{Key: KeySpace}
+		case 0x03:
+			events <- Event{Key: KeyInterrupt}
+		default:
+			events <- Event{Key: KeyRune, Rune: rune(b)}
+		}
+	}
+}
+
+// readEscape decodes the arrow keys, anything else is a bare escape
+func readEscape(in *bufio.Reader) Event {
+	if in.Buffered() < 2 {
+		return Event{Key: KeyEscape}
+	}
+	if b, _ := in.ReadByte(); b != '[' && b != 'O' {
+		return Event{Key: KeyEscape}
+	}
+	b, _ := in.ReadByte()
+	switch b {
+	case 'A':
+		return Event{Key: KeyUp}
+	case 'B':
+		return Event{Key: KeyDown}
+	case 'C':
+		return Event{Key: KeyRight}
+	case 'D':
+		return Event{Key: KeyLeft}
+	}
+	return Event{Key: KeyEscape}
+}
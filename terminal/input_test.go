@@ -0,0 +1,41 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadEvents(t *testing.T) {
+	input := "\x1b[A\x1b[B\x1bOC\x1b[D\r\n\x7f\x08 \x03xz\x1b"
+	want := []Event{
+		{Key: KeyUp},
+		{Key: KeyDown},
+		{Key: KeyRight},
+		{Key: KeyLeft},
+		{Key: KeyEnter},
+		{Key: KeyEnter},
+		{Key: KeyBackspace},
+		{Key: KeyBackspace},
+		{Key: KeySpace},
+		{Key: KeyInterrupt},
+		{Key: KeyRune, Rune: 'x'},
+		{Key: KeyRune, Rune: 'z'},
+		{Key: KeyEscape},
+	}
+
+	events := make(chan Event, len(want)+1)
+	ReadEvents(strings.NewReader(input), events)
+
+	var got []Event
+	for e := range events {
+		got = append(got, e)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events %v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,48 @@
+//go:build linux || darwin
+
+package terminal
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// State is the terminal configuration to restore after raw mode
+type State struct {
+	termios syscall.Termios
+}
+
+func ioctl(fd uintptr, request uintptr, termios *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// MakeRaw turns off echo, line buffering and signal keys so every key press
+// reaches the emulator as it happens
+func MakeRaw(fd uintptr) (*State, error) {
+	var old State
+	if err := ioctl(fd, getTermios, &old.termios); err != nil {
+		return nil, err
+	}
+
+	raw := old.termios
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	if err := ioctl(fd, setTermios, &raw); err != nil {
+		return nil, err
+	}
+	return &old, nil
+}
+
+func Restore(fd uintptr, state *State) error {
+	return ioctl(fd, setTermios, &state.termios)
+}
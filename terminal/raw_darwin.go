@@ -0,0 +1,10 @@
+package terminal
+
+import (
+	"syscall"
+)
+
+const (
+	getTermios = syscall.TIOCGETA
+	setTermios = syscall.TIOCSETA
+)
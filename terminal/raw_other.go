@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+
+package terminal
+
+import (
+	"errors"
+)
+
+type State struct{}
+
+var errUnsupported = errors.New("terminal: raw mode is not supported on this platform")
+
+func MakeRaw(fd uintptr) (*State, error) {
+	return nil, errUnsupported
+}
+
+func Restore(fd uintptr, state *State) error {
+	return errUnsupported
+}
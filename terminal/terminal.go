@@ -0,0 +1,128 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+)
+
+// every character cell shows two pixels stacked with an upper half block
+const (
+	Columns = ppu.Width
+	Rows    = ppu.Height / 2
+
+	upperHalfBlock = "▀"
+)
+
+type cell struct {
+	top    ppu.Color
+	bottom ppu.Color
+}
+
+// Renderer draws the framebuffer with ANSI escapes, only rewriting the
+// cells that changed since the previous frame
+type Renderer struct {
+	w io.Writer
+	// TrueColor selects 24-bit colours, otherwise the 256 colour palette is used
+	TrueColor bool
+
+	screen [Rows][Columns]cell
+	drawn  bool
+	buf    bytes.Buffer
+}
+
+func NewRenderer(w io.Writer, trueColor bool) *Renderer {
+	return &Renderer{w: w, TrueColor: trueColor}
+}
+
+// Start clears the screen and hides the cursor
+func (r *Renderer) Start() error {
+	r.drawn = false
+	_, err := io.WriteString(r.w, "\x1b[?25l\x1b[2J")
+	return err
+}
+
+// Stop restores the terminal colours and cursor
+func (r *Renderer) Stop() error {
+	_, err := fmt.Fprintf(r.w, "\x1b[0m\x1b[%d;1H\x1b[?25h\r\n", Rows+1)
+	return err
+}
+
+func (r *Renderer) Draw(framebuffer []ppu.Color) error {
+	r.buf.Reset()
+
+	// cursor position and colours the terminal is known to be at
+	cursorRow, cursorCol := -1, -1
+	fg, bg := -1, -1
+
+	for y := 0; y < Rows; y++ {
+		for x := 0; x < Columns; x++ {
+			c := cell{
+				top:    framebuffer[2*y*Columns+x],
+				bottom: framebuffer[(2*y+1)*Columns+x],
+			}
+			if r.drawn && r.screen[y][x] == c {
+				continue
+			}
+			r.screen[y][x] = c
+
+			if cursorRow != y || cursorCol != x {
+				fmt.Fprintf(&r.buf, "\x1b[%d;%dH", y+1, x+1)
+			}
+			if int(c.top) != fg {
+				r.color(38, c.top)
+				fg = int(c.top)
+			}
+			if int(c.bottom) != bg {
+				r.color(48, c.bottom)
+				bg = int(c.bottom)
+			}
+			r.buf.WriteString(upperHalfBlock)
+			cursorRow, cursorCol = y, x+1
+		}
+	}
+	r.drawn = true
+
+	if r.buf.Len() == 0 {
+		return nil
+	}
+	r.buf.WriteString("\x1b[0m")
+	_, err := r.w.Write(r.buf.Bytes())
+	return err
+}
+
+// color selects the foreground (38) or background (48) colour
+func (r *Renderer) color(layer int, c ppu.Color) {
+	red, green, blue := c.RGB()
+	if r.TrueColor {
+		fmt.Fprintf(&r.buf, "\x1b[%d;2;%d;%d;%dm", layer, red, green, blue)
+		return
+	}
+	if red == green && green == blue {
+		fmt.Fprintf(&r.buf, "\x1b[%d;5;%dm", layer, grey256(int(red)))
+		return
+	}
+	fmt.Fprintf(&r.buf, "\x1b[%d;5;%dm", layer, cube256(red, green, blue))
+}
+
+// cube256 picks the closest entry of the 6x6x6 colour cube, 16 to 231
+func cube256(red, green, blue uint8) int {
+	level := func(v uint8) int {
+		return (int(v)*5 + 127) / 255
+	}
+	return 16 + 36*level(red) + 6*level(green) + level(blue)
+}
+
+// grey256 picks the closest entry of the 24 step greyscale ramp, 232 to 255
+func grey256(grey int) int {
+	step := (grey - 8 + 5) / 10
+	if step < 0 {
+		step = 0
+	}
+	if step > 23 {
+		step = 23
+	}
+	return 232 + step
+}
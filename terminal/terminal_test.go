@@ -0,0 +1,60 @@
+package terminal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+)
+
+func TestDrawOnlyRewritesChangedCells(t *testing.T) {
+	var out bytes.Buffer
+	r := NewRenderer(&out, true)
+	framebuffer := make([]ppu.Color, ppu.Width*ppu.Height)
+
+	if err := r.Draw(framebuffer); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(out.String(), upperHalfBlock); got != Rows*Columns {
+		t.Fatalf("first frame drew %d cells, want %d", got, Rows*Columns)
+	}
+
+	out.Reset()
+	if err := r.Draw(framebuffer); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("unchanged frame wrote %q", out.String())
+	}
+
+	// the bottom half of the cell at row 2, column 5
+	framebuffer[5*Columns+5] = ppu.NewColor(0x1F, 0, 0)
+	out.Reset()
+	if err := r.Draw(framebuffer); err != nil {
+		t.Fatal(err)
+	}
+	want := "\x1b[3;6H\x1b[38;2;0;0;0m\x1b[48;2;255;0;0m" + upperHalfBlock + "\x1b[0m"
+	if out.String() != want {
+		t.Errorf("changed cell wrote %q, want %q", out.String(), want)
+	}
+}
+
+func TestColor256(t *testing.T) {
+	tests := []struct {
+		color ppu.Color
+		want  string
+	}{
+		{ppu.NewColor(0, 0, 0), "\x1b[38;5;232m"},
+		{ppu.NewColor(0x1F, 0x1F, 0x1F), "\x1b[38;5;255m"},
+		{ppu.NewColor(0x1F, 0, 0), "\x1b[38;5;196m"},
+		{ppu.NewColor(0, 0x1F, 0x1F), "\x1b[38;5;51m"},
+	}
+	for _, tt := range tests {
+		r := NewRenderer(nil, false)
+		r.color(38, tt.color)
+		if got := r.buf.String(); got != tt.want {
+			t.Errorf("color %04X: got %q, want %q", uint16(tt.color), got, tt.want)
+		}
+	}
+}
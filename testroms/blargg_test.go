@@ -0,0 +1,72 @@
+package testroms
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/cpu"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// Blargg's ROMs print their results, ending with "Passed" or "Failed",
+// through the serial port or, for halt_bug, only on the screen. The budgets
+// include the boot ROM, which takes about 5.6s.
+var blarggROMs = []struct {
+	name    string
+	seconds uint64
+	screen  bool
+}{
+	{"cpu_instrs", 60, false},
+	{"instr_timing", 10, false},
+	{"mem_timing", 10, false},
+	{"halt_bug", 10, true},
+}
+
+// screenText reads the background map as the text blargg's font puts there,
+// one ASCII tile per character
+func screenText(c *cpu.CPU) string {
+	var s strings.Builder
+	for y := 0; y < 18; y++ {
+		for x := 0; x < 20; x++ {
+			s.WriteByte(byte(c.MMU().Peek(types.Word(0x9800 + y*32 + x))))
+		}
+		s.WriteByte('\n')
+	}
+	return s.String()
+}
+
+func TestBlargg(t *testing.T) {
+	for _, rom := range blarggROMs {
+		for _, engine := range engines {
+			t.Run(rom.name+"/"+engine.name, func(t *testing.T) {
+				c := boot(t, filepath.Join("testdata", "blargg", rom.name+".gb"), engine.blockCache)
+				out := &serialLog{}
+				c.ConnectSerial(out)
+
+				output := out.String
+				if rom.screen {
+					output = func() string { return screenText(c) }
+				}
+				// the screen is only read once a frame
+				var next uint64
+				finished := run(t, c, rom.seconds*cyclesPerSecond, func() bool {
+					if c.Cycles() < next {
+						return false
+					}
+					if rom.screen {
+						next = c.Cycles() + 70224
+					}
+					s := output()
+					return strings.Contains(s, "Passed") || strings.Contains(s, "Failed")
+				})
+				if !finished {
+					t.Fatalf("no result within %ds, output:\n%s", rom.seconds, output())
+				}
+				if !strings.Contains(output(), "Passed") {
+					t.Fatalf("output:\n%s", output())
+				}
+			})
+		}
+	}
+}
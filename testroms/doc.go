@@ -0,0 +1,3 @@
+// Package testroms runs third party test ROMs against the emulator. The ROMs
+// are committed under testdata, a missing one fails its test.
+package testroms
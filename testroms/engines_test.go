@@ -0,0 +1,59 @@
+package testroms
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/cpu"
+)
+
+// each ROM is stepped from its entry point under both engines in lockstep,
+// which must agree on the registers and cycle count after every instruction
+var enginesROMs = []struct {
+	path  string
+	steps int
+}{
+	{filepath.Join("testdata", "blargg", "cpu_instrs.gb"), 6_000_000},
+	{filepath.Join("testdata", "mooneye", "acceptance", "oam_dma_timing.gb"), 200_000},
+	{filepath.Join("testdata", "screenshots", "dmg-acid2.gb"), 300_000},
+}
+
+func TestEnginesAgree(t *testing.T) {
+	for _, r := range enginesROMs {
+		t.Run(filepath.Base(r.path), func(t *testing.T) {
+			interpreter := boot(t, r.path, false)
+			blocks := boot(t, r.path, true)
+			interpreter.SkipBootROM()
+			blocks.SkipBootROM()
+			for i := 0; i < r.steps; i++ {
+				want, err := interpreter.Step()
+				if err != nil {
+					t.Fatalf("interpreter, step %d: %v", i, err)
+				}
+				got, err := blocks.Step()
+				if err != nil {
+					t.Fatalf("blocks, step %d: %v", i, err)
+				}
+				if got != want || !sameState(interpreter, blocks) {
+					t.Fatalf("step %d took %d cycles and left %s, the interpreter took %d and left %s",
+						i, got, state(blocks), want, state(interpreter))
+				}
+			}
+		})
+	}
+}
+
+func sameState(a, b *cpu.CPU) bool {
+	ra, rb := a.Registers(), b.Registers()
+	return ra.AF.Get() == rb.AF.Get() && ra.BC.Get() == rb.BC.Get() &&
+		ra.DE.Get() == rb.DE.Get() && ra.HL.Get() == rb.HL.Get() &&
+		ra.SP.Get() == rb.SP.Get() && ra.PC.Get() == rb.PC.Get() &&
+		a.Cycles() == b.Cycles()
+}
+
+func state(c *cpu.CPU) string {
+	r := c.Registers()
+	return fmt.Sprintf("AF=%04X BC=%04X DE=%04X HL=%04X SP=%04X PC=%04X after %d cycles",
+		r.AF.Get(), r.BC.Get(), r.DE.Get(), r.HL.Get(), r.SP.Get(), r.PC.Get(), c.Cycles())
+}
@@ -0,0 +1,70 @@
+package testroms
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/cartridge"
+	"github.com/cgimenes/gomenes-boy/hardware/cpu"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// cyclesPerSecond is the DMG clock
+const cyclesPerSecond = 4194304
+
+// serialLog collects everything a ROM sends through the link port
+type serialLog struct {
+	bytes.Buffer
+}
+
+func (l *serialLog) Transfer(b types.Byte) types.Byte {
+	l.WriteByte(b)
+	return 0xFF
+}
+
+// engines are the execution modes every ROM is checked under
+var engines = []struct {
+	name       string
+	blockCache bool
+}{
+	{"interpreter", false},
+	{"blocks", true},
+}
+
+// boot loads the ROM at path into a fresh machine. A missing ROM fails the
+// test: they are all committed under testdata.
+func boot(t *testing.T, path string, blockCache bool) *cpu.CPU {
+	t.Helper()
+
+	rom, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cart, err := cartridge.New(rom)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &cpu.CPU{}
+	c.Init()
+	c.LoadCartridge(cart)
+	c.EnableBlockCache(blockCache)
+	return c
+}
+
+// run steps c until done returns true or the cycle budget runs out, and
+// reports whether done was reached
+func run(t *testing.T, c *cpu.CPU, budget uint64, done func() bool) bool {
+	t.Helper()
+
+	for c.Cycles() < budget {
+		if _, err := c.Step(); err != nil {
+			t.Fatalf("after %d cycles: %v", c.Cycles(), err)
+		}
+		if done() {
+			return true
+		}
+	}
+	return false
+}
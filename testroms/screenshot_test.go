@@ -0,0 +1,121 @@
+package testroms
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/ppu"
+)
+
+var update = flag.Bool("update", false, "rewrite the reference screenshots")
+
+// each ROM is run for a number of frames from its entry point, then its
+// screen is compared with testdata/screenshots/<name>.png
+var screenshots = []struct {
+	name   string
+	rom    string
+	frames uint64
+}{
+	{"dmg-acid2", "dmg-acid2.gb", 60},
+	{"cgb-acid2", "cgb-acid2.gbc", 60},
+}
+
+func TestScreenshots(t *testing.T) {
+	dir := filepath.Join("testdata", "screenshots")
+	for _, s := range screenshots {
+		for _, engine := range engines {
+			t.Run(s.name+"/"+engine.name, func(t *testing.T) {
+				c := boot(t, filepath.Join(dir, s.rom), engine.blockCache)
+				c.SkipBootROM()
+				done := func() bool { return c.PPU().Frame >= s.frames }
+				if !run(t, c, s.frames*2*ppu.CyclesPerFrame, done) {
+					t.Fatalf("only %d of %d frames drawn", c.PPU().Frame, s.frames)
+				}
+				got := c.PPU().Image()
+
+				reference := filepath.Join(dir, s.name+".png")
+				if *update && !engine.blockCache {
+					if err := writePNG(reference, got); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+				want, err := readPNG(reference)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				diff, mismatches := compareImages(got, want)
+				if mismatches == 0 {
+					return
+				}
+				diffPath := filepath.Join(os.TempDir(), "gomenes-boy-"+s.name+"-"+engine.name+"-diff.png")
+				gotPath := filepath.Join(os.TempDir(), "gomenes-boy-"+s.name+"-"+engine.name+".png")
+				if err := writePNG(diffPath, diff); err != nil {
+					t.Error(err)
+				}
+				if err := writePNG(gotPath, got); err != nil {
+					t.Error(err)
+				}
+				t.Fatalf("%d pixels differ, screen written to %s and diff to %s", mismatches, gotPath, diffPath)
+			})
+		}
+	}
+}
+
+// compareImages returns an image with the differing pixels in red over a
+// faded copy of want, and how many pixels differ. Pixels are compared at the
+// 5 bits per channel the PPU produces, so references using the usual DMG
+// greys, 0xAA and 0x55, still match.
+func compareImages(got *image.RGBA, want image.Image) (*image.RGBA, int) {
+	bounds := got.Bounds()
+	diff := image.NewRGBA(bounds)
+	mismatches := 0
+	if want.Bounds() != bounds {
+		return diff, bounds.Dx() * bounds.Dy()
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			w := color.RGBAModel.Convert(want.At(x, y)).(color.RGBA)
+			if rgb555(got.RGBAAt(x, y)) == rgb555(w) {
+				faded := 0xC0 + color.GrayModel.Convert(w).(color.Gray).Y/4
+				diff.SetRGBA(x, y, color.RGBA{faded, faded, faded, 0xFF})
+				continue
+			}
+			mismatches++
+			diff.SetRGBA(x, y, color.RGBA{0xFF, 0x00, 0x00, 0xFF})
+		}
+	}
+	return diff, mismatches
+}
+
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func rgb555(c color.RGBA) [3]uint8 {
+	return [3]uint8{c.R >> 3, c.G >> 3, c.B >> 3}
+}
@@ -0,0 +1,176 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cgimenes/gomenes-boy/disasm"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// Line is a parsed trace line
+type Line struct {
+	A, F, B, C, D, E, H, L types.Byte
+	SP, PC                 types.Word
+	PCMEM                  [4]types.Byte
+}
+
+// Parse reads a line in the Gameboy Doctor format
+func Parse(s string) (Line, error) {
+	var l Line
+	bytes := map[string]*types.Byte{
+		"A": &l.A, "F": &l.F, "B": &l.B, "C": &l.C,
+		"D": &l.D, "E": &l.E, "H": &l.H, "L": &l.L,
+	}
+	words := map[string]*types.Word{"SP": &l.SP, "PC": &l.PC}
+
+	fields := strings.Fields(s)
+	if len(fields) != 11 {
+		return l, fmt.Errorf("trace: expected 11 fields in %q", s)
+	}
+	for _, field := range fields {
+		name, value, ok := strings.Cut(field, ":")
+		if !ok {
+			return l, fmt.Errorf("trace: bad field %q", field)
+		}
+		if b, ok := bytes[name]; ok {
+			v, err := strconv.ParseUint(value, 16, 8)
+			if err != nil {
+				return l, fmt.Errorf("trace: bad %s in %q", name, s)
+			}
+			*b = types.Byte(v)
+		} else if w, ok := words[name]; ok {
+			v, err := strconv.ParseUint(value, 16, 16)
+			if err != nil {
+				return l, fmt.Errorf("trace: bad %s in %q", name, s)
+			}
+			*w = types.Word(v)
+		} else if name == "PCMEM" {
+			mem := strings.Split(value, ",")
+			if len(mem) != 4 {
+				return l, fmt.Errorf("trace: bad PCMEM in %q", s)
+			}
+			for i, m := range mem {
+				v, err := strconv.ParseUint(m, 16, 8)
+				if err != nil {
+					return l, fmt.Errorf("trace: bad PCMEM in %q", s)
+				}
+				l.PCMEM[i] = types.Byte(v)
+			}
+		} else {
+			return l, fmt.Errorf("trace: unknown field %q", name)
+		}
+	}
+	return l, nil
+}
+
+// Instruction disassembles the instruction at PC from PCMEM
+func (l Line) Instruction() disasm.Instruction {
+	return disasm.At(func(address types.Word) types.Byte {
+		return l.PCMEM[(address-l.PC)%4]
+	}, l.PC)
+}
+
+// Differences lists the registers and flags that differ between two lines
+func Differences(a, b Line) []string {
+	var diffs []string
+	bytes := []struct {
+		name string
+		a, b types.Byte
+	}{
+		{"A", a.A, b.A}, {"B", a.B, b.B}, {"C", a.C, b.C}, {"D", a.D, b.D},
+		{"E", a.E, b.E}, {"H", a.H, b.H}, {"L", a.L, b.L},
+	}
+	for _, r := range bytes {
+		if r.a != r.b {
+			diffs = append(diffs, fmt.Sprintf("%s: %02X != %02X", r.name, r.a, r.b))
+		}
+	}
+	for i, flag := range []string{"Z", "N", "H", "C"} {
+		bit := byte(7 - i)
+		if types.GetBit(bit, a.F) != types.GetBit(bit, b.F) {
+			diffs = append(diffs, fmt.Sprintf("flag %s: %d != %d", flag, types.GetBit(bit, a.F), types.GetBit(bit, b.F)))
+		}
+	}
+	if a.F&0x0F != b.F&0x0F {
+		diffs = append(diffs, fmt.Sprintf("F low nibble: %X != %X", a.F&0x0F, b.F&0x0F))
+	}
+	if a.SP != b.SP {
+		diffs = append(diffs, fmt.Sprintf("SP: %04X != %04X", a.SP, b.SP))
+	}
+	if a.PC != b.PC {
+		diffs = append(diffs, fmt.Sprintf("PC: %04X != %04X", a.PC, b.PC))
+	}
+	if a.PCMEM != b.PCMEM {
+		diffs = append(diffs, fmt.Sprintf("PCMEM: % X != % X", a.PCMEM, b.PCMEM))
+	}
+	return diffs
+}
+
+// Divergence is the first point where two traces differ
+type Divergence struct {
+	// Number is the 1 based line number of the first differing line
+	Number int
+	// Context holds the identical lines that came right before
+	Context []string
+	// Previous is the identical line right before, whatever the context,
+	// and empty when the traces differ from their first line
+	Previous string
+	A, B     string
+	// Ended is set when one trace is shorter than the other, the missing
+	// line is then empty
+	Ended bool
+}
+
+// Diff streams two traces and returns their first divergence, or nil when
+// they are identical
+func Diff(a, b io.Reader, context int) (*Divergence, error) {
+	sa := newScanner(a)
+	sb := newScanner(b)
+
+	var recent []string
+	var previous string
+	for n := 1; ; n++ {
+		okA := sa.Scan()
+		okB := sb.Scan()
+		if err := sa.Err(); err != nil {
+			return nil, err
+		}
+		if err := sb.Err(); err != nil {
+			return nil, err
+		}
+		if !okA && !okB {
+			return nil, nil
+		}
+
+		la := strings.TrimSpace(sa.Text())
+		lb := strings.TrimSpace(sb.Text())
+		if okA && okB && la == lb {
+			previous = la
+			if context > 0 {
+				if len(recent) == context {
+					recent = recent[1:]
+				}
+				recent = append(recent, la)
+			}
+			continue
+		}
+
+		if !okA {
+			la = ""
+		}
+		if !okB {
+			lb = ""
+		}
+		return &Divergence{Number: n, Context: recent, Previous: previous, A: la, B: lb, Ended: !okA || !okB}, nil
+	}
+}
+
+func newScanner(r io.Reader) *bufio.Scanner {
+	s := bufio.NewScanner(bufio.NewReaderSize(r, 1<<20))
+	s.Buffer(make([]byte, 4096), 1<<20)
+	return s
+}
@@ -0,0 +1,82 @@
+package trace
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	line1 = "A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,C3,50,01"
+	line2 = "A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0101 PCMEM:C3,50,01,CE"
+	line3 = "A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0150 PCMEM:3E,20,E0,00"
+	line4 = "A:20 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0152 PCMEM:E0,00,F0,00"
+	// line4 with a different A and a cleared carry
+	line4b = "A:21 F:A0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0152 PCMEM:E0,00,F0,00"
+)
+
+func trace(lines ...string) *strings.Reader {
+	return strings.NewReader(strings.Join(lines, "\n") + "\n")
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    *strings.Reader
+		context int
+		want    *Divergence
+	}{
+		{"identical", trace(line1, line2, line3), trace(line1, line2, line3), 2, nil},
+		{"trailing spaces", trace(line1, line2), trace(line1+"  ", line2), 2, nil},
+		{"first line", trace(line1, line2), trace(line2, line2), 2,
+			&Divergence{Number: 1, A: line1, B: line2}},
+		{"first differing instruction", trace(line1, line2, line3, line4, line1), trace(line1, line2, line3, line4b, line2), 2,
+			&Divergence{Number: 4, Context: []string{line2, line3}, Previous: line3, A: line4, B: line4b}},
+		{"no context", trace(line1, line2, line3, line4), trace(line1, line2, line3, line4b), 0,
+			&Divergence{Number: 4, Previous: line3, A: line4, B: line4b}},
+		{"no context on the second line", trace(line1, line2), trace(line1, line3), 0,
+			&Divergence{Number: 2, Previous: line1, A: line2, B: line3}},
+		{"a ends first", trace(line1, line2), trace(line1, line2, line3), 1,
+			&Divergence{Number: 3, Context: []string{line2}, Previous: line2, B: line3, Ended: true}},
+		{"b ends first", trace(line1, line2, line3), trace(line1), 1,
+			&Divergence{Number: 2, Context: []string{line1}, Previous: line1, A: line2, Ended: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Diff(tt.a, tt.b, tt.context)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDifferences(t *testing.T) {
+	a, err := Parse(line4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Parse(line4b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diffs := Differences(a, a); len(diffs) != 0 {
+		t.Errorf("a line differs from itself: %v", diffs)
+	}
+	want := []string{"A: 20 != 21", "flag C: 1 != 0"}
+	if diffs := Differences(a, b); !reflect.DeepEqual(diffs, want) {
+		t.Errorf("got %q, want %q", diffs, want)
+	}
+
+	c, err := Parse("A:20 F:B5 B:00 C:13 D:00 E:D8 H:01 L:4D SP:DFFE PC:0153 PCMEM:00,F0,00,00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"F low nibble: 0 != 5", "SP: FFFE != DFFE", "PC: 0152 != 0153", "PCMEM: E0 00 F0 00 != 00 F0 00 00"}
+	if diffs := Differences(a, c); !reflect.DeepEqual(diffs, want) {
+		t.Errorf("got %q, want %q", diffs, want)
+	}
+}
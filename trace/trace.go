@@ -0,0 +1,50 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/cgimenes/gomenes-boy/hardware/cpu"
+	"github.com/cgimenes/gomenes-boy/hardware/types"
+)
+
+// Logger writes a line per instruction in the Gameboy Doctor format:
+//
+//	A:00 F:11 B:22 C:33 D:44 E:55 H:66 L:77 SP:8888 PC:9999 PCMEM:AA,BB,CC,DD
+//
+// Logging starts once the CPU reaches StartPC, when set, and has run at
+// least StartCycle cycles.
+type Logger struct {
+	StartPC    *types.Word
+	StartCycle uint64
+
+	w       *bufio.Writer
+	started bool
+}
+
+func New(w io.Writer) *Logger {
+	return &Logger{w: bufio.NewWriterSize(w, 1<<16)}
+}
+
+func (l *Logger) BeforeInstruction(c *cpu.CPU) {
+	r := c.Registers()
+	pc := r.PC.Get()
+
+	if !l.started {
+		if c.Cycles() < l.StartCycle || (l.StartPC != nil && pc != *l.StartPC) {
+			return
+		}
+		l.started = true
+	}
+
+	m := c.MMU()
+	fmt.Fprintf(l.w, "A:%02X F:%02X B:%02X C:%02X D:%02X E:%02X H:%02X L:%02X SP:%04X PC:%04X PCMEM:%02X,%02X,%02X,%02X\n",
+		r.A.Get(), r.F.Get(), r.B.Get(), r.C.Get(), r.D.Get(), r.E.Get(), r.H.Get(), r.L.Get(),
+		r.SP.Get(), pc, m.Peek(pc), m.Peek(pc+1), m.Peek(pc+2), m.Peek(pc+3))
+}
+
+// Flush writes out the buffered lines
+func (l *Logger) Flush() error {
+	return l.w.Flush()
+}
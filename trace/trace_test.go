@@ -0,0 +1,124 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cgimenes/gomenes-boy/hardware/cpu"
+)
+
+func TestParse(t *testing.T) {
+	line, err := Parse("A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,C3,13,02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Line{
+		A: 0x01, F: 0xB0, B: 0x00, C: 0x13, D: 0x00, E: 0xD8, H: 0x01, L: 0x4D,
+		SP: 0xFFFE, PC: 0x0100, PCMEM: [4]byte{0x00, 0xC3, 0x13, 0x02},
+	}
+	if line != want {
+		t.Errorf("got %+v, want %+v", line, want)
+	}
+	if got := line.Instruction().Text; got != "NOP" {
+		t.Errorf("instruction %q, want NOP", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"empty", ""},
+		{"missing field", "A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100"},
+		{"no colon", "A01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,C3,13,02"},
+		{"byte too wide", "A:100 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,C3,13,02"},
+		{"word not hex", "A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:01G0 PCMEM:00,C3,13,02"},
+		{"short PCMEM", "A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,C3,13"},
+		{"bad PCMEM byte", "A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,C3,13,XY"},
+		{"unknown field", "A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 X:4D SP:FFFE PC:0100 PCMEM:00,C3,13,02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.line); err == nil {
+				t.Errorf("%q parsed without an error", tt.line)
+			}
+		})
+	}
+}
+
+// TestLoggerRoundTrip parses back what the logger writes while the boot ROM
+// runs and checks it against the CPU state each line was written from
+func TestLoggerRoundTrip(t *testing.T) {
+	c := &cpu.CPU{}
+	c.Init()
+
+	var buf bytes.Buffer
+	l := New(&buf)
+	var want []Line
+	for i := 0; i < 500; i++ {
+		r := c.Registers()
+		pc := r.PC.Get()
+		m := c.MMU()
+		want = append(want, Line{
+			A: r.A.Get(), F: r.F.Get(), B: r.B.Get(), C: r.C.Get(),
+			D: r.D.Get(), E: r.E.Get(), H: r.H.Get(), L: r.L.Get(),
+			SP: r.SP.Get(), PC: pc,
+			PCMEM: [4]byte{m.Peek(pc), m.Peek(pc + 1), m.Peek(pc + 2), m.Peek(pc + 3)},
+		})
+		l.BeforeInstruction(c)
+		if _, err := c.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := bufio.NewScanner(&buf)
+	n := 0
+	for ; s.Scan(); n++ {
+		if n >= len(want) {
+			t.Fatalf("more than %d lines logged", len(want))
+		}
+		line, err := Parse(s.Text())
+		if err != nil {
+			t.Fatalf("line %d: %v", n+1, err)
+		}
+		if line != want[n] {
+			t.Fatalf("line %d parsed as %+v, want %+v", n+1, line, want[n])
+		}
+	}
+	if n != len(want) {
+		t.Fatalf("%d lines logged, want %d", n, len(want))
+	}
+}
+
+func TestLoggerStart(t *testing.T) {
+	c := &cpu.CPU{}
+	c.Init()
+
+	var buf bytes.Buffer
+	l := New(&buf)
+	// the boot ROM clears VRAM in a loop from 0x0007
+	start := c.PC() + 7
+	l.StartPC = &start
+	for i := 0; i < 100; i++ {
+		l.BeforeInstruction(c)
+		if _, err := c.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Flush()
+
+	first, _, _ := strings.Cut(buf.String(), "\n")
+	line, err := Parse(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line.PC != start {
+		t.Errorf("logging started at %04X, want %04X", line.PC, start)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cgimenes/gomenes-boy/trace"
+)
+
+// exit codes of tracediff, following diff and cmp
+const (
+	tracediffSame     = 0
+	tracediffDiverged = 1
+	tracediffError    = 2
+)
+
+// tracediffCommand reports the first line where two traces disagree
+func tracediffCommand(args []string) int {
+	flags := flag.NewFlagSet("tracediff", flag.ContinueOnError)
+	context := flags.Int("context", 5, "identical lines to show before the divergence")
+
+	// the traces may come before the flags
+	var paths []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		paths, args = append(paths, args[0]), args[1:]
+	}
+	if err := flags.Parse(args); err != nil {
+		return tracediffError
+	}
+	paths = append(paths, flags.Args()...)
+	if len(paths) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: gomenes-boy tracediff a.log b.log [--context n]")
+		return tracediffError
+	}
+
+	a, err := os.Open(paths[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tracediff: %v\n", err)
+		return tracediffError
+	}
+	defer a.Close()
+	b, err := os.Open(paths[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tracediff: %v\n", err)
+		return tracediffError
+	}
+	defer b.Close()
+
+	d, err := trace.Diff(a, b, *context)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tracediff: %v\n", err)
+		return tracediffError
+	}
+	if d == nil {
+		fmt.Println("traces are identical")
+		return tracediffSame
+	}
+
+	fmt.Printf("traces diverge at line %d\n\n", d.Number)
+	for i, line := range d.Context {
+		fmt.Printf("  %8d  %s\n", d.Number-len(d.Context)+i, line)
+	}
+	fmt.Printf("< %8d  %s\n", d.Number, orEnd(d.A, paths[0]))
+	fmt.Printf("> %8d  %s\n", d.Number, orEnd(d.B, paths[1]))
+	if d.Ended {
+		return tracediffDiverged
+	}
+
+	la, errA := trace.Parse(d.A)
+	lb, errB := trace.Parse(d.B)
+	if errA != nil || errB != nil {
+		return tracediffDiverged
+	}
+
+	fmt.Println()
+	for _, diff := range trace.Differences(la, lb) {
+		fmt.Printf("  %s\n", diff)
+	}
+
+	// the instruction on the line before is the one that produced the
+	// divergent state
+	if d.Previous != "" {
+		if previous, err := trace.Parse(d.Previous); err == nil {
+			fmt.Printf("\ncaused by %v\n", previous.Instruction())
+		}
+	} else {
+		fmt.Println("\ntraces differ from their first line")
+	}
+	return tracediffDiverged
+}
+
+func orEnd(line, path string) string {
+	if line == "" {
+		return "(end of " + path + ")"
+	}
+	return line
+}